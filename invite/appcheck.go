@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"firebase.google.com/go/v4/appcheck"
+)
+
+// appCheckHeader carries the Firebase App Check token from web clients
+const appCheckHeader = "X-Firebase-AppCheck"
+
+// App Check modes, set with APP_CHECK_MODE
+const (
+	appCheckOff     = "off"     // tokens are not checked
+	appCheckMonitor = "monitor" // tokens are checked and failures logged, but requests proceed
+	appCheckEnforce = "enforce" // requests without a valid token are rejected
+)
+
+var (
+	appCheckClient *appcheck.Client
+	appCheckMode   = appCheckOff
+	// appCheckRouteModes overrides the mode of individual operations, from APP_CHECK_ROUTES
+	appCheckRouteModes = map[string]string{}
+)
+
+// validAppCheckMode reports whether mode is one of the App Check modes
+func validAppCheckMode(mode string) bool {
+	return mode == appCheckOff || mode == appCheckMonitor || mode == appCheckEnforce
+}
+
+// parseAppCheckRoutes parses a comma-separated list of operationId=mode entries
+func parseAppCheckRoutes(value string) (map[string]string, error) {
+	modes := map[string]string{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		operationID, mode, ok := strings.Cut(entry, "=")
+		operationID = strings.TrimSpace(operationID)
+		mode = strings.ToLower(strings.TrimSpace(mode))
+		if !ok || operationID == "" || !validAppCheckMode(mode) {
+			return nil, fmt.Errorf("%q is not an operationId=off|monitor|enforce entry", entry)
+		}
+		modes[operationID] = mode
+	}
+	return modes, nil
+}
+
+// loadAppCheckConfig reads APP_CHECK_MODE and APP_CHECK_ROUTES and initializes the App
+// Check client if any route checks tokens
+func loadAppCheckConfig() {
+	appCheckMode = strings.ToLower(getEnv("APP_CHECK_MODE", appCheckOff))
+	if !validAppCheckMode(appCheckMode) {
+		fatal("Invalid APP_CHECK_MODE", "mode", appCheckMode)
+	}
+	var err error
+	appCheckRouteModes, err = parseAppCheckRoutes(getEnv("APP_CHECK_ROUTES", ""))
+	if err != nil {
+		fatal("Invalid APP_CHECK_ROUTES", "error", err)
+	}
+
+	enabled := appCheckMode != appCheckOff
+	for _, mode := range appCheckRouteModes {
+		enabled = enabled || mode != appCheckOff
+	}
+	if !enabled {
+		return
+	}
+
+	appCheckClient, err = firebaseApp.AppCheck(context.Background())
+	if err != nil {
+		fatal("Error initializing Firebase App Check", "error", err)
+	}
+	slog.Info("Firebase App Check enabled", "mode", appCheckMode, "routes", appCheckRouteModes)
+}
+
+// appCheckModeFor returns the App Check mode of route: its APP_CHECK_ROUTES entry if it
+// has one, otherwise APP_CHECK_MODE for routes marked AppCheck
+func appCheckModeFor(route apiRoute) string {
+	if mode, ok := appCheckRouteModes[route.OperationID]; ok {
+		return mode
+	}
+	if route.AppCheck {
+		return appCheckMode
+	}
+	return appCheckOff
+}
+
+// checkAppCheckRoutes stops the service if APP_CHECK_ROUTES names an unknown operation,
+// so a typo cannot silently leave a route unchecked
+func checkAppCheckRoutes(routes []apiRoute) {
+	known := map[string]bool{}
+	for _, route := range routes {
+		known[route.OperationID] = true
+	}
+	for operationID := range appCheckRouteModes {
+		if !known[operationID] {
+			fatal("Unknown operation in APP_CHECK_ROUTES", "operationId", operationID)
+		}
+	}
+}
+
+// verifyAppCheckToken checks the App Check token on a request and returns the app ID
+func verifyAppCheckToken(r *http.Request) (string, error) {
+	token := r.Header.Get(appCheckHeader)
+	if token == "" {
+		return "", fmt.Errorf("missing %s header", appCheckHeader)
+	}
+	decoded, err := appCheckClient.VerifyToken(token)
+	if err != nil {
+		return "", fmt.Errorf("app check verification failed: %v", err)
+	}
+	return decoded.AppID, nil
+}
+
+// requireAppCheck verifies the Firebase App Check token of requests to route. In monitor
+// mode failures are only logged and counted, so enforcement can be rolled out once the
+// metrics show legitimate clients are sending tokens.
+func requireAppCheck(route, mode string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		appID, err := verifyAppCheckToken(r)
+		switch {
+		case err == nil:
+			recordAppCheck(route, "valid")
+			slog.DebugContext(ctx, "App Check token verified", "appId", appID)
+		case r.Header.Get(appCheckHeader) == "":
+			recordAppCheck(route, "missing")
+		default:
+			recordAppCheck(route, "invalid")
+		}
+
+		if err != nil {
+			if mode == appCheckEnforce {
+				slog.WarnContext(ctx, "Rejected request without valid App Check token", "route", route, "error", err)
+				writeError(w, r, http.StatusUnauthorized, errCodeAppCheckFailed, "Missing or invalid App Check token", nil)
+				return
+			}
+			slog.InfoContext(ctx, "App Check token missing or invalid (monitor mode)", "route", route, "error", err)
+		}
+
+		handler(w, r)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+// Principal is the verified caller of a request
+type Principal struct {
+	UID           string
+	Email         string
+	EmailVerified bool
+	Token         *auth.Token
+}
+
+type principalContextKey struct{}
+
+// principalFromContext returns the principal stored by requireAuth, or nil
+func principalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalContextKey{}).(*Principal)
+	return p
+}
+
+// Helper function to extract Firebase token from Authorization header
+func extractFirebaseToken(r *http.Request) (string, error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return "", fmt.Errorf("missing Authorization header")
+	}
+
+	token, ok := strings.CutPrefix(authHeader, "Bearer ")
+	if !ok || token == "" {
+		return "", fmt.Errorf("invalid Authorization header format")
+	}
+
+	return token, nil
+}
+
+// errTokenRevoked is returned when a token was issued before the user's sessions were revoked
+var errTokenRevoked = errors.New("token has been revoked")
+
+// Helper function to verify a Firebase ID token and build the principal.
+// checkRevoked also rejects tokens revoked since they were issued, at the cost of a
+// call to Firebase Auth.
+func verifyFirebaseToken(ctx context.Context, token string, checkRevoked bool) (*Principal, error) {
+	var verifiedToken *auth.Token
+	var err error
+	if checkRevoked {
+		verifiedToken, err = firebaseAuth.VerifyIDTokenAndCheckRevoked(ctx, token)
+	} else {
+		verifiedToken, err = firebaseAuth.VerifyIDToken(ctx, token)
+	}
+	if auth.IsIDTokenRevoked(err) {
+		return nil, errTokenRevoked
+	}
+	if err != nil {
+		return nil, fmt.Errorf("token verification failed: %v", err)
+	}
+
+	principal := &Principal{
+		UID:   verifiedToken.UID,
+		Token: verifiedToken,
+	}
+	principal.Email, _ = verifiedToken.Claims["email"].(string)
+	principal.EmailVerified, _ = verifiedToken.Claims["email_verified"].(bool)
+	return principal, nil
+}
+
+// isStateChanging reports whether a request may modify data
+func isStateChanging(r *http.Request) bool {
+	return r.Method != http.MethodGet && r.Method != http.MethodHead
+}
+
+// requireAuth verifies the Firebase ID token and stores the principal in the request context.
+// State-changing requests are also checked against token revocation, so signing a user
+// out everywhere takes effect immediately rather than when their token expires.
+func requireAuth(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		token, err := extractFirebaseToken(r)
+		if err != nil {
+			slog.WarnContext(ctx, "Authentication failed", "error", err)
+			writeError(w, r, http.StatusUnauthorized, errCodeUnauthorized, "Missing or invalid Authorization header", nil)
+			return
+		}
+
+		principal, err := verifyFirebaseToken(ctx, token, isStateChanging(r))
+		if errors.Is(err, errTokenRevoked) {
+			slog.WarnContext(ctx, "Rejected revoked Firebase token", "method", r.Method)
+			writeError(w, r, http.StatusUnauthorized, errCodeTokenRevoked, "Token has been revoked, please sign in again", nil)
+			return
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "Firebase token verification failed", "error", err)
+			writeError(w, r, http.StatusUnauthorized, errCodeUnauthorized, "Invalid or expired token", nil)
+			return
+		}
+
+		slog.DebugContext(ctx, "Firebase token verified", "uid", principal.UID)
+		handler(w, r.WithContext(context.WithValue(ctx, principalContextKey{}, principal)))
+	}
+}
+
+// Club roles, from least to most privileged
+const (
+	roleMember = "member"
+	roleAdmin  = "admin"
+	roleOwner  = "owner"
+)
+
+var clubRoleRank = map[string]int{
+	roleMember: 1,
+	roleAdmin:  2,
+	roleOwner:  3,
+}
+
+// errForbidden is wrapped by policies that deny access
+var errForbidden = errors.New("forbidden")
+
+// errEmailNotVerified is returned by requireVerifiedEmail
+var errEmailNotVerified = fmt.Errorf("%w: verified email required", errForbidden)
+
+// Policy decides whether a principal may perform an action
+type Policy func(ctx context.Context, p *Principal) error
+
+// memberRole returns the role of uid in club, or "" if uid is not a member.
+// Members without an explicit role are plain members.
+func memberRole(club *Club, uid string) string {
+	for _, member := range club.Members {
+		if member.ID == uid {
+			if member.Role == "" {
+				return roleMember
+			}
+			return member.Role
+		}
+	}
+	return ""
+}
+
+// hasClubRole reports whether uid holds role, or a more privileged one, in club
+func hasClubRole(club *Club, uid, role string) bool {
+	rank := clubRoleRank[memberRole(club, uid)]
+	return rank > 0 && rank >= clubRoleRank[role]
+}
+
+// requireClubRole allows principals holding at least role in the club
+func requireClubRole(clubID, role string) Policy {
+	return func(ctx context.Context, p *Principal) error {
+		club, err := getClub(ctx, clubID)
+		if err != nil {
+			return err
+		}
+		if !hasClubRole(club, p.UID, role) {
+			return fmt.Errorf("%w: %s role required in club", errForbidden, role)
+		}
+		return nil
+	}
+}
+
+// requireVerifiedEmail allows principals whose email address has been verified.
+// Use it wherever the caller's email is their identity, e.g. accepting an invite
+// sent to that address or acting as a club admin.
+func requireVerifiedEmail() Policy {
+	return func(ctx context.Context, p *Principal) error {
+		if p.Email == "" || !p.EmailVerified {
+			return errEmailNotVerified
+		}
+		return nil
+	}
+}
+
+// getClub loads a club; missing clubs decode as a club with no members
+func getClub(ctx context.Context, clubID string) (*Club, error) {
+	var club Club
+	if err := firebaseGet(ctx, "clubs", firebaseDB.NewRef(fmt.Sprintf("clubs/%s", clubID)), &club); err != nil {
+		return nil, fmt.Errorf("failed to load club: %v", err)
+	}
+	return &club, nil
+}
+
+// authorize evaluates policies against the request principal, writing a 401/403/500
+// error and returning false if any of them denies access
+func authorize(w http.ResponseWriter, r *http.Request, policies ...Policy) bool {
+	ctx := r.Context()
+	principal := principalFromContext(ctx)
+	if principal == nil {
+		writeError(w, r, http.StatusUnauthorized, errCodeUnauthorized, "Authentication required", nil)
+		return false
+	}
+
+	for _, policy := range policies {
+		err := policy(ctx, principal)
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, errEmailNotVerified) {
+			slog.InfoContext(ctx, "Authorization denied", "uid", principal.UID, "reason", err)
+			writeError(w, r, http.StatusForbidden, errCodeEmailNotVerified, "Verify your email address to perform this action", nil)
+			return false
+		}
+		if errors.Is(err, errForbidden) {
+			slog.InfoContext(ctx, "Authorization denied", "uid", principal.UID, "reason", err)
+			writeError(w, r, http.StatusForbidden, errCodeForbidden, "You do not have permission to perform this action", nil)
+			return false
+		}
+		// Auth errors here usually mean the service account lacks Realtime Database access
+		slog.ErrorContext(ctx, "Authorization check failed", "uid", principal.UID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to check permissions", nil)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	firebase "firebase.google.com/go/v4"
+)
+
+// testClubs is the Realtime Database content the fake database starts with, by path
+var testClubs = map[string]string{
+	"/clubs/club1.json": `{"members": [
+		{"id": "member", "role": "member"},
+		{"id": "admin", "role": "admin"},
+		{"id": "owner", "role": "owner"},
+		{"id": "unknown", "role": "superuser"},
+		{"id": "norole"}
+	]}`,
+}
+
+// testDB is the fake Realtime Database behind firebaseDB in tests
+var testDB = newFakeDatabase()
+
+// TestMain points firebaseDB at a fake Realtime Database holding testClubs. Reads of
+// clubs/broken fail with a 500.
+func TestMain(m *testing.M) {
+	for path, body := range testClubs {
+		var v interface{}
+		if err := json.Unmarshal([]byte(body), &v); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid test club:", path, err)
+			os.Exit(1)
+		}
+		testDB.write(splitPath(path), v)
+	}
+	testDB.failPaths["clubs/broken"] = true
+	server := httptest.NewServer(testDB)
+
+	ctx := context.Background()
+	app, err := firebase.NewApp(ctx, &firebase.Config{
+		ProjectID:   "test",
+		DatabaseURL: strings.Replace(server.URL, "http://127.0.0.1", "localhost", 1) + "?ns=test",
+	})
+	if err == nil {
+		firebaseDB, err = app.Database(ctx)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to set up fake database:", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	server.Close()
+	os.Exit(code)
+}
+
+func TestExtractFirebaseToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    string
+		wantErr bool
+	}{
+		{name: "bearer token", header: "Bearer abc.def", want: "abc.def"},
+		{name: "missing header", header: "", wantErr: true},
+		{name: "no scheme", header: "abc.def", wantErr: true},
+		{name: "other scheme", header: "Basic abc.def", wantErr: true},
+		{name: "lowercase scheme", header: "bearer abc.def", wantErr: true},
+		{name: "empty token", header: "Bearer ", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			got, err := extractFirebaseToken(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractFirebaseToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("extractFirebaseToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasClubRole(t *testing.T) {
+	club := &Club{Members: []Member{
+		{ID: "member", Role: roleMember},
+		{ID: "admin", Role: roleAdmin},
+		{ID: "owner", Role: roleOwner},
+		{ID: "unknown", Role: "superuser"},
+		{ID: "norole"},
+	}}
+
+	tests := []struct {
+		name string
+		club *Club
+		uid  string
+		role string
+		want bool
+	}{
+		{name: "member as member", club: club, uid: "member", role: roleMember, want: true},
+		{name: "member as admin", club: club, uid: "member", role: roleAdmin, want: false},
+		{name: "member as owner", club: club, uid: "member", role: roleOwner, want: false},
+		{name: "admin as member", club: club, uid: "admin", role: roleMember, want: true},
+		{name: "admin as admin", club: club, uid: "admin", role: roleAdmin, want: true},
+		{name: "admin as owner", club: club, uid: "admin", role: roleOwner, want: false},
+		{name: "owner as admin", club: club, uid: "owner", role: roleAdmin, want: true},
+		{name: "owner as owner", club: club, uid: "owner", role: roleOwner, want: true},
+		{name: "unknown role as member", club: club, uid: "unknown", role: roleMember, want: false},
+		{name: "empty role as member", club: club, uid: "norole", role: roleMember, want: true},
+		{name: "empty role as admin", club: club, uid: "norole", role: roleAdmin, want: false},
+		{name: "non-member", club: club, uid: "stranger", role: roleMember, want: false},
+		{name: "empty uid", club: club, uid: "", role: roleMember, want: false},
+		{name: "missing club", club: &Club{}, uid: "member", role: roleMember, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasClubRole(tt.club, tt.uid, tt.role); got != tt.want {
+				t.Errorf("hasClubRole(%q, %q) = %v, want %v", tt.uid, tt.role, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireClubRole(t *testing.T) {
+	tests := []struct {
+		name          string
+		clubID        string
+		uid           string
+		role          string
+		wantForbidden bool
+		wantErr       bool
+	}{
+		{name: "member", clubID: "club1", uid: "member", role: roleMember},
+		{name: "admin", clubID: "club1", uid: "admin", role: roleAdmin},
+		{name: "owner as admin", clubID: "club1", uid: "owner", role: roleAdmin},
+		{name: "member as admin", clubID: "club1", uid: "member", role: roleAdmin, wantForbidden: true, wantErr: true},
+		{name: "unknown role", clubID: "club1", uid: "unknown", role: roleMember, wantForbidden: true, wantErr: true},
+		{name: "empty role", clubID: "club1", uid: "norole", role: roleMember},
+		{name: "non-member", clubID: "club1", uid: "stranger", role: roleMember, wantForbidden: true, wantErr: true},
+		{name: "missing club", clubID: "missing", uid: "member", role: roleMember, wantForbidden: true, wantErr: true},
+		{name: "load failure", clubID: "broken", uid: "member", role: roleMember, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := requireClubRole(tt.clubID, tt.role)(context.Background(), &Principal{UID: tt.uid})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("requireClubRole() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := errors.Is(err, errForbidden); got != tt.wantForbidden {
+				t.Errorf("errors.Is(err, errForbidden) = %v, want %v (err: %v)", got, tt.wantForbidden, err)
+			}
+		})
+	}
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal Principal
+		wantErr   bool
+	}{
+		{name: "verified", principal: Principal{UID: "u", Email: "a@example.com", EmailVerified: true}},
+		{name: "unverified", principal: Principal{UID: "u", Email: "a@example.com"}, wantErr: true},
+		{name: "empty email", principal: Principal{UID: "u", EmailVerified: true}, wantErr: true},
+		{name: "no email claims", principal: Principal{UID: "u"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := requireVerifiedEmail()(context.Background(), &tt.principal)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("requireVerifiedEmail() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, errEmailNotVerified) {
+				t.Errorf("requireVerifiedEmail() error = %v, want errEmailNotVerified", err)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	allow := func(context.Context, *Principal) error { return nil }
+	deny := func(err error) Policy {
+		return func(context.Context, *Principal) error { return err }
+	}
+	verified := &Principal{UID: "admin", Email: "a@example.com", EmailVerified: true}
+
+	tests := []struct {
+		name       string
+		principal  *Principal
+		policies   []Policy
+		wantOK     bool
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "no policies", principal: verified, wantOK: true, wantStatus: http.StatusOK},
+		{name: "all allow", principal: verified, policies: []Policy{allow, allow}, wantOK: true, wantStatus: http.StatusOK},
+		{name: "no principal", policies: []Policy{allow}, wantStatus: http.StatusUnauthorized, wantCode: errCodeUnauthorized},
+		{name: "forbidden", principal: verified, policies: []Policy{deny(fmt.Errorf("%w: admin role required", errForbidden))},
+			wantStatus: http.StatusForbidden, wantCode: errCodeForbidden},
+		{name: "email not verified", principal: verified, policies: []Policy{deny(errEmailNotVerified)},
+			wantStatus: http.StatusForbidden, wantCode: errCodeEmailNotVerified},
+		{name: "load failure", principal: verified, policies: []Policy{deny(errors.New("failed to load club"))},
+			wantStatus: http.StatusInternalServerError, wantCode: errCodeInternal},
+		{name: "stops at first denial", principal: verified, policies: []Policy{allow, deny(errEmailNotVerified), deny(errors.New("unreached"))},
+			wantStatus: http.StatusForbidden, wantCode: errCodeEmailNotVerified},
+		{name: "club role member as admin", principal: &Principal{UID: "member"}, policies: []Policy{requireClubRole("club1", roleAdmin)},
+			wantStatus: http.StatusForbidden, wantCode: errCodeForbidden},
+		{name: "club role verified admin", principal: verified, policies: []Policy{requireClubRole("club1", roleAdmin), requireVerifiedEmail()},
+			wantOK: true, wantStatus: http.StatusOK},
+		{name: "club role unverified admin", principal: &Principal{UID: "admin", Email: "a@example.com"},
+			policies:   []Policy{requireClubRole("club1", roleAdmin), requireVerifiedEmail()},
+			wantStatus: http.StatusForbidden, wantCode: errCodeEmailNotVerified},
+		{name: "club load failure", principal: verified, policies: []Policy{requireClubRole("broken", roleMember)},
+			wantStatus: http.StatusInternalServerError, wantCode: errCodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tt.principal != nil {
+				r = r.WithContext(context.WithValue(r.Context(), principalContextKey{}, tt.principal))
+			}
+			w := httptest.NewRecorder()
+
+			if got := authorize(w, r, tt.policies...); got != tt.wantOK {
+				t.Errorf("authorize() = %v, want %v", got, tt.wantOK)
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			var response ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode error response %q: %v", w.Body.String(), err)
+			}
+			if response.Error.Code != tt.wantCode {
+				t.Errorf("error code = %q, want %q", response.Error.Code, tt.wantCode)
+			}
+		})
+	}
+}
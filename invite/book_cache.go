@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dhvogel/bookclurb-invite/hardcover"
+)
+
+// firebaseBookCacheStore persists ISBN lookups at hardcoverBooks/{isbn13} so they
+// survive restarts and are shared between instances
+type firebaseBookCacheStore struct{}
+
+// cachedBookID is the stored form of a hardcover.BookCacheEntry
+type cachedBookID struct {
+	BookID    int   `json:"bookId"` // 0 if no book has the ISBN
+	ExpiresAt int64 `json:"expiresAt"`
+}
+
+func (firebaseBookCacheStore) LoadBookID(ctx context.Context, isbn13 string) (hardcover.BookCacheEntry, bool, error) {
+	var cached *cachedBookID
+	if err := firebaseGet(ctx, "hardcover_books", firebaseDB.NewRef(fmt.Sprintf("hardcoverBooks/%s", isbn13)), &cached); err != nil {
+		return hardcover.BookCacheEntry{}, false, err
+	}
+	if cached == nil {
+		return hardcover.BookCacheEntry{}, false, nil
+	}
+	return hardcover.BookCacheEntry{BookID: cached.BookID, Expires: time.UnixMilli(cached.ExpiresAt)}, true, nil
+}
+
+func (firebaseBookCacheStore) SaveBookID(ctx context.Context, isbn13 string, entry hardcover.BookCacheEntry) error {
+	return firebaseUpdate(ctx, "hardcover_books", firebaseDB.NewRef(fmt.Sprintf("hardcoverBooks/%s", isbn13)), map[string]interface{}{
+		"bookId":    entry.BookID,
+		"expiresAt": entry.Expires.UnixMilli(),
+	})
+}
+
+// loadBookCacheConfig sets up caching of ISBN lookups on the Hardcover client
+func loadBookCacheConfig() {
+	cache := hardcover.NewBookCache(getEnvInt("HARDCOVER_BOOK_CACHE_SIZE", hardcover.DefaultBookCacheSize))
+	if cache == nil {
+		slog.Info("Hardcover book cache disabled")
+		return
+	}
+	cache.TTL = time.Duration(getEnvInt("HARDCOVER_BOOK_CACHE_TTL_HOURS", int(hardcover.DefaultBookCacheTTL/time.Hour))) * time.Hour
+	cache.NegativeTTL = time.Duration(getEnvInt("HARDCOVER_BOOK_CACHE_NEGATIVE_TTL_MINUTES", int(hardcover.DefaultBookCacheNegativeTTL/time.Minute))) * time.Minute
+	cache.Observe = recordBookCacheLookup
+	if getEnv("HARDCOVER_BOOK_CACHE_FIREBASE", "false") == "true" {
+		cache.Store = firebaseBookCacheStore{}
+	}
+	hardcoverClient.Books = cache
+	slog.Info("Hardcover book cache enabled", "ttl", cache.TTL, "negativeTtl", cache.NegativeTTL, "firebase", cache.Store != nil)
+}
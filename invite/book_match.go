@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dhvogel/bookclurb-invite/hardcover"
+	"github.com/dhvogel/bookclurb-invite/isbn"
+)
+
+// Title/author search fallback tuning. A search result is picked without asking the
+// user only if it scores at least bookMatchMinScore and beats the runner-up by
+// bookMatchMinMargin, or ties it but has bookMatchPopularityRatio times its readers.
+// Results under bookMatchMinCandidate are not offered at all.
+const (
+	bookSearchResults        = 10
+	maxBookCandidates        = 5
+	bookMatchMinScore        = 0.85
+	bookMatchMinMargin       = 0.1
+	bookMatchPopularityRatio = 10
+	bookMatchMinCandidate    = 0.4
+)
+
+// bookRef identifies a book to sync: by ISBN with the title and author as a fallback,
+// or by a Hardcover book ID the user picked from candidates
+type bookRef struct {
+	ISBN            string
+	Title           string
+	Author          string
+	HardcoverBookID int
+}
+
+// BookMatch holds the optional fields of a sync request that identify the book when
+// its ISBN is missing or not catalogued on Hardcover
+type BookMatch struct {
+	Title           string `json:"title,omitempty"`
+	Author          string `json:"author,omitempty"`
+	HardcoverBookID int    `json:"hardcoverBookId,omitempty"` // a candidate the user picked
+}
+
+// validate requires an ISBN unless the title or a Hardcover book ID is given
+func (m *BookMatch) validate(v *validator, isbn string) {
+	switch {
+	case isbn != "":
+		v.isbn("isbn", isbn)
+	case m.Title == "" && m.HardcoverBookID == 0:
+		v.add("isbn", "is required unless title or hardcoverBookId is given")
+	}
+	if m.HardcoverBookID < 0 {
+		v.add("hardcoverBookId", "must be a positive number")
+	}
+	v.maxLength("title", m.Title, maxTitleLength)
+	v.maxLength("author", m.Author, maxNameLength)
+}
+
+// ref returns the bookRef for isbn and the match fields
+func (m *BookMatch) ref(isbn string) bookRef {
+	return bookRef{ISBN: normalizeISBN(isbn), Title: m.Title, Author: m.Author, HardcoverBookID: m.HardcoverBookID}
+}
+
+// BookCandidate is a possible Hardcover match offered to the user
+type BookCandidate struct {
+	ID          int      `json:"id"`
+	Title       string   `json:"title"`
+	Authors     []string `json:"authors"`
+	ReleaseYear int      `json:"releaseYear,omitempty"`
+	Readers     int      `json:"readers,omitempty"` // Hardcover users who shelved it
+	Score       float64  `json:"score"`
+}
+
+// BookCandidatesDetails is returned in APIError.Details for hardcover_book_ambiguous
+// errors. Retry the request with hardcoverBookId set to the chosen candidate.
+type BookCandidatesDetails struct {
+	Candidates []BookCandidate `json:"candidates"`
+}
+
+// ambiguousBookError is returned when a title/author search has no confident match
+type ambiguousBookError struct {
+	Candidates []BookCandidate
+}
+
+func (e *ambiguousBookError) Error() string {
+	return fmt.Sprintf("%d possible Hardcover matches", len(e.Candidates))
+}
+
+// resolveHardcoverBook returns the Hardcover book ID for ref. When the ISBN is not
+// catalogued on Hardcover it searches by title and author, returning an
+// *ambiguousBookError with candidates if no result is a confident match.
+func resolveHardcoverBook(ctx context.Context, token string, ref bookRef) (int, error) {
+	if ref.HardcoverBookID > 0 {
+		return ref.HardcoverBookID, nil
+	}
+
+	if ref.ISBN != "" {
+		bookID, err := hardcoverClient.FindBookIDByISBN(ctx, token, ref.ISBN)
+		lookupFailed := errors.Is(err, hardcover.ErrNotFound) || errors.Is(err, isbn.ErrInvalid)
+		if err == nil || !lookupFailed || ref.Title == "" {
+			return bookID, err
+		}
+		slog.InfoContext(ctx, "ISBN not found on Hardcover, searching by title", "isbn", ref.ISBN, "title", ref.Title, "error", err)
+	}
+	if ref.Title == "" {
+		return 0, fmt.Errorf("%w: no ISBN or title to look up", hardcover.ErrNotFound)
+	}
+
+	results, err := hardcoverClient.SearchBooks(ctx, token, strings.TrimSpace(ref.Title+" "+ref.Author), bookSearchResults)
+	if err != nil {
+		return 0, fmt.Errorf("book search failed: %w", err)
+	}
+	candidates := scoreBookCandidates(ref.Title, ref.Author, results)
+	if len(candidates) == 0 {
+		return 0, fmt.Errorf("%w: no search match for %q", hardcover.ErrNotFound, ref.Title)
+	}
+
+	if best, ok := confidentMatch(candidates); ok {
+		slog.InfoContext(ctx, "Matched Hardcover book by title", "title", ref.Title, "bookId", best.ID, "score", best.Score)
+		return best.ID, nil
+	}
+	return 0, &ambiguousBookError{Candidates: candidates}
+}
+
+// confidentMatch returns the best candidate if it is clearly the book that was meant
+func confidentMatch(candidates []BookCandidate) (BookCandidate, bool) {
+	best := candidates[0]
+	if best.Score < bookMatchMinScore {
+		return best, false
+	}
+	if len(candidates) == 1 {
+		return best, true
+	}
+	runnerUp := candidates[1]
+	if best.Score-runnerUp.Score >= bookMatchMinMargin {
+		return best, true
+	}
+	// Reprints and special editions often match as well as the original; the one
+	// most readers shelved is the one meant
+	return best, best.Readers >= bookMatchPopularityRatio*max(runnerUp.Readers, 1)
+}
+
+// scoreBookCandidates scores search results against the wanted title and author and
+// returns the plausible ones, best first
+func scoreBookCandidates(title, author string, results []hardcover.BookCandidate) []BookCandidate {
+	candidates := []BookCandidate{}
+	for _, result := range results {
+		score := titleSimilarity(title, result.Title)
+		if author != "" {
+			score = 0.7*score + 0.3*authorSimilarity(author, result.Authors)
+		}
+		if score < bookMatchMinCandidate {
+			continue
+		}
+		candidates = append(candidates, BookCandidate{
+			ID:          result.ID,
+			Title:       result.Title,
+			Authors:     result.Authors,
+			ReleaseYear: result.ReleaseYear,
+			Readers:     result.UsersCount,
+			Score:       float64(int(score*100)) / 100,
+		})
+	}
+
+	// Among equal scores, the edition most readers shelved is the likely one
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].Readers > candidates[j].Readers
+	})
+	if len(candidates) > maxBookCandidates {
+		candidates = candidates[:maxBookCandidates]
+	}
+	return candidates
+}
+
+var nonWordPattern = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// matchWords splits s into lowercase words, dropping punctuation and articles
+func matchWords(s string) []string {
+	var words []string
+	for _, word := range nonWordPattern.Split(strings.ToLower(s), -1) {
+		switch word {
+		case "", "the", "a", "an":
+			continue
+		}
+		words = append(words, word)
+	}
+	return words
+}
+
+// wordSimilarity is the Dice coefficient of the word sets of a and b, from 0 to 1
+func wordSimilarity(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	set := map[string]bool{}
+	for _, word := range a {
+		set[word] = true
+	}
+	common := 0
+	seen := map[string]bool{}
+	for _, word := range b {
+		if set[word] && !seen[word] {
+			common++
+		}
+		seen[word] = true
+	}
+	return 2 * float64(common) / float64(len(set)+len(seen))
+}
+
+// titleSimilarity compares titles with and without their subtitles, since catalogues
+// disagree on whether to include them
+func titleSimilarity(wanted, candidate string) float64 {
+	best := 0.0
+	for _, w := range []string{wanted, mainTitle(wanted)} {
+		for _, c := range []string{candidate, mainTitle(candidate)} {
+			best = max(best, wordSimilarity(matchWords(w), matchWords(c)))
+		}
+	}
+	return best
+}
+
+// mainTitle drops a subtitle after a colon or parenthesis
+func mainTitle(title string) string {
+	if i := strings.IndexAny(title, ":("); i > 0 {
+		return title[:i]
+	}
+	return title
+}
+
+// authorSimilarity is the best match between any wanted author (comma or "and"
+// separated) and any candidate author. A matching surname counts as a strong match,
+// since initials and middle names vary between catalogues.
+func authorSimilarity(wanted string, candidates []string) float64 {
+	best := 0.0
+	for _, name := range strings.FieldsFunc(strings.ReplaceAll(wanted, " and ", ","), func(r rune) bool { return r == ',' || r == '&' }) {
+		words := matchWords(name)
+		if len(words) == 0 {
+			continue
+		}
+		for _, candidate := range candidates {
+			candidateWords := matchWords(candidate)
+			score := wordSimilarity(words, candidateWords)
+			if len(candidateWords) > 0 && words[len(words)-1] == candidateWords[len(candidateWords)-1] {
+				score = max(score, 0.8)
+			}
+			best = max(best, score)
+		}
+	}
+	return best
+}
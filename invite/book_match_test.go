@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/dhvogel/bookclurb-invite/hardcover"
+)
+
+func TestScoreBookCandidates(t *testing.T) {
+	dune := []hardcover.BookCandidate{
+		{ID: 3, Title: "The Dune Encyclopedia", Authors: []string{"Willis E. McNelly"}, UsersCount: 10},
+		{ID: 2, Title: "Dune Messiah", Authors: []string{"Frank Herbert"}, UsersCount: 500},
+		{ID: 1, Title: "Dune", Authors: []string{"Frank Herbert"}, UsersCount: 1000},
+		{ID: 4, Title: "Middlemarch", Authors: []string{"George Eliot"}, UsersCount: 800},
+	}
+	editions := func(readers ...int) []hardcover.BookCandidate {
+		var results []hardcover.BookCandidate
+		for i, n := range readers {
+			results = append(results, hardcover.BookCandidate{ID: 10 + i, Title: "Emma", Authors: []string{"Jane Austen"}, UsersCount: n})
+		}
+		return results
+	}
+
+	tests := []struct {
+		name          string
+		title         string
+		author        string
+		results       []hardcover.BookCandidate
+		wantIDs       []int
+		wantScores    []float64
+		wantConfident int // the candidate picked without asking, or 0 if the user must choose
+	}{
+		{
+			name: "confident match", title: "Dune", author: "Frank Herbert", results: dune,
+			wantIDs: []int{1, 2, 3}, wantScores: []float64{1, 0.76, 0.46}, wantConfident: 1,
+		},
+		{
+			name: "title only", title: "Dune", results: dune,
+			wantIDs: []int{1, 2, 3}, wantScores: []float64{1, 0.66, 0.66}, wantConfident: 1,
+		},
+		{
+			name: "subtitle ignored", title: "Sapiens", author: "Yuval Noah Harari",
+			results: []hardcover.BookCandidate{{ID: 5, Title: "Sapiens: A Brief History of Humankind", Authors: []string{"Yuval Noah Harari"}}},
+			wantIDs: []int{5}, wantScores: []float64{1}, wantConfident: 5,
+		},
+		{
+			name: "surname matches", title: "Emma", author: "J. Austen", results: editions(100),
+			wantIDs: []int{10}, wantScores: []float64{0.94}, wantConfident: 10,
+		},
+		{
+			name: "ambiguous editions", title: "Emma", author: "Jane Austen", results: editions(50, 100),
+			wantIDs: []int{11, 10}, wantScores: []float64{1, 1},
+		},
+		{
+			name: "popular edition", title: "Emma", author: "Jane Austen", results: editions(40, 5000),
+			wantIDs: []int{11, 10}, wantScores: []float64{1, 1}, wantConfident: 11,
+		},
+		{
+			name: "wrong author", title: "Emma", author: "Jane Austen",
+			results: []hardcover.BookCandidate{{ID: 6, Title: "Emma", Authors: []string{"Alexander McCall Smith"}, UsersCount: 300}},
+			wantIDs: []int{6}, wantScores: []float64{0.7},
+		},
+		{
+			name: "no plausible result", title: "Dune", author: "Frank Herbert",
+			results: []hardcover.BookCandidate{{ID: 4, Title: "Middlemarch", Authors: []string{"George Eliot"}}},
+			wantIDs: nil,
+		},
+		{
+			name: "at most five candidates", title: "Emma", author: "Jane Austen", results: editions(1, 2, 3, 4, 5, 6, 7),
+			wantIDs: []int{16, 15, 14, 13, 12}, wantScores: []float64{1, 1, 1, 1, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates := scoreBookCandidates(tt.title, tt.author, tt.results)
+			var ids []int
+			var scores []float64
+			for _, candidate := range candidates {
+				ids = append(ids, candidate.ID)
+				scores = append(scores, candidate.Score)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) || !reflect.DeepEqual(scores, tt.wantScores) {
+				t.Fatalf("candidates = %v with scores %v, want %v with %v", ids, scores, tt.wantIDs, tt.wantScores)
+			}
+			if len(candidates) == 0 {
+				return
+			}
+
+			confident := 0
+			if best, ok := confidentMatch(candidates); ok {
+				confident = best.ID
+			}
+			if confident != tt.wantConfident {
+				t.Errorf("confident match = %d, want %d", confident, tt.wantConfident)
+			}
+		})
+	}
+}
+
+func TestAuthorSimilarity(t *testing.T) {
+	tests := []struct {
+		wanted     string
+		candidates []string
+		want       float64
+	}{
+		{wanted: "Frank Herbert", candidates: []string{"Frank Herbert"}, want: 1},
+		{wanted: "F. Herbert", candidates: []string{"Frank Herbert"}, want: 0.8},
+		{wanted: "Terry Pratchett and Neil Gaiman", candidates: []string{"Neil Gaiman"}, want: 1},
+		{wanted: "Terry Pratchett & Neil Gaiman", candidates: []string{"Terry Pratchett"}, want: 1},
+		{wanted: "Jane Austen", candidates: []string{"Alexander McCall Smith"}, want: 0},
+		{wanted: "Jane Austen", candidates: nil, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%v", tt.wanted, tt.candidates), func(t *testing.T) {
+			if got := authorSimilarity(tt.wanted, tt.candidates); got != tt.want {
+				t.Errorf("authorSimilarity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
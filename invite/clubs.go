@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"firebase.google.com/go/v4/db"
+)
+
+// Club membership is written only by this service: the database rules deny client
+// writes to clubs/{clubId}/members, memberCount and memberRoles, so joining, leaving
+// and role changes go through the endpoints below.
+
+var (
+	// errLastAdmin is returned when a change would leave a club with members but no admin
+	errLastAdmin = errors.New("club must keep at least one admin")
+	// errMemberNotFound is returned when changing the role of someone who is not a member
+	errMemberNotFound = errors.New("not a member of the club")
+	// errOwnerRole is returned when changing the role of a club owner
+	errOwnerRole = errors.New("the club owner's role cannot be changed")
+)
+
+// ClubMembershipResponse is returned by the membership endpoints
+type ClubMembershipResponse struct {
+	Success bool   `json:"success"`
+	ClubID  string `json:"clubId"`
+	UserID  string `json:"userId"`
+	Role    string `json:"role,omitempty"` // the member's role, empty after leaving
+}
+
+// UpdateMemberRoleRequest is the body of PUT /v1/clubs/{clubId}/members/{userId}/role
+type UpdateMemberRoleRequest struct {
+	Role string `json:"role"` // "member" or "admin"
+}
+
+func (req *UpdateMemberRoleRequest) validate(v *validator) {
+	if v.required("role", req.Role) && req.Role != roleMember && req.Role != roleAdmin {
+		v.add("role", "must be member or admin")
+	}
+}
+
+// DeleteClubResponse is returned after deleting a club
+type DeleteClubResponse struct {
+	Success bool   `json:"success"`
+	ClubID  string `json:"clubId"`
+}
+
+// decodeMembers reads a members node. Members are written as an array, but older
+// clubs may store them as an object keyed by push ID; those are returned in key order.
+func decodeMembers(node db.TransactionNode) ([]map[string]interface{}, error) {
+	var raw interface{}
+	if err := node.Unmarshal(&raw); err != nil {
+		return nil, err
+	}
+
+	var entries []interface{}
+	switch value := raw.(type) {
+	case []interface{}:
+		entries = value
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			entries = append(entries, value[key])
+		}
+	}
+
+	members := make([]map[string]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		if member, ok := entry.(map[string]interface{}); ok {
+			members = append(members, member)
+		}
+	}
+	return members, nil
+}
+
+// rawMemberRole returns the role of a member read by decodeMembers
+func rawMemberRole(member map[string]interface{}) string {
+	role, _ := member["role"].(string)
+	if role == "" {
+		return roleMember
+	}
+	return role
+}
+
+// countAdmins counts the members holding at least the admin role
+func countAdmins(members []map[string]interface{}) int {
+	admins := 0
+	for _, member := range members {
+		if clubRoleRank[rawMemberRole(member)] >= clubRoleRank[roleAdmin] {
+			admins++
+		}
+	}
+	return admins
+}
+
+// memberFromPrincipal builds the member record for a user joining a club
+func memberFromPrincipal(p *Principal) map[string]interface{} {
+	name, _ := p.Token.Claims["name"].(string)
+	if name == "" {
+		name = p.Email
+	}
+	picture, _ := p.Token.Claims["picture"].(string)
+	return map[string]interface{}{
+		"id":       p.UID,
+		"name":     name,
+		"img":      picture,
+		"role":     roleMember,
+		"joinedAt": time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// memberRoles maps each member's ID to their role. The database rules read it to
+// decide who may read a club and write its fields, since they cannot search the
+// members array.
+func memberRoles(members []map[string]interface{}) map[string]interface{} {
+	roles := make(map[string]interface{}, len(members))
+	for _, member := range members {
+		if uid, _ := member["id"].(string); uid != "" {
+			roles[uid] = rawMemberRole(member)
+		}
+	}
+	return roles
+}
+
+// updateClubMembers rewrites a club's members in a transaction, so concurrent joins
+// and leaves are not lost, and then stores the new member count and memberRoles. Raw
+// maps preserve fields Member does not model.
+func updateClubMembers(ctx context.Context, clubID string, fn func([]map[string]interface{}) ([]map[string]interface{}, error)) error {
+	clubRef := firebaseDB.NewRef(fmt.Sprintf("clubs/%s", clubID))
+	var before int
+	var updated []map[string]interface{}
+	err := firebaseTransaction(ctx, "clubs", clubRef.Child("members"), func(node db.TransactionNode) (interface{}, error) {
+		members, err := decodeMembers(node)
+		if err != nil {
+			return nil, err
+		}
+		before = len(members)
+		if updated, err = fn(members); err != nil {
+			return nil, err
+		}
+		return updated, nil
+	})
+	if err != nil || before == 0 && len(updated) == 0 {
+		// Nothing to count, and writing memberCount would recreate a deleted club
+		return err
+	}
+
+	// memberRoles gates access in the database rules, so a failed write is an error
+	// rather than a stale count; rewriting the whole map makes a retry repair it
+	err = firebaseUpdate(ctx, "clubs", clubRef, map[string]interface{}{
+		"memberCount": len(updated),
+		"memberRoles": memberRoles(updated),
+	})
+	if err != nil {
+		return fmt.Errorf("updating member roles: %w", err)
+	}
+	return nil
+}
+
+// addClubMember adds member to the club unless a member with the same ID exists, and
+// adds the club to the member's clubs
+func addClubMember(ctx context.Context, clubID string, member map[string]interface{}) error {
+	err := updateClubMembers(ctx, clubID, func(members []map[string]interface{}) ([]map[string]interface{}, error) {
+		for _, existing := range members {
+			if existing["id"] == member["id"] {
+				return members, nil
+			}
+		}
+		return append(members, member), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	uid, _ := member["id"].(string)
+	if err := updateUserClubs(ctx, uid, clubID, true); err != nil {
+		slog.WarnContext(ctx, "Failed to add club to user", "uid", uid, "clubId", clubID, "error", err)
+	}
+	return nil
+}
+
+// removeClubMember removes uid from the club and the club from the user's clubs. The
+// last admin cannot leave while other members remain.
+func removeClubMember(ctx context.Context, clubID, uid string) error {
+	err := updateClubMembers(ctx, clubID, func(members []map[string]interface{}) ([]map[string]interface{}, error) {
+		remaining := make([]map[string]interface{}, 0, len(members))
+		for _, member := range members {
+			if member["id"] != uid {
+				remaining = append(remaining, member)
+			}
+		}
+		if len(remaining) > 0 && countAdmins(remaining) == 0 && countAdmins(members) > 0 {
+			return nil, errLastAdmin
+		}
+		return remaining, nil
+	})
+	if err != nil {
+		return err
+	}
+	return updateUserClubs(ctx, uid, clubID, false)
+}
+
+// setClubMemberRole changes the role of uid in the club
+func setClubMemberRole(ctx context.Context, clubID, uid, role string) error {
+	return updateClubMembers(ctx, clubID, func(members []map[string]interface{}) ([]map[string]interface{}, error) {
+		found := false
+		for _, member := range members {
+			if member["id"] != uid {
+				continue
+			}
+			if rawMemberRole(member) == roleOwner {
+				return nil, errOwnerRole
+			}
+			member["role"] = role
+			found = true
+		}
+		if !found {
+			return nil, errMemberNotFound
+		}
+		if countAdmins(members) == 0 {
+			return nil, errLastAdmin
+		}
+		return members, nil
+	})
+}
+
+// updateUserClubs adds clubID to, or removes it from, the user's list of clubs
+func updateUserClubs(ctx context.Context, uid, clubID string, member bool) error {
+	userClubsRef := firebaseDB.NewRef(fmt.Sprintf("users/%s/clubs", uid))
+	return firebaseTransaction(ctx, "users", userClubsRef, func(node db.TransactionNode) (interface{}, error) {
+		var clubs []string
+		if err := node.Unmarshal(&clubs); err != nil {
+			return nil, err
+		}
+		updated := make([]string, 0, len(clubs))
+		for _, id := range clubs {
+			if id != clubID {
+				updated = append(updated, id)
+			}
+		}
+		if !member {
+			return updated, nil
+		}
+		if len(updated) < len(clubs) {
+			return clubs, nil
+		}
+		return append(clubs, clubID), nil
+	})
+}
+
+// joinClubHandler adds the caller to a public club
+func joinClubHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	principal := principalFromContext(ctx)
+	clubID := r.PathValue("clubId")
+
+	var club struct {
+		Name     string `json:"name"`
+		IsPublic bool   `json:"isPublic"`
+	}
+	if err := firebaseGet(ctx, "clubs", firebaseDB.NewRef(fmt.Sprintf("clubs/%s", clubID)), &club); err != nil {
+		slog.ErrorContext(ctx, "Failed to load club", "clubId", clubID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to load club", nil)
+		return
+	}
+	if club.Name == "" {
+		writeError(w, r, http.StatusNotFound, errCodeNotFound, "Club not found", nil)
+		return
+	}
+	if !club.IsPublic {
+		writeError(w, r, http.StatusForbidden, errCodeForbidden, "This club is private; joining requires an invite", nil)
+		return
+	}
+
+	if err := addClubMember(ctx, clubID, memberFromPrincipal(principal)); err != nil {
+		slog.ErrorContext(ctx, "Failed to add club member", "uid", principal.UID, "clubId", clubID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to join club", nil)
+		return
+	}
+
+	slog.InfoContext(ctx, "Joined public club", "uid", principal.UID, "clubId", clubID)
+	writeJSON(w, http.StatusOK, ClubMembershipResponse{Success: true, ClubID: clubID, UserID: principal.UID, Role: roleMember})
+}
+
+// leaveClubHandler removes the caller from a club. Leaving a club the caller is not a
+// member of only removes it from their list of clubs.
+func leaveClubHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	principal := principalFromContext(ctx)
+	clubID := r.PathValue("clubId")
+
+	if err := removeClubMember(ctx, clubID, principal.UID); err != nil {
+		if errors.Is(err, errLastAdmin) {
+			writeError(w, r, http.StatusConflict, errCodeLastAdmin, "Make another member an admin before leaving the club", nil)
+			return
+		}
+		slog.ErrorContext(ctx, "Failed to remove club member", "uid", principal.UID, "clubId", clubID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to leave club", nil)
+		return
+	}
+
+	slog.InfoContext(ctx, "Left club", "uid", principal.UID, "clubId", clubID)
+	writeJSON(w, http.StatusOK, ClubMembershipResponse{Success: true, ClubID: clubID, UserID: principal.UID})
+}
+
+// updateMemberRoleHandler makes a member an admin or a plain member. Only verified
+// club admins may call it, and a club always keeps at least one admin.
+func updateMemberRoleHandler(w http.ResponseWriter, r *http.Request) {
+	var req UpdateMemberRoleRequest
+	if !decodeJSON(w, r, &req) || !validateRequest(w, r, &req) {
+		return
+	}
+
+	ctx := r.Context()
+	clubID := r.PathValue("clubId")
+	userID := r.PathValue("userId")
+	if !authorize(w, r, requireClubRole(clubID, roleAdmin), requireVerifiedEmail()) {
+		return
+	}
+
+	if err := setClubMemberRole(ctx, clubID, userID, req.Role); err != nil {
+		switch {
+		case errors.Is(err, errMemberNotFound):
+			writeError(w, r, http.StatusNotFound, errCodeNotFound, "Member not found", nil)
+		case errors.Is(err, errOwnerRole):
+			writeError(w, r, http.StatusForbidden, errCodeForbidden, "The club owner's role cannot be changed", nil)
+		case errors.Is(err, errLastAdmin):
+			writeError(w, r, http.StatusConflict, errCodeLastAdmin, "Cannot remove the last admin from the club", nil)
+		default:
+			slog.ErrorContext(ctx, "Failed to update member role", "clubId", clubID, "memberId", userID, "error", err)
+			writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to update member role", nil)
+		}
+		return
+	}
+
+	slog.InfoContext(ctx, "Updated member role", "clubId", clubID, "memberId", userID, "role", req.Role,
+		"uid", principalFromContext(ctx).UID)
+	writeJSON(w, http.StatusOK, ClubMembershipResponse{Success: true, ClubID: clubID, UserID: userID, Role: req.Role})
+}
+
+// deleteClubHandler deletes a club after removing it from every member's clubs. Only
+// verified club admins may call it.
+func deleteClubHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	clubID := r.PathValue("clubId")
+	if !authorize(w, r, requireClubRole(clubID, roleAdmin), requireVerifiedEmail()) {
+		return
+	}
+
+	club, err := getClub(ctx, clubID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load club", "clubId", clubID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to delete club", nil)
+		return
+	}
+	for _, member := range club.Members {
+		if member.ID == "" {
+			continue
+		}
+		if err := updateUserClubs(ctx, member.ID, clubID, false); err != nil {
+			slog.ErrorContext(ctx, "Failed to remove club from member", "clubId", clubID, "memberId", member.ID, "error", err)
+			writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to delete club", nil)
+			return
+		}
+	}
+
+	if err := firebaseDelete(ctx, "clubs", firebaseDB.NewRef(fmt.Sprintf("clubs/%s", clubID))); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete club", "clubId", clubID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to delete club", nil)
+		return
+	}
+
+	slog.InfoContext(ctx, "Deleted club", "clubId", clubID, "uid", principalFromContext(ctx).UID, "members", len(club.Members))
+	writeJSON(w, http.StatusOK, DeleteClubResponse{Success: true, ClubID: clubID})
+}
@@ -0,0 +1,482 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+// testPrincipal returns a signed-in user; verified users have a verified email
+func testPrincipal(uid string, verified bool) *Principal {
+	return &Principal{
+		UID:           uid,
+		Email:         uid + "@example.com",
+		EmailVerified: verified,
+		Token:         &auth.Token{UID: uid, Claims: map[string]interface{}{"name": "User " + uid}},
+	}
+}
+
+// serveClubRequest calls handler as principal with the path values of a /v1 route
+// and returns the recorded response
+func serveClubRequest(handler http.HandlerFunc, method, body string, principal *Principal, pathValues map[string]string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	r := httptest.NewRequest(method, "/", reader)
+	for name, value := range pathValues {
+		r.SetPathValue(name, value)
+	}
+	if principal != nil {
+		r = r.WithContext(context.WithValue(r.Context(), principalContextKey{}, principal))
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+// errorCode returns the error code of a JSON error response, or "" for success
+func errorCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	if w.Code < 400 {
+		return ""
+	}
+	var response ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode error response %q: %v", w.Body.String(), err)
+	}
+	return response.Error.Code
+}
+
+// seedClub stores a club with the given members and matching memberRoles, and adds
+// the club to each member's clubs
+func seedClub(t *testing.T, clubID string, public bool, members ...Member) {
+	t.Helper()
+	raw := make([]map[string]interface{}, 0, len(members))
+	for _, member := range members {
+		entry := map[string]interface{}{"id": member.ID, "name": "User " + member.ID}
+		if member.Role != "" {
+			entry["role"] = member.Role
+		}
+		raw = append(raw, entry)
+		testDB.set(t, fmt.Sprintf("users/%s/clubs", member.ID), fmt.Sprintf("[%q]", clubID))
+	}
+	club, _ := json.Marshal(map[string]interface{}{
+		"name":        "Club " + clubID,
+		"isPublic":    public,
+		"members":     raw,
+		"memberCount": len(raw),
+		"memberRoles": memberRoles(raw),
+	})
+	testDB.set(t, "clubs/"+clubID, string(club))
+}
+
+// clubMemberIDs returns the IDs in a club's members, in order
+func clubMemberIDs(clubID string) []string {
+	var ids []string
+	members, _ := testDB.get(fmt.Sprintf("clubs/%s/members", clubID)).([]interface{})
+	for _, member := range members {
+		if m, ok := member.(map[string]interface{}); ok {
+			id, _ := m["id"].(string)
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// userClubIDs returns the clubs listed for a user
+func userClubIDs(uid string) []string {
+	var ids []string
+	clubs, _ := testDB.get(fmt.Sprintf("users/%s/clubs", uid)).([]interface{})
+	for _, club := range clubs {
+		id, _ := club.(string)
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// checkMembership verifies that members, memberCount and memberRoles agree with want,
+// a map from member ID to role
+func checkMembership(t *testing.T, clubID string, want map[string]string) {
+	t.Helper()
+	if ids := clubMemberIDs(clubID); len(ids) != len(want) {
+		t.Errorf("members = %v, want %d members", ids, len(want))
+	}
+	if len(want) == 0 {
+		if count := testDB.get(fmt.Sprintf("clubs/%s/memberCount", clubID)); count != nil && count != float64(0) {
+			t.Errorf("memberCount = %v, want 0", count)
+		}
+	} else if count := testDB.get(fmt.Sprintf("clubs/%s/memberCount", clubID)); count != float64(len(want)) {
+		t.Errorf("memberCount = %v, want %d", count, len(want))
+	}
+
+	roles, _ := testDB.get(fmt.Sprintf("clubs/%s/memberRoles", clubID)).(map[string]interface{})
+	got := make(map[string]string, len(roles))
+	for uid, role := range roles {
+		got[uid], _ = role.(string)
+	}
+	if !reflect.DeepEqual(got, want) && !(len(got) == 0 && len(want) == 0) {
+		t.Errorf("memberRoles = %v, want %v", got, want)
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestMemberRoles(t *testing.T) {
+	tests := []struct {
+		name    string
+		members []map[string]interface{}
+		want    map[string]interface{}
+	}{
+		{name: "no members", want: map[string]interface{}{}},
+		{
+			name: "roles by id",
+			members: []map[string]interface{}{
+				{"id": "a", "role": roleAdmin},
+				{"id": "b", "role": roleMember},
+				{"id": "c", "role": roleOwner},
+			},
+			want: map[string]interface{}{"a": roleAdmin, "b": roleMember, "c": roleOwner},
+		},
+		{
+			name:    "missing role is member",
+			members: []map[string]interface{}{{"id": "a"}},
+			want:    map[string]interface{}{"a": roleMember},
+		},
+		{
+			name:    "members without an id are skipped",
+			members: []map[string]interface{}{{"role": roleAdmin}, {"id": "", "role": roleAdmin}, {"id": 7}},
+			want:    map[string]interface{}{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := memberRoles(tt.members); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("memberRoles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinClubHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		setup      func(t *testing.T, clubID string)
+		uid        string
+		wantStatus int
+		wantCode   string
+		wantRoles  map[string]string // membership after the request, if checked
+	}{
+		{
+			name:       "public club",
+			setup:      func(t *testing.T, clubID string) { seedClub(t, clubID, true, Member{ID: "admin", Role: roleAdmin}) },
+			uid:        "joiner",
+			wantStatus: http.StatusOK,
+			wantRoles:  map[string]string{"admin": roleAdmin, "joiner": roleMember},
+		},
+		{
+			name: "already a member",
+			setup: func(t *testing.T, clubID string) {
+				seedClub(t, clubID, true, Member{ID: "admin", Role: roleAdmin}, Member{ID: "joiner", Role: roleMember})
+			},
+			uid:        "joiner",
+			wantStatus: http.StatusOK,
+			wantRoles:  map[string]string{"admin": roleAdmin, "joiner": roleMember},
+		},
+		{
+			name: "members stored as an object",
+			setup: func(t *testing.T, clubID string) {
+				testDB.set(t, "clubs/"+clubID, `{"name": "Legacy", "isPublic": true,
+					"members": {"-b": {"id": "second"}, "-a": {"id": "first", "role": "admin"}}}`)
+			},
+			uid:        "joiner",
+			wantStatus: http.StatusOK,
+			wantRoles:  map[string]string{"first": roleAdmin, "second": roleMember, "joiner": roleMember},
+		},
+		{
+			name:       "private club",
+			setup:      func(t *testing.T, clubID string) { seedClub(t, clubID, false, Member{ID: "admin", Role: roleAdmin}) },
+			uid:        "joiner",
+			wantStatus: http.StatusForbidden,
+			wantCode:   errCodeForbidden,
+			wantRoles:  map[string]string{"admin": roleAdmin},
+		},
+		{
+			name:       "missing club",
+			setup:      func(t *testing.T, clubID string) {},
+			uid:        "joiner",
+			wantStatus: http.StatusNotFound,
+			wantCode:   errCodeNotFound,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clubID := fmt.Sprintf("join%d", i)
+			tt.setup(t, clubID)
+
+			w := serveClubRequest(joinClubHandler, http.MethodPost, "", testPrincipal(tt.uid, true), map[string]string{"clubId": clubID})
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body: %s)", w.Code, tt.wantStatus, w.Body)
+			}
+			if code := errorCode(t, w); code != tt.wantCode {
+				t.Errorf("error code = %q, want %q", code, tt.wantCode)
+			}
+			if tt.wantRoles != nil {
+				checkMembership(t, clubID, tt.wantRoles)
+			}
+			if joined := containsString(userClubIDs(tt.uid), clubID); joined != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("user clubs %v contain %s = %v", userClubIDs(tt.uid), clubID, joined)
+			}
+		})
+	}
+
+	t.Run("load failure", func(t *testing.T) {
+		w := serveClubRequest(joinClubHandler, http.MethodPost, "", testPrincipal("joiner", true), map[string]string{"clubId": "broken"})
+		if w.Code != http.StatusInternalServerError || errorCode(t, w) != errCodeInternal {
+			t.Errorf("status = %d, body %s; want 500 internal_error", w.Code, w.Body)
+		}
+	})
+}
+
+func TestJoinClubHandlerConcurrent(t *testing.T) {
+	seedClub(t, "joinconcurrent", true, Member{ID: "admin", Role: roleAdmin})
+
+	const joiners = 8
+	want := map[string]string{"admin": roleAdmin}
+	var wg sync.WaitGroup
+	for i := 0; i < joiners; i++ {
+		uid := fmt.Sprintf("joiner%d", i)
+		want[uid] = roleMember
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := serveClubRequest(joinClubHandler, http.MethodPost, "", testPrincipal(uid, true), map[string]string{"clubId": "joinconcurrent"})
+			if w.Code != http.StatusOK {
+				t.Errorf("join as %s: status = %d (body: %s)", uid, w.Code, w.Body)
+			}
+		}()
+	}
+	wg.Wait()
+
+	checkMembership(t, "joinconcurrent", want)
+}
+
+func TestLeaveClubHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		members    []Member
+		uid        string
+		wantStatus int
+		wantCode   string
+		wantRoles  map[string]string
+	}{
+		{
+			name:       "member leaves",
+			members:    []Member{{ID: "admin", Role: roleAdmin}, {ID: "member", Role: roleMember}},
+			uid:        "member",
+			wantStatus: http.StatusOK,
+			wantRoles:  map[string]string{"admin": roleAdmin},
+		},
+		{
+			name:       "admin leaves with another admin",
+			members:    []Member{{ID: "admin", Role: roleAdmin}, {ID: "admin2", Role: roleAdmin}, {ID: "member"}},
+			uid:        "admin",
+			wantStatus: http.StatusOK,
+			wantRoles:  map[string]string{"admin2": roleAdmin, "member": roleMember},
+		},
+		{
+			name:       "last admin with members",
+			members:    []Member{{ID: "admin", Role: roleAdmin}, {ID: "member", Role: roleMember}},
+			uid:        "admin",
+			wantStatus: http.StatusConflict,
+			wantCode:   errCodeLastAdmin,
+			wantRoles:  map[string]string{"admin": roleAdmin, "member": roleMember},
+		},
+		{
+			name:       "last member",
+			members:    []Member{{ID: "admin", Role: roleAdmin}},
+			uid:        "admin",
+			wantStatus: http.StatusOK,
+			wantRoles:  map[string]string{},
+		},
+		{
+			name:       "owner leaves with an admin",
+			members:    []Member{{ID: "owner", Role: roleOwner}, {ID: "admin", Role: roleAdmin}},
+			uid:        "owner",
+			wantStatus: http.StatusOK,
+			wantRoles:  map[string]string{"admin": roleAdmin},
+		},
+		{
+			name:       "not a member",
+			members:    []Member{{ID: "admin", Role: roleAdmin}},
+			uid:        "stranger",
+			wantStatus: http.StatusOK,
+			wantRoles:  map[string]string{"admin": roleAdmin},
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clubID := fmt.Sprintf("leave%d", i)
+			seedClub(t, clubID, false, tt.members...)
+			testDB.set(t, fmt.Sprintf("users/%s/clubs", tt.uid), fmt.Sprintf(`["other", %q]`, clubID))
+
+			w := serveClubRequest(leaveClubHandler, http.MethodDelete, "", testPrincipal(tt.uid, false), map[string]string{"clubId": clubID})
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body: %s)", w.Code, tt.wantStatus, w.Body)
+			}
+			if code := errorCode(t, w); code != tt.wantCode {
+				t.Errorf("error code = %q, want %q", code, tt.wantCode)
+			}
+			checkMembership(t, clubID, tt.wantRoles)
+
+			clubs := userClubIDs(tt.uid)
+			if left := !containsString(clubs, clubID); left != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("user clubs = %v after leaving %s with status %d", clubs, clubID, w.Code)
+			}
+			if !containsString(clubs, "other") {
+				t.Errorf("user clubs = %v, want other clubs kept", clubs)
+			}
+		})
+	}
+}
+
+func TestUpdateMemberRoleHandler(t *testing.T) {
+	members := []Member{
+		{ID: "owner", Role: roleOwner},
+		{ID: "admin", Role: roleAdmin},
+		{ID: "member", Role: roleMember},
+	}
+	unchanged := map[string]string{"owner": roleOwner, "admin": roleAdmin, "member": roleMember}
+
+	tests := []struct {
+		name       string
+		members    []Member
+		caller     *Principal
+		target     string
+		body       string
+		wantStatus int
+		wantCode   string
+		wantRoles  map[string]string
+	}{
+		{
+			name: "promote member", members: members, caller: testPrincipal("admin", true), target: "member",
+			body: `{"role": "admin"}`, wantStatus: http.StatusOK,
+			wantRoles: map[string]string{"owner": roleOwner, "admin": roleAdmin, "member": roleAdmin},
+		},
+		{
+			name: "demote admin", members: members, caller: testPrincipal("owner", true), target: "admin",
+			body: `{"role": "member"}`, wantStatus: http.StatusOK,
+			wantRoles: map[string]string{"owner": roleOwner, "admin": roleMember, "member": roleMember},
+		},
+		{
+			name: "demote self as last admin", members: []Member{{ID: "admin", Role: roleAdmin}, {ID: "member"}},
+			caller: testPrincipal("admin", true), target: "admin", body: `{"role": "member"}`,
+			wantStatus: http.StatusConflict, wantCode: errCodeLastAdmin,
+			wantRoles: map[string]string{"admin": roleAdmin, "member": roleMember},
+		},
+		{
+			name: "owner role", members: members, caller: testPrincipal("admin", true), target: "owner",
+			body: `{"role": "member"}`, wantStatus: http.StatusForbidden, wantCode: errCodeForbidden, wantRoles: unchanged,
+		},
+		{
+			name: "not a member", members: members, caller: testPrincipal("admin", true), target: "stranger",
+			body: `{"role": "admin"}`, wantStatus: http.StatusNotFound, wantCode: errCodeNotFound, wantRoles: unchanged,
+		},
+		{
+			name: "caller is a member", members: members, caller: testPrincipal("member", true), target: "member",
+			body: `{"role": "admin"}`, wantStatus: http.StatusForbidden, wantCode: errCodeForbidden, wantRoles: unchanged,
+		},
+		{
+			name: "caller is not in the club", members: members, caller: testPrincipal("stranger", true), target: "member",
+			body: `{"role": "admin"}`, wantStatus: http.StatusForbidden, wantCode: errCodeForbidden, wantRoles: unchanged,
+		},
+		{
+			name: "unverified admin", members: members, caller: testPrincipal("admin", false), target: "member",
+			body: `{"role": "admin"}`, wantStatus: http.StatusForbidden, wantCode: errCodeEmailNotVerified, wantRoles: unchanged,
+		},
+		{
+			name: "unknown role", members: members, caller: testPrincipal("admin", true), target: "member",
+			body: `{"role": "owner"}`, wantStatus: http.StatusBadRequest, wantCode: errCodeValidationFailed, wantRoles: unchanged,
+		},
+		{
+			name: "missing role", members: members, caller: testPrincipal("admin", true), target: "member",
+			body: `{}`, wantStatus: http.StatusBadRequest, wantCode: errCodeValidationFailed, wantRoles: unchanged,
+		},
+		{
+			name: "unauthenticated", members: members, target: "member",
+			body: `{"role": "admin"}`, wantStatus: http.StatusUnauthorized, wantCode: errCodeUnauthorized, wantRoles: unchanged,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clubID := fmt.Sprintf("role%d", i)
+			seedClub(t, clubID, false, tt.members...)
+
+			w := serveClubRequest(updateMemberRoleHandler, http.MethodPut, tt.body, tt.caller,
+				map[string]string{"clubId": clubID, "userId": tt.target})
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body: %s)", w.Code, tt.wantStatus, w.Body)
+			}
+			if code := errorCode(t, w); code != tt.wantCode {
+				t.Errorf("error code = %q, want %q", code, tt.wantCode)
+			}
+			checkMembership(t, clubID, tt.wantRoles)
+		})
+	}
+}
+
+func TestDeleteClubHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		caller      *Principal
+		wantStatus  int
+		wantCode    string
+		wantDeleted bool
+	}{
+		{name: "admin", caller: testPrincipal("admin", true), wantStatus: http.StatusOK, wantDeleted: true},
+		{name: "owner", caller: testPrincipal("owner", true), wantStatus: http.StatusOK, wantDeleted: true},
+		{name: "member", caller: testPrincipal("member", true), wantStatus: http.StatusForbidden, wantCode: errCodeForbidden},
+		{name: "non-member", caller: testPrincipal("stranger", true), wantStatus: http.StatusForbidden, wantCode: errCodeForbidden},
+		{name: "unverified admin", caller: testPrincipal("admin", false), wantStatus: http.StatusForbidden, wantCode: errCodeEmailNotVerified},
+		{name: "unauthenticated", wantStatus: http.StatusUnauthorized, wantCode: errCodeUnauthorized},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clubID := fmt.Sprintf("delete%d", i)
+			seedClub(t, clubID, false, Member{ID: "owner", Role: roleOwner}, Member{ID: "admin", Role: roleAdmin}, Member{ID: "member"})
+
+			w := serveClubRequest(deleteClubHandler, http.MethodDelete, "", tt.caller, map[string]string{"clubId": clubID})
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body: %s)", w.Code, tt.wantStatus, w.Body)
+			}
+			if code := errorCode(t, w); code != tt.wantCode {
+				t.Errorf("error code = %q, want %q", code, tt.wantCode)
+			}
+
+			if deleted := testDB.get("clubs/"+clubID) == nil; deleted != tt.wantDeleted {
+				t.Errorf("club deleted = %v, want %v", deleted, tt.wantDeleted)
+			}
+			for _, uid := range []string{"owner", "admin", "member"} {
+				if listed := containsString(userClubIDs(uid), clubID); listed == tt.wantDeleted {
+					t.Errorf("clubs of %s = %v after deleting %s with status %d", uid, userClubIDs(uid), clubID, w.Code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// corsAllowedHeaders are the request headers browsers may send cross-origin
+var corsAllowedHeaders = []string{"Content-Type", "Authorization", requestIDHeader, appCheckHeader}
+
+// corsAllowlist holds the origins allowed to call the service from a browser
+type corsAllowlist struct {
+	exact     map[string]bool
+	wildcards []originWildcard
+}
+
+// originWildcard matches any subdomain of suffix with the given scheme and port,
+// e.g. https://*.bookclurb.app
+type originWildcard struct {
+	scheme string
+	suffix string // ".bookclurb.app"
+	port   string
+}
+
+var corsOrigins corsAllowlist
+
+// parseCORSOrigins parses a comma- or space-separated list of origins.
+// Entries are exact origins (https://bookclurb.app) or wildcard subdomains (https://*.bookclurb.app).
+func parseCORSOrigins(value string) (corsAllowlist, error) {
+	allowlist := corsAllowlist{exact: map[string]bool{}}
+
+	fields := strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' })
+	for _, field := range fields {
+		u, err := url.Parse(strings.TrimSuffix(field, "/"))
+		if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			return corsAllowlist{}, fmt.Errorf("invalid origin %q", field)
+		}
+
+		host := strings.ToLower(u.Hostname())
+		if strings.HasPrefix(host, "*.") {
+			allowlist.wildcards = append(allowlist.wildcards, originWildcard{
+				scheme: strings.ToLower(u.Scheme),
+				suffix: host[1:],
+				port:   u.Port(),
+			})
+			continue
+		}
+		if strings.Contains(host, "*") {
+			return corsAllowlist{}, fmt.Errorf("invalid origin %q: wildcards are only allowed as the leftmost label", field)
+		}
+		allowlist.exact[strings.ToLower(u.Scheme)+"://"+strings.ToLower(u.Host)] = true
+	}
+
+	return allowlist, nil
+}
+
+// allows reports whether a browser Origin header value is on the allowlist
+func (a corsAllowlist) allows(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return false
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if a.exact[scheme+"://"+strings.ToLower(u.Host)] {
+		return true
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, wildcard := range a.wildcards {
+		if scheme == wildcard.scheme && u.Port() == wildcard.port &&
+			strings.HasSuffix(host, wildcard.suffix) && len(host) > len(wildcard.suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadCORSConfig reads CORS_ALLOWED_ORIGINS, defaulting to the origin of BASE_URL
+func loadCORSConfig() {
+	value := getEnv("CORS_ALLOWED_ORIGINS", "")
+	if value == "" {
+		if u, err := url.Parse(baseURL); err == nil {
+			value = u.Scheme + "://" + u.Host
+		}
+		slog.Info("CORS_ALLOWED_ORIGINS not set, allowing BASE_URL origin only", "origin", value)
+	}
+
+	allowlist, err := parseCORSOrigins(value)
+	if err != nil {
+		fatal("Invalid CORS_ALLOWED_ORIGINS", "error", err)
+	}
+	corsOrigins = allowlist
+}
+
+// corsHandler applies the CORS origin allowlist and security headers.
+// methods are the methods the route accepts; OPTIONS is always allowed for preflights.
+func corsHandler(handler http.HandlerFunc, methods ...string) http.HandlerFunc {
+	allowedMethods := strings.Join(append(append([]string{}, methods...), http.MethodOptions), ", ")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		w.Header().Add("Vary", "Origin")
+
+		// Security headers
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("X-Frame-Options", "DENY")
+		w.Header().Set("X-XSS-Protection", "1; mode=block")
+
+		// Requests without an Origin header are not from a browser context
+		if origin != "" && !corsOrigins.allows(origin) {
+			slog.WarnContext(r.Context(), "Rejected request from disallowed origin", "origin", origin, "method", r.Method)
+			writeError(w, r, http.StatusForbidden, errCodeForbidden, "Origin not allowed", nil)
+			return
+		}
+
+		// Handle OPTIONS preflight request
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Allow", allowedMethods)
+			requestedMethod := r.Header.Get("Access-Control-Request-Method")
+			if origin == "" || requestedMethod == "" {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			if !containsMethod(methods, requestedMethod) {
+				writeError(w, r, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "Method not allowed", nil)
+				return
+			}
+
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			w.Header().Set("Access-Control-Allow-Methods", allowedMethods)
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(corsAllowedHeaders, ", "))
+			w.Header().Set("Access-Control-Max-Age", "3600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if origin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			w.Header().Set("Access-Control-Expose-Headers", requestIDHeader)
+		}
+
+		handler(w, r)
+	}
+}
+
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method || (m == http.MethodGet && method == http.MethodHead) {
+			return true
+		}
+	}
+	return false
+}
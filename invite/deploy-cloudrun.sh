@@ -172,7 +172,28 @@ if [ -z "$FIREBASE_PROJECT_ID" ]; then
   exit 1
 fi
 
-ENV_VARS="EMAIL_USER=$EMAIL_USER,EMAIL_PASSWORD=$EMAIL_PASSWORD,BASE_URL=$BASE_URL,FIREBASE_DATABASE_URL=$FIREBASE_DATABASE_URL,FIREBASE_PROJECT_ID=$FIREBASE_PROJECT_ID"
+# Variables are separated with | (see --set-env-vars below) because some values are comma-separated lists
+ENV_VARS="EMAIL_USER=$EMAIL_USER|EMAIL_PASSWORD=$EMAIL_PASSWORD|BASE_URL=$BASE_URL|FIREBASE_DATABASE_URL=$FIREBASE_DATABASE_URL|FIREBASE_PROJECT_ID=$FIREBASE_PROJECT_ID"
+
+# Browser origins allowed by CORS (space-separated; defaults to the BASE_URL origin)
+if [ -n "$CORS_ALLOWED_ORIGINS" ]; then
+  ENV_VARS="$ENV_VARS|CORS_ALLOWED_ORIGINS=$CORS_ALLOWED_ORIGINS"
+fi
+
+# Key-encryption keys for stored Hardcover tokens (keyId:base64key,...; first is primary)
+if [ -n "$SECRET_ENCRYPTION_KEYS" ]; then
+  ENV_VARS="$ENV_VARS|SECRET_ENCRYPTION_KEYS=$SECRET_ENCRYPTION_KEYS"
+fi
+
+# Firebase App Check: off, monitor or enforce
+if [ -n "$APP_CHECK_MODE" ]; then
+  ENV_VARS="$ENV_VARS|APP_CHECK_MODE=$APP_CHECK_MODE"
+fi
+
+# Per-operation App Check modes (operationId=mode,...), overriding APP_CHECK_MODE
+if [ -n "$APP_CHECK_ROUTES" ]; then
+  ENV_VARS="$ENV_VARS|APP_CHECK_ROUTES=$APP_CHECK_ROUTES"
+fi
 
 echo "🌐 Allowing unauthenticated access (Firebase token verification required)"
 
@@ -195,8 +216,9 @@ gcloud run deploy "$SERVICE_NAME" \
   --allow-unauthenticated \
   --memory 256Mi \
   --timeout 60 \
+  --no-cpu-throttling \
   --max-instances 10 \
-  --set-env-vars="$ENV_VARS" \
+  --set-env-vars="^|^$ENV_VARS" \
   ${SERVICE_ACCOUNT:+--service-account="$SERVICE_ACCOUNT"} \
   || {
     echo ""
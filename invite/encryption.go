@@ -0,0 +1,195 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// EncryptedSecret is an envelope-encrypted value as stored in the Realtime Database.
+// The value is sealed with a random per-record data key, and the data key is sealed
+// with the key-encryption key named by KeyID.
+type EncryptedSecret struct {
+	KeyID      string `json:"keyId"`
+	WrappedKey string `json:"wrappedKey"` // nonce || AES-GCM(data key)
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// secretKeyring holds the key-encryption keys. The primary key encrypts new secrets;
+// the others are kept so secrets written before a rotation can still be read.
+type secretKeyring struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+var (
+	encryptionKeys *secretKeyring
+
+	// errEncryptionNotConfigured is returned when no key-encryption keys are configured
+	errEncryptionNotConfigured = errors.New("secret encryption keys not configured")
+)
+
+// parseSecretKeyring parses a comma-separated list of keyId:base64key entries. Keys must
+// be 32 bytes (AES-256); the first entry is the primary key.
+func parseSecretKeyring(value string) (*secretKeyring, error) {
+	keyring := &secretKeyring{keys: map[string]cipher.AEAD{}}
+
+	for i, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		keyID, encoded, ok := strings.Cut(entry, ":")
+		if !ok || keyID == "" {
+			return nil, fmt.Errorf("invalid key entry %d: expected keyId:base64key", i+1)
+		}
+		if _, exists := keyring.keys[keyID]; exists {
+			return nil, fmt.Errorf("duplicate key ID %q", keyID)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %q is not valid base64", keyID)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("key %q must be 32 bytes, got %d", keyID, len(key))
+		}
+		aead, err := newGCM(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %v", keyID, err)
+		}
+
+		keyring.keys[keyID] = aead
+		if keyring.primary == "" {
+			keyring.primary = keyID
+		}
+	}
+
+	if keyring.primary == "" {
+		return nil, errors.New("no keys configured")
+	}
+	return keyring, nil
+}
+
+// loadEncryptionConfig reads the key-encryption keys from SECRET_ENCRYPTION_KEYS
+func loadEncryptionConfig() {
+	value := getEnv("SECRET_ENCRYPTION_KEYS", "")
+	if value == "" {
+		slog.Warn("SECRET_ENCRYPTION_KEYS not set. Hardcover tokens cannot be stored.")
+		return
+	}
+
+	keyring, err := parseSecretKeyring(value)
+	if err != nil {
+		fatal("Invalid SECRET_ENCRYPTION_KEYS", "error", err)
+	}
+	encryptionKeys = keyring
+	slog.Info("Secret encryption enabled", "primaryKeyId", keyring.primary, "keys", len(keyring.keys))
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext with aead, prefixing the random nonce
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts a value produced by seal
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+// encryptSecret envelope-encrypts plaintext with the primary key. additionalData binds
+// the ciphertext to where it is stored, so it cannot be copied to another record.
+func encryptSecret(plaintext, additionalData string) (*EncryptedSecret, error) {
+	if encryptionKeys == nil {
+		return nil, errEncryptionNotConfigured
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %v", err)
+	}
+	dataAEAD, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := seal(dataAEAD, []byte(plaintext), []byte(additionalData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt secret: %v", err)
+	}
+	wrappedKey, err := seal(encryptionKeys.keys[encryptionKeys.primary], dataKey, []byte(encryptionKeys.primary))
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %v", err)
+	}
+
+	nonceSize := dataAEAD.NonceSize()
+	return &EncryptedSecret{
+		KeyID:      encryptionKeys.primary,
+		WrappedKey: base64.StdEncoding.EncodeToString(wrappedKey),
+		Nonce:      base64.StdEncoding.EncodeToString(sealed[:nonceSize]),
+		Ciphertext: base64.StdEncoding.EncodeToString(sealed[nonceSize:]),
+	}, nil
+}
+
+// decryptSecret decrypts a secret produced by encryptSecret with the same additionalData.
+// stale reports whether the secret was encrypted with a key other than the primary key
+// and should be re-encrypted.
+func decryptSecret(secret *EncryptedSecret, additionalData string) (plaintext string, stale bool, err error) {
+	if encryptionKeys == nil {
+		return "", false, errEncryptionNotConfigured
+	}
+	kek, ok := encryptionKeys.keys[secret.KeyID]
+	if !ok {
+		return "", false, fmt.Errorf("unknown encryption key ID %q", secret.KeyID)
+	}
+
+	wrappedKey, err := base64.StdEncoding.DecodeString(secret.WrappedKey)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid wrapped key encoding: %v", err)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(secret.Nonce)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid nonce encoding: %v", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(secret.Ciphertext)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid ciphertext encoding: %v", err)
+	}
+
+	dataKey, err := open(kek, wrappedKey, []byte(secret.KeyID))
+	if err != nil {
+		return "", false, fmt.Errorf("failed to unwrap data key: %v", err)
+	}
+	dataAEAD, err := newGCM(dataKey)
+	if err != nil {
+		return "", false, err
+	}
+	decrypted, err := open(dataAEAD, append(nonce, ciphertext...), []byte(additionalData))
+	if err != nil {
+		return "", false, fmt.Errorf("failed to decrypt secret: %v", err)
+	}
+
+	return string(decrypted), secret.KeyID != encryptionKeys.primary, nil
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testKey returns a base64 AES-256 key filled with b
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(rune(b)), 32)))
+}
+
+// useKeyring configures the key-encryption keys for the rest of the test
+func useKeyring(t *testing.T, value string) {
+	t.Helper()
+	previous := encryptionKeys
+	t.Cleanup(func() { encryptionKeys = previous })
+	if value == "" {
+		encryptionKeys = nil
+		return
+	}
+	keyring, err := parseSecretKeyring(value)
+	if err != nil {
+		t.Fatalf("parseSecretKeyring(%q) error = %v", value, err)
+	}
+	encryptionKeys = keyring
+}
+
+func TestParseSecretKeyring(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		wantPrimary string
+		wantKeys    int
+		wantErr     bool
+	}{
+		{name: "single key", value: "k1:" + testKey('a'), wantPrimary: "k1", wantKeys: 1},
+		{name: "first key is primary", value: "k2:" + testKey('b') + ", k1:" + testKey('a'), wantPrimary: "k2", wantKeys: 2},
+		{name: "empty entries skipped", value: ",k1:" + testKey('a') + ",", wantPrimary: "k1", wantKeys: 1},
+		{name: "empty", value: "", wantErr: true},
+		{name: "missing key ID", value: ":" + testKey('a'), wantErr: true},
+		{name: "missing separator", value: testKey('a'), wantErr: true},
+		{name: "duplicate key ID", value: "k1:" + testKey('a') + ",k1:" + testKey('b'), wantErr: true},
+		{name: "invalid base64", value: "k1:not base64!", wantErr: true},
+		{name: "short key", value: "k1:" + base64.StdEncoding.EncodeToString([]byte("too short")), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring, err := parseSecretKeyring(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSecretKeyring() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if keyring.primary != tt.wantPrimary || len(keyring.keys) != tt.wantKeys {
+				t.Errorf("primary = %q with %d keys, want %q with %d", keyring.primary, len(keyring.keys), tt.wantPrimary, tt.wantKeys)
+			}
+		})
+	}
+}
+
+func TestEncryptSecretRoundTrip(t *testing.T) {
+	useKeyring(t, "k1:"+testKey('a'))
+
+	for _, plaintext := range []string{"", "hc_token", strings.Repeat("x", maxTokenLength), "ünïcödé ✓"} {
+		aad := hardcoverTokenAAD("alice")
+		secret, err := encryptSecret(plaintext, aad)
+		if err != nil {
+			t.Fatalf("encryptSecret() error = %v", err)
+		}
+		if secret.KeyID != "k1" {
+			t.Errorf("KeyID = %q, want k1", secret.KeyID)
+		}
+		if plaintext != "" && strings.Contains(secret.Ciphertext, base64.StdEncoding.EncodeToString([]byte(plaintext))) {
+			t.Errorf("ciphertext contains the plaintext")
+		}
+
+		got, stale, err := decryptSecret(secret, aad)
+		if err != nil {
+			t.Fatalf("decryptSecret() error = %v", err)
+		}
+		if got != plaintext || stale {
+			t.Errorf("decryptSecret() = %q, stale %v; want %q, not stale", got, stale, plaintext)
+		}
+	}
+
+	// Each secret gets its own data key and nonce
+	first, _ := encryptSecret("hc_token", "aad")
+	second, _ := encryptSecret("hc_token", "aad")
+	if first.Ciphertext == second.Ciphertext || first.WrappedKey == second.WrappedKey || first.Nonce == second.Nonce {
+		t.Errorf("encrypting the same secret twice produced repeated values")
+	}
+}
+
+func TestDecryptSecretRejectsOtherRecords(t *testing.T) {
+	useKeyring(t, "k1:"+testKey('a'))
+
+	secret, err := encryptSecret("hc_token", hardcoverTokenAAD("alice"))
+	if err != nil {
+		t.Fatalf("encryptSecret() error = %v", err)
+	}
+
+	tampered := func(edit func(s *EncryptedSecret)) *EncryptedSecret {
+		copied := *secret
+		edit(&copied)
+		return &copied
+	}
+	flip := func(encoded string) string {
+		data, _ := base64.StdEncoding.DecodeString(encoded)
+		data[len(data)-1] ^= 1
+		return base64.StdEncoding.EncodeToString(data)
+	}
+
+	tests := []struct {
+		name   string
+		secret *EncryptedSecret
+		aad    string
+	}{
+		{name: "another user's AAD", secret: secret, aad: hardcoverTokenAAD("bob")},
+		{name: "empty AAD", secret: secret, aad: ""},
+		{name: "modified ciphertext", secret: tampered(func(s *EncryptedSecret) { s.Ciphertext = flip(s.Ciphertext) }), aad: hardcoverTokenAAD("alice")},
+		{name: "modified wrapped key", secret: tampered(func(s *EncryptedSecret) { s.WrappedKey = flip(s.WrappedKey) }), aad: hardcoverTokenAAD("alice")},
+		{name: "modified nonce", secret: tampered(func(s *EncryptedSecret) { s.Nonce = flip(s.Nonce) }), aad: hardcoverTokenAAD("alice")},
+		{name: "invalid encoding", secret: tampered(func(s *EncryptedSecret) { s.Ciphertext = "%%%" }), aad: hardcoverTokenAAD("alice")},
+		{name: "truncated wrapped key", secret: tampered(func(s *EncryptedSecret) { s.WrappedKey = "AAAA" }), aad: hardcoverTokenAAD("alice")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _, err := decryptSecret(tt.secret, tt.aad); err == nil {
+				t.Errorf("decryptSecret() = %q, want an error", got)
+			}
+		})
+	}
+}
+
+func TestSecretKeyRotation(t *testing.T) {
+	useKeyring(t, "old:"+testKey('a'))
+	aad := hardcoverTokenAAD("alice")
+	secret, err := encryptSecret("hc_token", aad)
+	if err != nil {
+		t.Fatalf("encryptSecret() error = %v", err)
+	}
+
+	// After rotation the old key still decrypts, and reports the secret as stale
+	useKeyring(t, "new:"+testKey('b')+",old:"+testKey('a'))
+	got, stale, err := decryptSecret(secret, aad)
+	if err != nil || got != "hc_token" || !stale {
+		t.Fatalf("decryptSecret() after rotation = %q, stale %v, error %v; want hc_token, stale", got, stale, err)
+	}
+
+	// Re-encrypting uses the new primary key
+	reencrypted, err := encryptSecret(got, aad)
+	if err != nil {
+		t.Fatalf("encryptSecret() error = %v", err)
+	}
+	if reencrypted.KeyID != "new" {
+		t.Errorf("KeyID = %q, want new", reencrypted.KeyID)
+	}
+	if got, stale, err := decryptSecret(reencrypted, aad); err != nil || got != "hc_token" || stale {
+		t.Errorf("decryptSecret() of re-encrypted secret = %q, stale %v, error %v", got, stale, err)
+	}
+
+	// A key ID cannot be pointed at a different key
+	forged := *secret
+	forged.KeyID = "new"
+	if _, _, err := decryptSecret(&forged, aad); err == nil {
+		t.Errorf("decryptSecret() with a swapped key ID succeeded")
+	}
+
+	// Once the old key is retired, its secrets can no longer be read
+	useKeyring(t, "new:"+testKey('b'))
+	if _, _, err := decryptSecret(secret, aad); err == nil || !strings.Contains(err.Error(), "unknown encryption key ID") {
+		t.Errorf("decryptSecret() with a retired key error = %v, want unknown key ID", err)
+	}
+}
+
+func TestSecretEncryptionNotConfigured(t *testing.T) {
+	useKeyring(t, "")
+	if _, err := encryptSecret("hc_token", "aad"); !errors.Is(err, errEncryptionNotConfigured) {
+		t.Errorf("encryptSecret() error = %v, want errEncryptionNotConfigured", err)
+	}
+	if _, _, err := decryptSecret(&EncryptedSecret{KeyID: "k1"}, "aad"); !errors.Is(err, errEncryptionNotConfigured) {
+		t.Errorf("decryptSecret() error = %v, want errEncryptionNotConfigured", err)
+	}
+}
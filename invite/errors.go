@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dhvogel/bookclurb-invite/hardcover"
+	"github.com/dhvogel/bookclurb-invite/isbn"
+)
+
+// Error codes returned in ErrorResponse.Error.Code
+const (
+	errCodeInvalidRequest        = "invalid_request"
+	errCodeValidationFailed      = "validation_failed"
+	errCodeRequestTooLarge       = "request_too_large"
+	errCodeUnauthorized          = "unauthorized"
+	errCodeForbidden             = "forbidden"
+	errCodeTokenRevoked          = "token_revoked"
+	errCodeEmailNotVerified      = "email_not_verified"
+	errCodeAppCheckFailed        = "app_check_failed"
+	errCodeNotFound              = "not_found"
+	errCodeMethodNotAllowed      = "method_not_allowed"
+	errCodeInternal              = "internal_error"
+	errCodeEmailUnavailable      = "email_unavailable"
+	errCodeEmailFailed           = "email_send_failed"
+	errCodeInviteNotFound        = "invite_not_found"
+	errCodeInviteInactive        = "invite_inactive"
+	errCodeInviteEmailMismatch   = "invite_email_mismatch"
+	errCodeLastAdmin             = "last_admin"
+	errCodeNoCurrentBook         = "no_current_book"
+	errCodeSyncNotRetryable      = "sync_not_retryable"
+	errCodeInvalidISBN           = "invalid_isbn"
+	errCodeEncryptionUnavailable = "encryption_unavailable"
+	errCodeHardcoverNotLinked    = "hardcover_not_linked"
+	errCodeHardcoverInvalidToken = "hardcover_invalid_token"
+	errCodeHardcoverBookNotFound = "hardcover_book_not_found"
+	errCodeHardcoverAmbiguous    = "hardcover_book_ambiguous"
+	errCodeHardcoverUnavailable  = "hardcover_unavailable"
+	errCodeHardcoverRateLimited  = "hardcover_rate_limited"
+)
+
+// APIError is the error object returned by every endpoint
+type APIError struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"requestId,omitempty"`
+}
+
+// ErrorResponse is the JSON envelope for error responses
+type ErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error envelope. The message is returned to clients as-is,
+// so it must never contain raw Go, Firebase or upstream error strings.
+func writeError(w http.ResponseWriter, r *http.Request, status int, code, message string, details interface{}) {
+	writeJSON(w, status, ErrorResponse{
+		Error: APIError{
+			Code:      code,
+			Message:   message,
+			Details:   details,
+			RequestID: requestIDFromContext(r.Context()),
+		},
+	})
+}
+
+// requireMethod writes a 405 error and returns false unless the request uses one of methods
+func requireMethod(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, r, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "Method not allowed", nil)
+	return false
+}
+
+// notFoundHandler returns the JSON envelope for unknown routes
+func notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusNotFound, errCodeNotFound, "Not found", nil)
+}
+
+// hardcoverErrorMessages are the client-facing messages for the Hardcover error codes,
+// which don't expose the upstream error text
+var hardcoverErrorMessages = map[string]string{
+	errCodeInternal:              "Failed to load the Hardcover account or sync settings",
+	errCodeInvalidISBN:           "The book's ISBN is not valid",
+	errCodeHardcoverNotLinked:    "No Hardcover account linked",
+	errCodeHardcoverAmbiguous:    "Several Hardcover books match; choose one",
+	errCodeHardcoverBookNotFound: "Book not found on Hardcover",
+	errCodeHardcoverInvalidToken: "Hardcover rejected the API token",
+	errCodeHardcoverRateLimited:  "Hardcover is rate limiting requests, try again later",
+	errCodeHardcoverUnavailable:  "Hardcover request failed",
+}
+
+// writeHardcoverError maps a Hardcover integration error to a status code without
+// exposing the upstream error text
+func writeHardcoverError(w http.ResponseWriter, r *http.Request, err error) {
+	var ambiguous *ambiguousBookError
+	switch {
+	case errors.Is(err, isbn.ErrInvalid):
+		writeError(w, r, http.StatusUnprocessableEntity, errCodeInvalidISBN, hardcoverErrorMessages[errCodeInvalidISBN], nil)
+	case errors.As(err, &ambiguous):
+		writeError(w, r, http.StatusConflict, errCodeHardcoverAmbiguous, hardcoverErrorMessages[errCodeHardcoverAmbiguous],
+			BookCandidatesDetails{Candidates: ambiguous.Candidates})
+	case errors.Is(err, hardcover.ErrNotFound):
+		writeError(w, r, http.StatusNotFound, errCodeHardcoverBookNotFound, hardcoverErrorMessages[errCodeHardcoverBookNotFound], nil)
+	case errors.Is(err, hardcover.ErrUnauthorized):
+		writeError(w, r, http.StatusUnprocessableEntity, errCodeHardcoverInvalidToken, hardcoverErrorMessages[errCodeHardcoverInvalidToken], nil)
+	case errors.Is(err, hardcover.ErrRateLimited):
+		var httpErr *hardcover.HTTPError
+		if errors.As(err, &httpErr) && httpErr.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(httpErr.RetryAfter.Seconds())))
+		}
+		writeError(w, r, http.StatusServiceUnavailable, errCodeHardcoverRateLimited, hardcoverErrorMessages[errCodeHardcoverRateLimited], nil)
+	default:
+		writeError(w, r, http.StatusBadGateway, errCodeHardcoverUnavailable, hardcoverErrorMessages[errCodeHardcoverUnavailable], nil)
+	}
+}
+
+// hardcoverErrorCode returns the error code writeHardcoverError would respond with, for
+// reporting the failures of individual items in a batch
+func hardcoverErrorCode(err error) string {
+	var ambiguous *ambiguousBookError
+	switch {
+	case errors.Is(err, errHardcoverTokenUnavailable), errors.Is(err, errSyncSettingsUnavailable):
+		return errCodeInternal
+	case errors.Is(err, isbn.ErrInvalid):
+		return errCodeInvalidISBN
+	case errors.As(err, &ambiguous):
+		return errCodeHardcoverAmbiguous
+	case errors.Is(err, hardcover.ErrNotFound):
+		return errCodeHardcoverBookNotFound
+	case errors.Is(err, hardcover.ErrUnauthorized):
+		return errCodeHardcoverInvalidToken
+	case errors.Is(err, hardcover.ErrRateLimited):
+		return errCodeHardcoverRateLimited
+	default:
+		return errCodeHardcoverUnavailable
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDatabase is an in-memory Realtime Database speaking enough of the REST protocol
+// for the Admin SDK: GET (with shallow and ETags), PUT (with If-Match, as used by
+// transactions), PATCH (including multi-path keys), POST and DELETE. Like the real
+// database it stores arrays as objects keyed by index, drops nulls and empty objects,
+// and returns objects with mostly dense integer keys as arrays.
+type fakeDatabase struct {
+	mu     sync.Mutex
+	root   map[string]interface{}
+	pushID int
+	// failPaths are paths whose reads fail with a 500
+	failPaths map[string]bool
+}
+
+func newFakeDatabase() *fakeDatabase {
+	return &fakeDatabase{root: map[string]interface{}{}, failPaths: map[string]bool{}}
+}
+
+// set stores the JSON value at path, failing the test if it doesn't parse
+func (f *fakeDatabase) set(t *testing.T, path, value string) {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(value), &v); err != nil {
+		t.Fatalf("invalid JSON for %s: %v", path, err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.write(splitPath(path), v)
+}
+
+// get returns the value at path as the database would serve it
+func (f *fakeDatabase) get(path string) interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return render(f.read(splitPath(path)))
+}
+
+func splitPath(path string) []string {
+	var segments []string
+	for _, segment := range strings.Split(strings.TrimSuffix(path, ".json"), "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+func (f *fakeDatabase) read(segments []string) interface{} {
+	var node interface{} = f.root
+	for _, segment := range segments {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		node = m[segment]
+	}
+	return node
+}
+
+// write stores v at segments, creating parents and pruning empty ones
+func (f *fakeDatabase) write(segments []string, v interface{}) {
+	v = normalize(v)
+	if len(segments) == 0 {
+		root, _ := v.(map[string]interface{})
+		if root == nil {
+			root = map[string]interface{}{}
+		}
+		f.root = root
+		return
+	}
+
+	parents := []map[string]interface{}{f.root}
+	for _, segment := range segments[:len(segments)-1] {
+		parent := parents[len(parents)-1]
+		child, ok := parent[segment].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			parent[segment] = child
+		}
+		parents = append(parents, child)
+	}
+
+	last := parents[len(parents)-1]
+	if v == nil {
+		delete(last, segments[len(segments)-1])
+	} else {
+		last[segments[len(segments)-1]] = v
+	}
+	for i := len(parents) - 1; i > 0; i-- {
+		if len(parents[i]) == 0 {
+			delete(parents[i-1], segments[i-1])
+		}
+	}
+}
+
+// normalize converts arrays to index-keyed objects and drops nulls and empty objects
+func normalize(v interface{}) interface{} {
+	var m map[string]interface{}
+	switch value := v.(type) {
+	case []interface{}:
+		m = make(map[string]interface{}, len(value))
+		for i, child := range value {
+			m[strconv.Itoa(i)] = child
+		}
+	case map[string]interface{}:
+		m = make(map[string]interface{}, len(value))
+		for key, child := range value {
+			m[key] = child
+		}
+	default:
+		return v
+	}
+	for key, child := range m {
+		if child = normalize(child); child == nil {
+			delete(m, key)
+		} else {
+			m[key] = child
+		}
+	}
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
+// render returns objects whose keys are integers filling more than half their range
+// as arrays, as the real database does
+func render(v interface{}) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	maxIndex := -1
+	for key := range m {
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || strconv.Itoa(i) != key {
+			maxIndex = -1
+			break
+		}
+		if i > maxIndex {
+			maxIndex = i
+		}
+	}
+	if maxIndex >= 0 && maxIndex < 2*len(m) {
+		array := make([]interface{}, maxIndex+1)
+		for key, child := range m {
+			i, _ := strconv.Atoi(key)
+			array[i] = render(child)
+		}
+		return array
+	}
+	rendered := make(map[string]interface{}, len(m))
+	for key, child := range m {
+		rendered[key] = render(child)
+	}
+	return rendered
+}
+
+func etag(v interface{}) string {
+	data, _ := json.Marshal(v) // map keys are sorted, so equal values hash equally
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+func (f *fakeDatabase) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	segments := splitPath(r.URL.Path)
+	path := strings.Join(segments, "/")
+	current := render(f.read(segments))
+
+	var body interface{}
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"error": %q}`, err.Error())
+			return
+		}
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if f.failPaths[path] {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"error": "internal"}`)
+			return
+		}
+		if r.URL.Query().Get("shallow") == "true" {
+			if m, ok := current.(map[string]interface{}); ok {
+				keys := make(map[string]interface{}, len(m))
+				for key := range m {
+					keys[key] = true
+				}
+				current = keys
+			} else if a, ok := current.([]interface{}); ok {
+				keys := make(map[string]interface{}, len(a))
+				for i, child := range a {
+					if child != nil {
+						keys[strconv.Itoa(i)] = true
+					}
+				}
+				current = keys
+			}
+		}
+		if r.Header.Get("X-Firebase-ETag") == "true" {
+			w.Header().Set("ETag", etag(current))
+		}
+		json.NewEncoder(w).Encode(current)
+
+	case http.MethodPut:
+		if match := r.Header.Get("If-Match"); match != "" && match != etag(current) {
+			w.Header().Set("ETag", etag(current))
+			w.WriteHeader(http.StatusPreconditionFailed)
+			json.NewEncoder(w).Encode(current)
+			return
+		}
+		f.write(segments, body)
+		json.NewEncoder(w).Encode(body)
+
+	case http.MethodPatch:
+		updates, ok := body.(map[string]interface{})
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error": "update must be an object"}`)
+			return
+		}
+		keys := make([]string, 0, len(updates))
+		for key := range updates {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			f.write(append(append([]string{}, segments...), splitPath(key)...), updates[key])
+		}
+		json.NewEncoder(w).Encode(body)
+
+	case http.MethodPost:
+		f.pushID++
+		name := fmt.Sprintf("-push%04d", f.pushID)
+		f.write(append(append([]string{}, segments...), name), body)
+		fmt.Fprintf(w, `{"name": %q}`, name)
+
+	case http.MethodDelete:
+		f.write(segments, nil)
+		fmt.Fprint(w, "null")
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
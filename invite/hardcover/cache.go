@@ -0,0 +1,190 @@
+package hardcover
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Default lifetimes of cached ISBN lookups. Book IDs practically never change, but
+// Hardcover catalogues new editions often enough that a miss is worth rechecking.
+const (
+	DefaultBookCacheSize        = 5000
+	DefaultBookCacheTTL         = 7 * 24 * time.Hour
+	DefaultBookCacheNegativeTTL = time.Hour
+)
+
+// Results of a BookCache lookup, passed to BookCache.Observe
+const (
+	CacheHit         = "hit"          // the ISBN's book ID was cached
+	CacheNegativeHit = "negative_hit" // the ISBN was cached as having no book
+	CacheStoreHit    = "store_hit"    // found in the Store after a memory miss
+	CacheMiss        = "miss"
+)
+
+// BookCacheEntry is a cached ISBN lookup. BookID is 0 if no book has the ISBN.
+type BookCacheEntry struct {
+	BookID  int
+	Expires time.Time
+}
+
+// BookCacheStore persists lookups beyond the process, so a restart or another
+// instance does not repeat them. Keys are ISBN-13s.
+type BookCacheStore interface {
+	LoadBookID(ctx context.Context, isbn13 string) (entry BookCacheEntry, ok bool, err error)
+	SaveBookID(ctx context.Context, isbn13 string, entry BookCacheEntry) error
+}
+
+// BookCache is an LRU cache of ISBN to book ID lookups, including ISBNs with no book.
+// Book IDs don't depend on the API token, so one cache serves every user, and
+// concurrent misses for the same ISBN share one lookup. A BookCache is safe for
+// concurrent use; a nil *BookCache caches nothing.
+type BookCache struct {
+	// TTL is how long a found book ID is kept, NegativeTTL how long a miss is
+	TTL         time.Duration
+	NegativeTTL time.Duration
+
+	// Store, if set, is consulted on a memory miss and written on every put
+	Store BookCacheStore
+
+	// Observe, if set, is called with the result of every lookup
+	Observe func(result string)
+
+	size    int
+	mu      sync.Mutex
+	order   *list.List // most recently used first
+	entries map[string]*list.Element
+	flight  singleflight.Group // in-flight lookups by ISBN-13
+}
+
+type bookCacheItem struct {
+	isbn13 string
+	entry  BookCacheEntry
+}
+
+// NewBookCache returns a cache holding up to size ISBNs with the default lifetimes,
+// or nil (no caching) if size is not positive
+func NewBookCache(size int) *BookCache {
+	if size <= 0 {
+		return nil
+	}
+	return &BookCache{
+		TTL:         DefaultBookCacheTTL,
+		NegativeTTL: DefaultBookCacheNegativeTTL,
+		size:        size,
+		order:       list.New(),
+		entries:     map[string]*list.Element{},
+	}
+}
+
+// get returns the cached book ID for isbn13, with found false if the ISBN is cached as
+// having no book, and ok false if it is not cached
+func (c *BookCache) get(ctx context.Context, isbn13 string) (bookID int, found, ok bool) {
+	if c == nil {
+		return 0, false, false
+	}
+
+	entry, ok := c.memory(isbn13)
+	result := CacheHit
+	if !ok && c.Store != nil {
+		stored, storedOK, err := c.Store.LoadBookID(ctx, isbn13)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to load cached Hardcover book ID", "isbn", isbn13, "error", err)
+		} else if storedOK && time.Now().Before(stored.Expires) {
+			entry, ok = stored, true
+			result = CacheStoreHit
+			c.remember(isbn13, stored)
+		}
+	}
+	switch {
+	case !ok:
+		result = CacheMiss
+	case entry.BookID == 0:
+		result = CacheNegativeHit
+	}
+	if c.Observe != nil {
+		c.Observe(result)
+	}
+	return entry.BookID, entry.BookID != 0, ok
+}
+
+// put caches the book ID for isbn13, or that it has no book if bookID is 0
+func (c *BookCache) put(ctx context.Context, isbn13 string, bookID int) {
+	if c == nil {
+		return
+	}
+
+	ttl := c.TTL
+	if bookID == 0 {
+		ttl = c.NegativeTTL
+	}
+	entry := BookCacheEntry{BookID: bookID, Expires: time.Now().Add(ttl)}
+	c.remember(isbn13, entry)
+	if c.Store != nil {
+		if err := c.Store.SaveBookID(ctx, isbn13, entry); err != nil {
+			slog.WarnContext(ctx, "Failed to save cached Hardcover book ID", "isbn", isbn13, "error", err)
+		}
+	}
+}
+
+// lookup runs fetch for a missed isbn13, sharing one call among concurrent misses for
+// the same ISBN. A shared failure other than ErrNotFound may be specific to the caller
+// that ran it, such as a rejected token or a cancelled request, so the other callers
+// then run their own fetch.
+func (c *BookCache) lookup(isbn13 string, fetch func() (int, error)) (int, error) {
+	if c == nil {
+		return fetch()
+	}
+	ran := false
+	v, err, shared := c.flight.Do(isbn13, func() (interface{}, error) {
+		ran = true
+		return fetch()
+	})
+	// shared is also true for the caller that ran fetch, which keeps its own error
+	if err != nil && shared && !ran && !errors.Is(err, ErrNotFound) {
+		return fetch()
+	}
+	return v.(int), err
+}
+
+// memory returns the unexpired in-process entry for isbn13
+func (c *BookCache) memory(isbn13 string) (BookCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[isbn13]
+	if !ok {
+		return BookCacheEntry{}, false
+	}
+	item := elem.Value.(*bookCacheItem)
+	if !time.Now().Before(item.entry.Expires) {
+		c.order.Remove(elem)
+		delete(c.entries, isbn13)
+		return BookCacheEntry{}, false
+	}
+	c.order.MoveToFront(elem)
+	return item.entry, true
+}
+
+// remember stores entry in process, evicting the least recently used ISBN when full
+func (c *BookCache) remember(isbn13 string, entry BookCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[isbn13]; ok {
+		elem.Value.(*bookCacheItem).entry = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[isbn13] = c.order.PushFront(&bookCacheItem{isbn13: isbn13, entry: entry})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*bookCacheItem).isbn13)
+	}
+}
@@ -0,0 +1,169 @@
+package hardcover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// concurrentLookups calls c.lookup for isbn13 from n goroutines at once. fetch blocks
+// until the first call has had time to be joined by the others, then returns result.
+func concurrentLookups(c *BookCache, isbn13 string, n int, result func() (int, error)) (fetches int32, ids []int, errs []error) {
+	started := make(chan struct{}, n)
+	release := make(chan struct{})
+	fetch := func() (int, error) {
+		if atomic.AddInt32(&fetches, 1) == 1 {
+			started <- struct{}{}
+			<-release
+		}
+		return result()
+	}
+
+	ids, errs = make([]int, n), make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ids[i], errs[i] = c.lookup(isbn13, fetch)
+		}(i)
+	}
+	<-started
+	time.Sleep(100 * time.Millisecond) // let the other goroutines join the flight
+	close(release)
+	wg.Wait()
+	return atomic.LoadInt32(&fetches), ids, errs
+}
+
+func TestBookCacheLookupSharesFetch(t *testing.T) {
+	c := NewBookCache(10)
+	fetches, ids, errs := concurrentLookups(c, "9780306406157", 20, func() (int, error) { return 42, nil })
+	if fetches != 1 {
+		t.Errorf("fetched %d times, want 1", fetches)
+	}
+	for i := range ids {
+		if ids[i] != 42 || errs[i] != nil {
+			t.Errorf("lookup %d = %d, %v; want 42", i, ids[i], errs[i])
+		}
+	}
+}
+
+func TestBookCacheLookupSharesNotFound(t *testing.T) {
+	c := NewBookCache(10)
+	notFound := fmt.Errorf("%w: no edition", ErrNotFound)
+	fetches, _, errs := concurrentLookups(c, "9780306406157", 20, func() (int, error) { return 0, notFound })
+	if fetches != 1 {
+		t.Errorf("fetched %d times, want 1", fetches)
+	}
+	for i, err := range errs {
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("lookup %d error = %v, want ErrNotFound", i, err)
+		}
+	}
+}
+
+func TestBookCacheLookupRetriesOtherSharedErrors(t *testing.T) {
+	// A rejected token belongs to whoever ran the fetch, so every other caller runs
+	// its own rather than taking the error
+	c := NewBookCache(10)
+	var calls int32
+	const n = 20
+	fetches, ids, errs := concurrentLookups(c, "9780306406157", n, func() (int, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return 0, ErrUnauthorized
+		}
+		return 42, nil
+	})
+	if fetches != n {
+		t.Errorf("fetched %d times, want %d", fetches, n)
+	}
+	failed := 0
+	for i := range ids {
+		switch {
+		case errors.Is(errs[i], ErrUnauthorized):
+			failed++
+		case errs[i] != nil || ids[i] != 42:
+			t.Errorf("lookup %d = %d, %v; want 42", i, ids[i], errs[i])
+		}
+	}
+	if failed != 1 {
+		t.Errorf("%d lookups failed, want only the one that ran the failing fetch", failed)
+	}
+}
+
+func TestBookCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewBookCache(2)
+	c.put(ctx, "a", 1)
+	c.put(ctx, "b", 2)
+	if _, _, ok := c.get(ctx, "a"); !ok { // a is now the most recently used
+		t.Fatalf("a is not cached")
+	}
+	c.put(ctx, "c", 3)
+
+	for isbn13, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, _, ok := c.get(ctx, isbn13); ok != want {
+			t.Errorf("%s cached = %v, want %v", isbn13, ok, want)
+		}
+	}
+	if c.order.Len() != 2 || len(c.entries) != 2 {
+		t.Errorf("cache holds %d entries in order and %d in the map, want 2", c.order.Len(), len(c.entries))
+	}
+
+	// Updating an entry refreshes it without growing the cache
+	c.put(ctx, "c", 4)
+	if bookID, found, ok := c.get(ctx, "c"); bookID != 4 || !found || !ok || c.order.Len() != 2 {
+		t.Errorf("get(c) = %d, %v, %v with %d entries; want 4 among 2", bookID, found, ok, c.order.Len())
+	}
+}
+
+func TestBookCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	c := NewBookCache(10)
+	c.TTL, c.NegativeTTL = time.Hour, -time.Second // misses expire immediately
+	var results []string
+	c.Observe = func(result string) { results = append(results, result) }
+
+	c.put(ctx, "found", 7)
+	c.put(ctx, "missing", 0)
+	if bookID, found, ok := c.get(ctx, "found"); bookID != 7 || !found || !ok {
+		t.Errorf("get(found) = %d, %v, %v; want 7, found", bookID, found, ok)
+	}
+	if _, _, ok := c.get(ctx, "missing"); ok {
+		t.Errorf("expired miss is still cached")
+	}
+
+	c.NegativeTTL = time.Hour
+	c.put(ctx, "missing", 0)
+	if bookID, found, ok := c.get(ctx, "missing"); bookID != 0 || found || !ok {
+		t.Errorf("get(missing) = %d, %v, %v; want a cached miss", bookID, found, ok)
+	}
+	want := []string{CacheHit, CacheMiss, CacheNegativeHit}
+	if fmt.Sprint(results) != fmt.Sprint(want) {
+		t.Errorf("observed %v, want %v", results, want)
+	}
+}
+
+func TestNilBookCache(t *testing.T) {
+	var c *BookCache
+	if NewBookCache(0) != nil {
+		t.Errorf("NewBookCache(0) is not nil")
+	}
+	c.put(context.Background(), "a", 1)
+	if _, _, ok := c.get(context.Background(), "a"); ok {
+		t.Errorf("nil cache returned an entry")
+	}
+	calls := 0
+	for i := 0; i < 2; i++ {
+		if bookID, err := c.lookup("a", func() (int, error) { calls++; return 1, nil }); bookID != 1 || err != nil {
+			t.Errorf("lookup() = %d, %v; want 1", bookID, err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("nil cache fetched %d times, want 2", calls)
+	}
+}
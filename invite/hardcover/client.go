@@ -0,0 +1,187 @@
+// Package hardcover is a client for the Hardcover GraphQL API (https://docs.hardcover.app/api/).
+package hardcover
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultEndpoint is the Hardcover production GraphQL endpoint
+const DefaultEndpoint = "https://api.hardcover.app/v1/graphql"
+
+// Result classes passed to Client.Instrument, suitable for use as metric labels
+const (
+	ResultOK          = "ok"
+	ResultTransport   = "transport_error"
+	ResultHTTP4xx     = "http_4xx"
+	ResultRateLimited = "rate_limited"
+	ResultHTTP5xx     = "http_5xx"
+	ResultDecode      = "decode_error"
+	ResultGraphQL     = "graphql_error"
+)
+
+// Client calls the Hardcover GraphQL API. A Client is safe for concurrent use and
+// should be reused so connections are pooled.
+type Client struct {
+	// Endpoint is the GraphQL endpoint URL
+	Endpoint string
+
+	// HTTPClient sends the requests
+	HTTPClient *http.Client
+
+	// Instrument, if set, is called before each attempt with the GraphQL operation
+	// name. It returns the context to send the request with and a function that is
+	// called with the result class and error once the attempt completes.
+	Instrument func(ctx context.Context, operation string) (context.Context, func(result string, err error))
+
+	// Retry controls retries of rate-limited and failed requests
+	Retry RetryPolicy
+
+	// Books, if set, caches ISBN lookups by FindBookIDByISBN and BookIDsByISBN
+	Books *BookCache
+
+	limits *limiters
+}
+
+// NewClient returns a client for endpoint, or DefaultEndpoint if endpoint is empty.
+// Requests are limited to Hardcover's per-token rate and retried per DefaultRetryPolicy.
+func NewClient(endpoint string) *Client {
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+
+	// Keep enough idle connections for a burst of syncs to reuse them
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 32
+
+	c := &Client{
+		Endpoint:   endpoint,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second, Transport: transport},
+		Retry:      DefaultRetryPolicy,
+	}
+	c.SetRateLimits(DefaultTokenRequestsPerMinute, DefaultTokenBurst, 0, 0)
+	return c
+}
+
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors,omitempty"`
+}
+
+var operationPattern = regexp.MustCompile(`(?:query|mutation)\s+(\w+)`)
+
+// OperationName extracts the operation name from a GraphQL document
+func OperationName(query string) string {
+	if matches := operationPattern.FindStringSubmatch(query); len(matches) > 1 {
+		return matches[1]
+	}
+	return "anonymous"
+}
+
+// Do sends a GraphQL query authenticated with token and decodes the response data
+// into out. token may include a "Bearer " prefix. Requests wait for the client's rate
+// limits and are retried with backoff on 429 and 5xx responses.
+func (c *Client) Do(ctx context.Context, token, query string, variables map[string]interface{}, out interface{}) error {
+	token = strings.TrimPrefix(strings.TrimSpace(token), "Bearer ")
+	operation := OperationName(query)
+	mutation := strings.HasPrefix(strings.TrimSpace(query), "mutation")
+
+	jsonData, err := json.Marshal(graphQLRequest{Query: query, Variables: variables})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := c.limits.wait(ctx, token); err != nil {
+			return err
+		}
+
+		result, err := c.attempt(ctx, token, operation, jsonData, out)
+		if err == nil || attempt >= c.Retry.MaxRetries || !retryable(result, mutation) {
+			return err
+		}
+
+		delay := c.Retry.backoff(attempt+1, err)
+		slog.WarnContext(ctx, "Retrying Hardcover request", "operation", operation, "attempt", attempt+1, "delay", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// attempt sends a single request and returns its result class
+func (c *Client) attempt(ctx context.Context, token, operation string, jsonData []byte, out interface{}) (result string, err error) {
+	result = ResultOK
+	if c.Instrument != nil {
+		var done func(string, error)
+		ctx, done = c.Instrument(ctx, operation)
+		defer func() { done(result, err) }()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint, bytes.NewReader(jsonData))
+	if err != nil {
+		return ResultTransport, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return ResultTransport, fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ResultTransport, fmt.Errorf("failed to read response: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		httpErr := &HTTPError{StatusCode: resp.StatusCode, Body: string(body), RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests:
+			return ResultRateLimited, httpErr
+		case resp.StatusCode >= http.StatusInternalServerError:
+			return ResultHTTP5xx, httpErr
+		default:
+			return ResultHTTP4xx, httpErr
+		}
+	}
+
+	var response graphQLResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return ResultDecode, fmt.Errorf("failed to parse response: %v", err)
+	}
+
+	if len(response.Errors) > 0 {
+		messages := make([]string, len(response.Errors))
+		for i, e := range response.Errors {
+			messages[i] = e.Message
+		}
+		return ResultGraphQL, &GraphQLError{Messages: messages}
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(response.Data, out); err != nil {
+			return ResultDecode, fmt.Errorf("failed to parse response data: %v", err)
+		}
+	}
+	return ResultOK, nil
+}
@@ -0,0 +1,219 @@
+package hardcover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer answers each request with the next of responses, repeating the last,
+// and counts requests
+type testServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	requests  int
+	responses []testResponse
+	auth      []string
+}
+
+type testResponse struct {
+	status     int
+	retryAfter string
+	body       string
+}
+
+func newTestServer(t *testing.T, responses ...testResponse) *testServer {
+	s := &testServer{responses: responses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		s.mu.Lock()
+		response := s.responses[min(s.requests, len(s.responses)-1)]
+		s.requests++
+		s.auth = append(s.auth, r.Header.Get("Authorization"))
+		s.mu.Unlock()
+
+		if response.retryAfter != "" {
+			w.Header().Set("Retry-After", response.retryAfter)
+		}
+		w.WriteHeader(response.status)
+		fmt.Fprint(w, response.body)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// newTestClient returns a client for server without rate limits and with short backoff
+func newTestClient(server *testServer) *Client {
+	c := NewClient(server.URL)
+	c.SetRateLimits(0, 0, 0, 0)
+	c.Retry = RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 20 * time.Millisecond}
+	return c
+}
+
+const (
+	testQuery    = `query TestQuery { me { id } }`
+	testMutation = `mutation TestMutation { insert_user_book(object: {}) { id } }`
+	okBody       = `{"data": {"me": [{"id": 7}]}}`
+)
+
+func TestClientDoRetries(t *testing.T) {
+	ok := testResponse{status: http.StatusOK, body: okBody}
+	unavailable := testResponse{status: http.StatusServiceUnavailable, body: "unavailable"}
+	limited := testResponse{status: http.StatusTooManyRequests, body: "slow down"}
+
+	tests := []struct {
+		name         string
+		query        string
+		responses    []testResponse
+		wantErr      error // matched with errors.Is; nil means success
+		wantStatus   int   // HTTPError status, when an error is expected
+		wantGraphQL  bool  // a GraphQLError is expected
+		wantRequests int
+	}{
+		{name: "query succeeds", query: testQuery, responses: []testResponse{ok}, wantRequests: 1},
+		{name: "query retried on 5xx", query: testQuery, responses: []testResponse{unavailable, unavailable, ok}, wantRequests: 3},
+		{name: "query retried on 429", query: testQuery, responses: []testResponse{limited, ok}, wantRequests: 2},
+		{name: "query gives up after max retries", query: testQuery, responses: []testResponse{unavailable},
+			wantStatus: http.StatusServiceUnavailable, wantRequests: 4},
+		{name: "mutation not retried on 5xx", query: testMutation, responses: []testResponse{unavailable, ok},
+			wantStatus: http.StatusServiceUnavailable, wantRequests: 1},
+		{name: "mutation retried on 429", query: testMutation, responses: []testResponse{limited, limited, ok}, wantRequests: 3},
+		{name: "mutation gives up on 429 after max retries", query: testMutation, responses: []testResponse{limited},
+			wantErr: ErrRateLimited, wantStatus: http.StatusTooManyRequests, wantRequests: 4},
+		{name: "unauthorized not retried", query: testQuery, responses: []testResponse{{status: http.StatusUnauthorized}, ok},
+			wantErr: ErrUnauthorized, wantStatus: http.StatusUnauthorized, wantRequests: 1},
+		{name: "other 4xx not retried", query: testQuery, responses: []testResponse{{status: http.StatusBadRequest}, ok},
+			wantStatus: http.StatusBadRequest, wantRequests: 1},
+		{name: "graphql errors not retried", query: testQuery,
+			responses:   []testResponse{{status: http.StatusOK, body: `{"errors": [{"message": "bad field"}]}`}, ok},
+			wantGraphQL: true, wantRequests: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, tt.responses...)
+			c := newTestClient(server)
+
+			var out struct {
+				Me []struct {
+					ID int `json:"id"`
+				} `json:"me"`
+			}
+			err := c.Do(context.Background(), "token", tt.query, nil, &out)
+
+			if got := server.count(); got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Do() error = %v, want %v", err, tt.wantErr)
+			}
+			var httpErr *HTTPError
+			if tt.wantStatus != 0 {
+				if !errors.As(err, &httpErr) || httpErr.StatusCode != tt.wantStatus {
+					t.Errorf("Do() error = %v, want HTTP %d", err, tt.wantStatus)
+				}
+				return
+			}
+			if tt.wantGraphQL {
+				var gqlErr *GraphQLError
+				if !errors.As(err, &gqlErr) {
+					t.Errorf("Do() error = %v, want a GraphQLError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			if len(out.Me) != 1 || out.Me[0].ID != 7 {
+				t.Errorf("decoded %+v, want me[0].id = 7", out)
+			}
+		})
+	}
+}
+
+func TestClientDoHonoursRetryAfter(t *testing.T) {
+	server := newTestServer(t,
+		testResponse{status: http.StatusTooManyRequests, retryAfter: "1"},
+		testResponse{status: http.StatusOK, body: okBody})
+	c := newTestClient(server)
+	c.Retry.MaxDelay = 2 * time.Second
+
+	start := time.Now()
+	if err := c.Do(context.Background(), "token", testMutation, nil, nil); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want at least the 1s Retry-After", elapsed)
+	}
+}
+
+func TestClientDoStopsOnCancel(t *testing.T) {
+	server := newTestServer(t, testResponse{status: http.StatusServiceUnavailable})
+	c := newTestClient(server)
+	c.Retry = RetryPolicy{MaxRetries: 10, BaseDelay: time.Second, MaxDelay: time.Second}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := c.Do(ctx, "token", testQuery, nil, nil); err == nil {
+		t.Fatal("Do() succeeded, want an error")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Do() returned after %v, want it to stop when the context is done", elapsed)
+	}
+	if got := server.count(); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+}
+
+func TestClientDoAuthorization(t *testing.T) {
+	server := newTestServer(t, testResponse{status: http.StatusOK, body: okBody})
+	c := newTestClient(server)
+
+	for _, token := range []string{"abc", "Bearer abc", "  Bearer abc  "} {
+		if err := c.Do(context.Background(), token, testQuery, nil, nil); err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+	}
+	for _, header := range server.auth {
+		if header != "Bearer abc" {
+			t.Errorf("Authorization = %q, want %q", header, "Bearer abc")
+		}
+	}
+}
+
+func TestClientDoInstrument(t *testing.T) {
+	server := newTestServer(t,
+		testResponse{status: http.StatusTooManyRequests},
+		testResponse{status: http.StatusOK, body: okBody})
+	c := newTestClient(server)
+
+	var operations, results []string
+	c.Instrument = func(ctx context.Context, operation string) (context.Context, func(string, error)) {
+		operations = append(operations, operation)
+		return ctx, func(result string, err error) { results = append(results, result) }
+	}
+	if err := c.Do(context.Background(), "token", testQuery, nil, nil); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+
+	if want := []string{"TestQuery", "TestQuery"}; strings.Join(operations, ",") != strings.Join(want, ",") {
+		t.Errorf("operations = %v, want %v", operations, want)
+	}
+	if want := []string{ResultRateLimited, ResultOK}; strings.Join(results, ",") != strings.Join(want, ",") {
+		t.Errorf("results = %v, want %v", results, want)
+	}
+}
@@ -0,0 +1,67 @@
+package hardcover
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnauthorized means Hardcover rejected the API token
+	ErrUnauthorized = errors.New("hardcover: unauthorized")
+
+	// ErrRateLimited means the request was throttled by Hardcover
+	ErrRateLimited = errors.New("hardcover: rate limited")
+
+	// ErrNotFound means the requested record does not exist on Hardcover
+	ErrNotFound = errors.New("hardcover: not found")
+
+	// ErrDuplicate means an insert conflicted with an existing record
+	ErrDuplicate = errors.New("hardcover: record already exists")
+)
+
+// HTTPError is returned for non-200 responses. It matches ErrUnauthorized for 401
+// and 403 responses and ErrRateLimited for 429 responses.
+type HTTPError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // from the Retry-After header, if any
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Body)
+}
+
+func (e *HTTPError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// GraphQLError is returned when a response carries GraphQL errors. It matches
+// ErrDuplicate for unique constraint violations.
+type GraphQLError struct {
+	Messages []string
+}
+
+func (e *GraphQLError) Error() string {
+	return "graphql errors: " + strings.Join(e.Messages, ", ")
+}
+
+func (e *GraphQLError) Is(target error) bool {
+	if target != ErrDuplicate {
+		return false
+	}
+	for _, message := range e.Messages {
+		if strings.Contains(message, "already exists") || strings.Contains(message, "duplicate") || strings.Contains(message, "unique") {
+			return true
+		}
+	}
+	return false
+}
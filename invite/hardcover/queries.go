@@ -0,0 +1,159 @@
+package hardcover
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dhvogel/bookclurb-invite/isbn"
+)
+
+// Me returns the Hardcover user that token belongs to. It returns ErrUnauthorized if
+// Hardcover does not resolve the token to a user.
+func (c *Client) Me(ctx context.Context, token string) (*User, error) {
+	query := `
+		query Me {
+			me {
+				id
+				username
+				cached_image
+			}
+		}
+	`
+
+	var data struct {
+		Me oneOrMany[User] `json:"me"`
+	}
+	if err := c.Do(ctx, token, query, nil, &data); err != nil {
+		return nil, err
+	}
+	if len(data.Me) == 0 {
+		return nil, fmt.Errorf("%w: no user for token", ErrUnauthorized)
+	}
+	return &data.Me[0], nil
+}
+
+// FindBookIDByISBN returns the ID of the book with an edition matching isbn in either
+// its ISBN-10 or ISBN-13 form, or ErrNotFound if there is none. An invalid ISBN
+// returns an error matching isbn.ErrInvalid without calling Hardcover. Results,
+// including ErrNotFound, are cached in c.Books.
+func (c *Client) FindBookIDByISBN(ctx context.Context, token, isbnValue string) (int, error) {
+	forms, err := isbn.Forms(isbnValue)
+	if err != nil {
+		return 0, err
+	}
+	given := isbn.Normalize(isbnValue)
+	if bookID, found, ok := c.Books.get(ctx, forms[0]); ok {
+		if !found {
+			return 0, fmt.Errorf("%w: no edition with ISBN %s (cached)", ErrNotFound, given)
+		}
+		return bookID, nil
+	}
+
+	return c.Books.lookup(forms[0], func() (int, error) {
+		return c.lookupBookID(ctx, token, forms, given)
+	})
+}
+
+// lookupBookID finds the book with an edition matching one of forms, preferring an
+// edition catalogued under the given form, and caches the result
+func (c *Client) lookupBookID(ctx context.Context, token string, forms []string, given string) (int, error) {
+	editions, err := c.editions(ctx, token, forms)
+	if err != nil {
+		return 0, err
+	}
+	// Prefer an edition catalogued under the exact form we were given
+	bookID := 0
+	for _, edition := range editions {
+		if edition.Book.ID == 0 {
+			continue
+		}
+		if edition.ISBN10 == given || edition.ISBN13 == given {
+			bookID = edition.Book.ID
+			break
+		}
+		if bookID == 0 {
+			bookID = edition.Book.ID
+		}
+	}
+	c.Books.put(ctx, forms[0], bookID)
+	if bookID == 0 {
+		return 0, fmt.Errorf("%w: no edition with ISBN %s", ErrNotFound, given)
+	}
+	return bookID, nil
+}
+
+// editions returns the editions whose ISBN-10 or ISBN-13 is one of isbns
+func (c *Client) editions(ctx context.Context, token string, isbns []string) ([]Edition, error) {
+	query := `
+		query LookupBooks($isbns: [String!]!) {
+			editions(where: {_or: [{isbn_13: {_in: $isbns}}, {isbn_10: {_in: $isbns}}]}) {
+				id
+				isbn_10
+				isbn_13
+				book {
+					id
+				}
+			}
+		}
+	`
+
+	var data struct {
+		Editions []Edition `json:"editions"`
+	}
+	if err := c.Do(ctx, token, query, map[string]interface{}{"isbns": isbns}, &data); err != nil {
+		return nil, err
+	}
+	return data.Editions, nil
+}
+
+// BookIDsByISBN resolves many ISBNs in one request, returning the book ID for each
+// given ISBN that matches an edition in either form. Invalid ISBNs and ISBNs without a
+// match are left out of the map. Only ISBNs not cached in c.Books are looked up.
+func (c *Client) BookIDsByISBN(ctx context.Context, token string, isbns []string) (map[string]int, error) {
+	bookIDs := map[string]int{}
+	formsOf := map[string][]string{}
+	var all []string
+	for _, given := range isbns {
+		forms, err := isbn.Forms(given)
+		if err != nil {
+			continue
+		}
+		if bookID, found, ok := c.Books.get(ctx, forms[0]); ok {
+			if found {
+				bookIDs[given] = bookID
+			}
+			continue
+		}
+		formsOf[given] = forms
+		all = append(all, forms...)
+	}
+	if len(all) == 0 {
+		return bookIDs, nil
+	}
+
+	editions, err := c.editions(ctx, token, all)
+	if err != nil {
+		return nil, err
+	}
+	byISBN := map[string]int{}
+	for _, edition := range editions {
+		if edition.Book.ID == 0 {
+			continue
+		}
+		for _, form := range []string{edition.ISBN10, edition.ISBN13} {
+			if _, ok := byISBN[form]; form != "" && !ok {
+				byISBN[form] = edition.Book.ID
+			}
+		}
+	}
+	for given, forms := range formsOf {
+		for _, form := range forms {
+			if bookID, ok := byISBN[form]; ok {
+				bookIDs[given] = bookID
+				break
+			}
+		}
+		c.Books.put(ctx, forms[0], bookIDs[given])
+	}
+	return bookIDs, nil
+}
@@ -0,0 +1,148 @@
+package hardcover
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Hardcover allows 60 requests per minute for each API token
+const (
+	DefaultTokenRequestsPerMinute = 60
+	DefaultTokenBurst             = 10
+)
+
+// maxTrackedTokens bounds the per-token limiter map; idle limiters are pruned past it
+const maxTrackedTokens = 1000
+
+// RetryPolicy controls how failed requests are retried
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt
+	MaxRetries int
+	// BaseDelay is the backoff before the first retry; it doubles on each retry
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff and any Retry-After wait
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy retries three times, waiting about 0.5s, 1s and 2s
+var DefaultRetryPolicy = RetryPolicy{MaxRetries: 3, BaseDelay: 500 * time.Millisecond, MaxDelay: 10 * time.Second}
+
+// limiters throttles requests globally and per API token
+type limiters struct {
+	global *rate.Limiter // nil means unlimited
+
+	tokenLimit rate.Limit
+	tokenBurst int
+
+	mu     sync.Mutex
+	tokens map[[sha256.Size]byte]*rate.Limiter
+}
+
+// perMinute converts a requests-per-minute budget to a rate.Limit; 0 means unlimited
+func perMinute(requests int) rate.Limit {
+	if requests <= 0 {
+		return rate.Inf
+	}
+	return rate.Limit(float64(requests) / 60)
+}
+
+// SetRateLimits configures client-side throttling. tokenPerMinute applies to each API
+// token and globalPerMinute to all requests from this client; 0 disables a limit.
+func (c *Client) SetRateLimits(tokenPerMinute, tokenBurst, globalPerMinute, globalBurst int) {
+	l := &limiters{
+		tokenLimit: perMinute(tokenPerMinute),
+		tokenBurst: max(tokenBurst, 1),
+		tokens:     map[[sha256.Size]byte]*rate.Limiter{},
+	}
+	if globalPerMinute > 0 {
+		l.global = rate.NewLimiter(perMinute(globalPerMinute), max(globalBurst, 1))
+	}
+	c.limits = l
+}
+
+// wait blocks until both the global and the token's limiter allow a request
+func (l *limiters) wait(ctx context.Context, token string) error {
+	if l == nil {
+		return nil
+	}
+	if l.global != nil {
+		if err := l.global.Wait(ctx); err != nil {
+			return fmt.Errorf("%w: global limit: %v", ErrRateLimited, err)
+		}
+	}
+	if err := l.forToken(token).Wait(ctx); err != nil {
+		return fmt.Errorf("%w: token limit: %v", ErrRateLimited, err)
+	}
+	return nil
+}
+
+// forToken returns the limiter for token, keyed by its hash so tokens are not retained
+func (l *limiters) forToken(token string) *rate.Limiter {
+	key := sha256.Sum256([]byte(token))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limiter, ok := l.tokens[key]
+	if ok {
+		return limiter
+	}
+	if len(l.tokens) >= maxTrackedTokens {
+		// A limiter that has refilled completely carries no state worth keeping
+		for k, existing := range l.tokens {
+			if existing.Tokens() >= float64(l.tokenBurst) {
+				delete(l.tokens, k)
+			}
+		}
+	}
+	limiter = rate.NewLimiter(l.tokenLimit, l.tokenBurst)
+	l.tokens[key] = limiter
+	return limiter
+}
+
+// retryable reports whether an attempt with the given result class should be retried.
+// Mutations are only retried when Hardcover rejected them outright with 429, since a
+// 5xx or dropped connection may have been applied.
+func retryable(result string, mutation bool) bool {
+	switch result {
+	case ResultRateLimited:
+		return true
+	case ResultTransport, ResultHTTP5xx:
+		return !mutation
+	}
+	return false
+}
+
+// backoff returns the wait before retry number attempt (1-based), preferring the
+// server's Retry-After when given
+func (p RetryPolicy) backoff(attempt int, err error) time.Duration {
+	if httpErr, ok := err.(*HTTPError); ok && httpErr.RetryAfter > 0 {
+		return min(httpErr.RetryAfter, p.MaxDelay)
+	}
+	delay := p.BaseDelay << (attempt - 1)
+	// Jitter spreads out retries from a burst of concurrent syncs
+	delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+	return min(delay, p.MaxDelay)
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		return max(time.Until(when), 0)
+	}
+	return 0
+}
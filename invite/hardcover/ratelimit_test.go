@@ -0,0 +1,133 @@
+package hardcover
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+		// dates are compared within a tolerance, since time passes while parsing
+		approx bool
+	}{
+		{name: "empty", value: "", want: 0},
+		{name: "seconds", value: "5", want: 5 * time.Second},
+		{name: "zero", value: "0", want: 0},
+		{name: "negative", value: "-3", want: 0},
+		{name: "not a number", value: "soon", want: 0},
+		{name: "fraction", value: "1.5", want: 0},
+		{name: "future date", value: time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat), want: 30 * time.Second, approx: true},
+		{name: "past date", value: time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseRetryAfter(tt.value)
+			if tt.approx {
+				// HTTP dates have second precision
+				if got < tt.want-2*time.Second || got > tt.want {
+					t.Errorf("parseRetryAfter(%q) = %v, want about %v", tt.value, got, tt.want)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	tests := []struct {
+		name     string
+		attempt  int
+		err      error
+		min, max time.Duration
+	}{
+		// Each retry doubles the base delay, jittered to between half and all of it
+		{name: "first retry", attempt: 1, err: errors.New("boom"), min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{name: "second retry", attempt: 2, err: errors.New("boom"), min: 100 * time.Millisecond, max: 200 * time.Millisecond},
+		{name: "third retry", attempt: 3, err: errors.New("boom"), min: 200 * time.Millisecond, max: 400 * time.Millisecond},
+		{name: "capped", attempt: 6, err: errors.New("boom"), min: time.Second, max: time.Second},
+		{name: "retry-after", attempt: 1, err: &HTTPError{StatusCode: 429, RetryAfter: 700 * time.Millisecond},
+			min: 700 * time.Millisecond, max: 700 * time.Millisecond},
+		{name: "retry-after capped", attempt: 1, err: &HTTPError{StatusCode: 429, RetryAfter: time.Minute}, min: time.Second, max: time.Second},
+		{name: "HTTP error without retry-after", attempt: 2, err: &HTTPError{StatusCode: 503},
+			min: 100 * time.Millisecond, max: 200 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 50; i++ {
+				if got := policy.backoff(tt.attempt, tt.err); got < tt.min || got > tt.max {
+					t.Fatalf("backoff(%d) = %v, want between %v and %v", tt.attempt, got, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		result   string
+		mutation bool
+		want     bool
+	}{
+		{result: ResultRateLimited, want: true},
+		{result: ResultRateLimited, mutation: true, want: true},
+		{result: ResultHTTP5xx, want: true},
+		{result: ResultHTTP5xx, mutation: true, want: false},
+		{result: ResultTransport, want: true},
+		{result: ResultTransport, mutation: true, want: false},
+		{result: ResultHTTP4xx, want: false},
+		{result: ResultGraphQL, want: false},
+		{result: ResultDecode, want: false},
+		{result: ResultOK, want: false},
+	}
+	for _, tt := range tests {
+		if got := retryable(tt.result, tt.mutation); got != tt.want {
+			t.Errorf("retryable(%q, mutation=%v) = %v, want %v", tt.result, tt.mutation, got, tt.want)
+		}
+	}
+}
+
+func TestLimitersPerToken(t *testing.T) {
+	c := &Client{}
+	c.SetRateLimits(1, 2, 0, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// Each token gets its own burst
+	for _, token := range []string{"a", "a", "b", "b"} {
+		if err := c.limits.wait(ctx, token); err != nil {
+			t.Fatalf("wait(%q) within burst: %v", token, err)
+		}
+	}
+	// A third request on the same token would wait about a minute
+	if err := c.limits.wait(ctx, "a"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("wait() past burst error = %v, want ErrRateLimited", err)
+	}
+}
+
+func TestLimitersGlobal(t *testing.T) {
+	c := &Client{}
+	c.SetRateLimits(0, 0, 1, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := c.limits.wait(ctx, "a"); err != nil {
+		t.Fatalf("first wait: %v", err)
+	}
+	// The global limit applies across tokens
+	if err := c.limits.wait(ctx, "b"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("wait() past global burst error = %v, want ErrRateLimited", err)
+	}
+}
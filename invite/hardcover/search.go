@@ -0,0 +1,98 @@
+package hardcover
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+)
+
+// BookCandidate is a book returned by a Hardcover search
+type BookCandidate struct {
+	ID          int
+	Title       string
+	Authors     []string
+	ReleaseYear int
+	UsersCount  int // readers who have the book on a shelf, a popularity signal
+}
+
+// SearchBooks searches Hardcover's book index and returns up to perPage results in
+// Hardcover's relevance order
+func (c *Client) SearchBooks(ctx context.Context, token, query string, perPage int) ([]BookCandidate, error) {
+	gql := `
+		query SearchBooks($query: String!, $perPage: Int!) {
+			search(query: $query, query_type: "Book", per_page: $perPage, page: 1) {
+				results
+			}
+		}
+	`
+
+	var data struct {
+		Search struct {
+			Results searchResults `json:"results"`
+		} `json:"search"`
+	}
+	if err := c.Do(ctx, token, gql, map[string]interface{}{"query": query, "perPage": perPage}, &data); err != nil {
+		return nil, err
+	}
+
+	candidates := make([]BookCandidate, 0, len(data.Search.Results.Hits))
+	for _, hit := range data.Search.Results.Hits {
+		doc := hit.Document
+		if doc.ID == 0 {
+			continue
+		}
+		candidates = append(candidates, BookCandidate{
+			ID:          int(doc.ID),
+			Title:       doc.Title,
+			Authors:     doc.AuthorNames,
+			ReleaseYear: int(doc.ReleaseYear),
+			UsersCount:  int(doc.UsersCount),
+		})
+	}
+	return candidates, nil
+}
+
+// searchResults is the search index response embedded in the GraphQL result
+type searchResults struct {
+	Hits []struct {
+		Document struct {
+			ID          looseInt `json:"id"`
+			Title       string   `json:"title"`
+			AuthorNames []string `json:"author_names"`
+			ReleaseYear looseInt `json:"release_year"`
+			UsersCount  looseInt `json:"users_count"`
+		} `json:"document"`
+	} `json:"hits"`
+}
+
+// UnmarshalJSON accepts the results either as an object or as a JSON-encoded string
+func (r *searchResults) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var encoded string
+		if err := json.Unmarshal(data, &encoded); err != nil {
+			return err
+		}
+		data = []byte(encoded)
+	}
+	type plain searchResults
+	return json.Unmarshal(data, (*plain)(r))
+}
+
+// looseInt decodes numbers that the search index returns as numbers or strings
+type looseInt int
+
+func (i *looseInt) UnmarshalJSON(data []byte) error {
+	s := string(bytes.Trim(bytes.TrimSpace(data), `"`))
+	if s == "" || s == "null" {
+		*i = 0
+		return nil
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	*i = looseInt(n)
+	return nil
+}
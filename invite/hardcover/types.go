@@ -0,0 +1,125 @@
+package hardcover
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// User is a Hardcover user account
+type User struct {
+	ID          int    `json:"id"`
+	Username    string `json:"username"`
+	CachedImage Image  `json:"cached_image"`
+}
+
+// Image is a cached image reference
+type Image struct {
+	URL string `json:"url"`
+}
+
+// UnmarshalJSON tolerates missing or non-object images, which decode as an empty Image
+func (i *Image) UnmarshalJSON(data []byte) error {
+	var image struct {
+		URL string `json:"url"`
+	}
+	if err := json.Unmarshal(data, &image); err == nil {
+		i.URL = image.URL
+	}
+	return nil
+}
+
+// Edition is a specific published edition of a book
+type Edition struct {
+	ID     int    `json:"id"`
+	ISBN10 string `json:"isbn_10"`
+	ISBN13 string `json:"isbn_13"`
+	Book   struct {
+		ID int `json:"id"`
+	} `json:"book"`
+}
+
+// UserBook is a book on a user's Hardcover shelf
+type UserBook struct {
+	ID       int      `json:"id"`
+	BookID   int      `json:"book_id"`
+	Rating   *float64 `json:"rating"`
+	Review   string   `json:"review"`
+	StatusID int      `json:"status_id"`
+
+	// Reads are the user's read-throughs of the book, newest first
+	Reads []UserBookRead `json:"user_book_reads"`
+}
+
+// UserBookRead is one read-through of a user_book, tracking dates and progress
+type UserBookRead struct {
+	ID            int    `json:"id"`
+	StartedAt     string `json:"started_at"`  // YYYY-MM-DD
+	FinishedAt    string `json:"finished_at"` // YYYY-MM-DD, empty while reading
+	ProgressPages int    `json:"progress_pages"`
+}
+
+// UserBookReadInput sets fields of a read-through. Zero values are left unset.
+type UserBookReadInput struct {
+	StartedAt     string // YYYY-MM-DD
+	FinishedAt    string // YYYY-MM-DD
+	ProgressPages int
+}
+
+// UserBookInput sets fields of a user_book. Zero values are left unset, so an update
+// only changes the fields that are given.
+type UserBookInput struct {
+	BookID    int
+	Rating    float64
+	Review    string
+	StatusID  int
+	ReadCount int
+
+	// ClearReview removes any review already on the user_book; Review is ignored
+	ClearReview bool
+
+	// PrivacySettingID is who can see the user_book, one of the Privacy constants
+	PrivacySettingID int
+	// ReviewHasSpoilers flags the review as containing spoilers; nil leaves it unset
+	ReviewHasSpoilers *bool
+}
+
+// Reading statuses for UserBook.StatusID
+const (
+	StatusWantToRead       = 1
+	StatusCurrentlyReading = 2
+	StatusRead             = 3
+	StatusPaused           = 4
+	StatusDidNotFinish     = 5
+)
+
+// Who can see a user_book, for UserBookInput.PrivacySettingID
+const (
+	PrivacyPublic    = 1
+	PrivacyFollowers = 2
+	PrivacyPrivate   = 3
+)
+
+// oneOrMany decodes fields that Hardcover returns either as a single object or as a list
+type oneOrMany[T any] []T
+
+func (l *oneOrMany[T]) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*l = nil
+		return nil
+	}
+	if len(data) > 0 && data[0] == '[' {
+		var items []T
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+		*l = items
+		return nil
+	}
+	var item T
+	if err := json.Unmarshal(data, &item); err != nil {
+		return err
+	}
+	*l = oneOrMany[T]{item}
+	return nil
+}
@@ -0,0 +1,335 @@
+package hardcover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FindUserBook returns the token owner's user_book for bookID, or ErrNotFound if the
+// book is not on their shelf
+func (c *Client) FindUserBook(ctx context.Context, token string, bookID int) (*UserBook, error) {
+	query := `
+		query FindUserBook($bookId: Int!) {
+			me {
+				user_books(where: {book_id: {_eq: $bookId}}, limit: 1) {
+					id
+					book_id
+					rating
+					review
+					status_id
+					user_book_reads(order_by: {id: desc}) {
+						id
+						started_at
+						finished_at
+						progress_pages
+					}
+				}
+			}
+		}
+	`
+
+	var data struct {
+		Me oneOrMany[struct {
+			UserBooks []UserBook `json:"user_books"`
+		}] `json:"me"`
+	}
+	if err := c.Do(ctx, token, query, map[string]interface{}{"bookId": bookID}, &data); err != nil {
+		return nil, err
+	}
+	if len(data.Me) == 0 {
+		return nil, fmt.Errorf("%w: no user for token", ErrUnauthorized)
+	}
+	if len(data.Me[0].UserBooks) == 0 {
+		return nil, fmt.Errorf("%w: book %d is not on the user's shelf", ErrNotFound, bookID)
+	}
+	return &data.Me[0].UserBooks[0], nil
+}
+
+// UserBooks returns the token owner's user_books for the given books, with their
+// read-throughs. Books that are not on the shelf are left out.
+func (c *Client) UserBooks(ctx context.Context, token string, bookIDs []int) ([]UserBook, error) {
+	if len(bookIDs) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		query UserBooks($bookIds: [Int!]!) {
+			me {
+				user_books(where: {book_id: {_in: $bookIds}}) {
+					id
+					book_id
+					rating
+					review
+					status_id
+					user_book_reads(order_by: {id: desc}) {
+						id
+						started_at
+						finished_at
+						progress_pages
+					}
+				}
+			}
+		}
+	`
+
+	var data struct {
+		Me oneOrMany[struct {
+			UserBooks []UserBook `json:"user_books"`
+		}] `json:"me"`
+	}
+	if err := c.Do(ctx, token, query, map[string]interface{}{"bookIds": bookIDs}, &data); err != nil {
+		return nil, err
+	}
+	if len(data.Me) == 0 {
+		return nil, fmt.Errorf("%w: no user for token", ErrUnauthorized)
+	}
+	return data.Me[0].UserBooks, nil
+}
+
+// userBookFields returns the GraphQL object fields and variables for the non-zero
+// fields of input
+func userBookFields(input UserBookInput) (declarations, fields []string, variables map[string]interface{}) {
+	variables = map[string]interface{}{}
+	add := func(name, field, gqlType string, value interface{}) {
+		declarations = append(declarations, fmt.Sprintf("$%s: %s", name, gqlType))
+		fields = append(fields, fmt.Sprintf("%s: $%s", field, name))
+		variables[name] = value
+	}
+
+	if input.BookID != 0 {
+		add("bookId", "book_id", "Int!", input.BookID)
+	}
+	if input.Rating != 0 {
+		add("rating", "rating", "numeric!", input.Rating)
+	}
+	if input.ClearReview {
+		fields = append(fields, "review: null")
+	} else if input.Review != "" {
+		add("review", "review", "String!", input.Review)
+	}
+	if input.StatusID != 0 {
+		add("statusId", "status_id", "Int!", input.StatusID)
+	}
+	if input.ReadCount != 0 {
+		add("readCount", "read_count", "Int!", input.ReadCount)
+	}
+	if input.PrivacySettingID != 0 {
+		add("privacySettingId", "privacy_setting_id", "Int!", input.PrivacySettingID)
+	}
+	if input.ReviewHasSpoilers != nil {
+		add("reviewHasSpoilers", "review_has_spoilers", "Boolean!", *input.ReviewHasSpoilers)
+	}
+	return declarations, fields, variables
+}
+
+// InsertUserBook adds a book to the token owner's shelf and returns the new user_book
+// ID. It returns an error matching ErrDuplicate if the book is already on the shelf.
+func (c *Client) InsertUserBook(ctx context.Context, token string, input UserBookInput) (int, error) {
+	declarations, fields, variables := userBookFields(input)
+	query := fmt.Sprintf(`
+		mutation CreateUserBook(%s) {
+			insert_user_book(object: {%s}) {
+				id
+			}
+		}
+	`, strings.Join(declarations, ", "), strings.Join(fields, ", "))
+
+	var data struct {
+		InsertUserBook oneOrMany[struct {
+			ID int `json:"id"`
+		}] `json:"insert_user_book"`
+	}
+	if err := c.Do(ctx, token, query, variables, &data); err != nil {
+		return 0, err
+	}
+	if len(data.InsertUserBook) == 0 || data.InsertUserBook[0].ID == 0 {
+		return 0, fmt.Errorf("failed to create user_book relationship")
+	}
+	return data.InsertUserBook[0].ID, nil
+}
+
+// UpdateUserBook sets the non-zero fields of input on an existing user_book. BookID
+// is ignored.
+func (c *Client) UpdateUserBook(ctx context.Context, token string, userBookID int, input UserBookInput) error {
+	input.BookID = 0
+	declarations, fields, variables := userBookFields(input)
+	if len(fields) == 0 {
+		return nil
+	}
+	variables["id"] = userBookID
+	query := fmt.Sprintf(`
+		mutation UpdateUserBook($id: Int!, %s) {
+			update_user_book(id: $id, object: {%s}) {
+				id
+			}
+		}
+	`, strings.Join(declarations, ", "), strings.Join(fields, ", "))
+
+	var data struct {
+		UpdateUserBook oneOrMany[struct {
+			ID int `json:"id"`
+		}] `json:"update_user_book"`
+	}
+	if err := c.Do(ctx, token, query, variables, &data); err != nil {
+		return err
+	}
+	if len(data.UpdateUserBook) == 0 || data.UpdateUserBook[0].ID == 0 {
+		return fmt.Errorf("%w: user_book %d", ErrNotFound, userBookID)
+	}
+	return nil
+}
+
+// ClearUserBookReview removes the review from a user_book, and its rating too if
+// clearRating is set. It returns ErrNotFound if the user_book no longer exists.
+func (c *Client) ClearUserBookReview(ctx context.Context, token string, userBookID int, clearRating bool) error {
+	fields := "review: null"
+	if clearRating {
+		fields += ", rating: null"
+	}
+	query := fmt.Sprintf(`
+		mutation ClearUserBookReview($id: Int!) {
+			update_user_book(id: $id, object: {%s}) {
+				id
+			}
+		}
+	`, fields)
+
+	var data struct {
+		UpdateUserBook oneOrMany[struct {
+			ID int `json:"id"`
+		}] `json:"update_user_book"`
+	}
+	if err := c.Do(ctx, token, query, map[string]interface{}{"id": userBookID}, &data); err != nil {
+		return err
+	}
+	if len(data.UpdateUserBook) == 0 || data.UpdateUserBook[0].ID == 0 {
+		return fmt.Errorf("%w: user_book %d", ErrNotFound, userBookID)
+	}
+	return nil
+}
+
+// DeleteUserBook removes a user_book, with its rating, review and read-throughs, from
+// the token owner's shelf. It returns ErrNotFound if the user_book no longer exists.
+func (c *Client) DeleteUserBook(ctx context.Context, token string, userBookID int) error {
+	query := `
+		mutation DeleteUserBook($id: Int!) {
+			delete_user_book(id: $id) {
+				id
+			}
+		}
+	`
+
+	var data struct {
+		DeleteUserBook oneOrMany[struct {
+			ID int `json:"id"`
+		}] `json:"delete_user_book"`
+	}
+	if err := c.Do(ctx, token, query, map[string]interface{}{"id": userBookID}, &data); err != nil {
+		return err
+	}
+	if len(data.DeleteUserBook) == 0 || data.DeleteUserBook[0].ID == 0 {
+		return fmt.Errorf("%w: user_book %d", ErrNotFound, userBookID)
+	}
+	return nil
+}
+
+// UpsertUserBook updates the token owner's user_book for input.BookID if there is one,
+// and inserts it otherwise. It returns the user_book ID and whether it was created.
+func (c *Client) UpsertUserBook(ctx context.Context, token string, input UserBookInput) (id int, created bool, err error) {
+	existing, err := c.FindUserBook(ctx, token, input.BookID)
+	if errors.Is(err, ErrNotFound) {
+		id, err = c.InsertUserBook(ctx, token, input)
+		if !errors.Is(err, ErrDuplicate) {
+			return id, err == nil, err
+		}
+		// Added concurrently since the lookup; fall through and update it
+		existing, err = c.FindUserBook(ctx, token, input.BookID)
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	update := input
+	update.ReadCount = 0 // keep the reader's own re-read count
+	if err := c.UpdateUserBook(ctx, token, existing.ID, update); err != nil {
+		return 0, false, err
+	}
+	return existing.ID, false, nil
+}
+
+// userBookReadInput converts input to a DatesReadInput object, omitting zero values
+func userBookReadInput(input UserBookReadInput) map[string]interface{} {
+	object := map[string]interface{}{}
+	if input.StartedAt != "" {
+		object["started_at"] = input.StartedAt
+	}
+	if input.FinishedAt != "" {
+		object["finished_at"] = input.FinishedAt
+	}
+	if input.ProgressPages != 0 {
+		object["progress_pages"] = input.ProgressPages
+	}
+	return object
+}
+
+// InsertUserBookRead starts a new read-through of a user_book and returns its ID
+func (c *Client) InsertUserBookRead(ctx context.Context, token string, userBookID int, input UserBookReadInput) (int, error) {
+	query := `
+		mutation InsertUserBookRead($userBookId: Int!, $read: DatesReadInput!) {
+			insert_user_book_read(user_book_id: $userBookId, user_book_read: $read) {
+				id
+				error
+			}
+		}
+	`
+
+	var data struct {
+		InsertUserBookRead oneOrMany[struct {
+			ID    int    `json:"id"`
+			Error string `json:"error"`
+		}] `json:"insert_user_book_read"`
+	}
+	variables := map[string]interface{}{"userBookId": userBookID, "read": userBookReadInput(input)}
+	if err := c.Do(ctx, token, query, variables, &data); err != nil {
+		return 0, err
+	}
+	if len(data.InsertUserBookRead) == 0 || data.InsertUserBookRead[0].ID == 0 {
+		if len(data.InsertUserBookRead) > 0 && data.InsertUserBookRead[0].Error != "" {
+			return 0, &GraphQLError{Messages: []string{data.InsertUserBookRead[0].Error}}
+		}
+		return 0, fmt.Errorf("failed to create user_book_read")
+	}
+	return data.InsertUserBookRead[0].ID, nil
+}
+
+// UpdateUserBookRead sets the non-zero fields of input on an existing read-through
+func (c *Client) UpdateUserBookRead(ctx context.Context, token string, readID int, input UserBookReadInput) error {
+	query := `
+		mutation UpdateUserBookRead($id: Int!, $read: DatesReadInput!) {
+			update_user_book_read(id: $id, object: $read) {
+				id
+				error
+			}
+		}
+	`
+
+	var data struct {
+		UpdateUserBookRead oneOrMany[struct {
+			ID    int    `json:"id"`
+			Error string `json:"error"`
+		}] `json:"update_user_book_read"`
+	}
+	variables := map[string]interface{}{"id": readID, "read": userBookReadInput(input)}
+	if err := c.Do(ctx, token, query, variables, &data); err != nil {
+		return err
+	}
+	if len(data.UpdateUserBookRead) == 0 || data.UpdateUserBookRead[0].ID == 0 {
+		if len(data.UpdateUserBookRead) > 0 && data.UpdateUserBookRead[0].Error != "" {
+			return &GraphQLError{Messages: []string{data.UpdateUserBookRead[0].Error}}
+		}
+		return fmt.Errorf("%w: user_book_read %d", ErrNotFound, readID)
+	}
+	return nil
+}
@@ -0,0 +1,452 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"firebase.google.com/go/v4/db"
+)
+
+// maxSyncHistory is how many sync attempts are kept for each user; older ones are pruned
+const maxSyncHistory = 100
+
+// maxSyncRetryBatch is how many failed attempts one retry request re-runs. A retry
+// runs within the request, and each attempt takes a few Hardcover calls.
+const maxSyncRetryBatch = 20
+
+// nonRetryableSyncErrors are the error codes a retry can't fix
+var nonRetryableSyncErrors = map[string]bool{
+	errCodeInvalidISBN:        true,
+	errCodeHardcoverNotLinked: true,
+}
+
+// Where a sync attempt came from
+const (
+	syncSourceRating      = "rating"       // POST /v1/me/hardcover/ratings
+	syncSourceReview      = "review"       // POST /v1/me/hardcover/reviews
+	syncSourceClubRatings = "club_ratings" // POST /v1/clubs/{clubId}/hardcover/ratings
+)
+
+// SyncAttempt is one rating or review sync to Hardcover, stored under
+// users/{uid}/hardcoverSyncHistory/{id}. IDs sort oldest first. Review text isn't
+// stored: an attempt that sent a review refers to the user's review of the book in the
+// club's history, which a retry reads again.
+type SyncAttempt struct {
+	ID              string  `json:"id,omitempty"` // the record's key; not stored in it
+	At              int64   `json:"at"`           // Unix seconds
+	Source          string  `json:"source"`       // "rating", "review" or "club_ratings"
+	ClubID          string  `json:"clubId,omitempty"`
+	ISBN            string  `json:"isbn,omitempty"`
+	Title           string  `json:"title,omitempty"`
+	Author          string  `json:"author,omitempty"`
+	HardcoverBookID int     `json:"hardcoverBookId,omitempty"` // 0 if the book lookup failed
+	UserBookID      int     `json:"hardcoverUserBookId,omitempty"`
+	Rating          float64 `json:"rating,omitempty"`
+	Review          bool    `json:"review,omitempty"`       // a review was sent; it is the user's review in clubId's history
+	Outcome         string  `json:"outcome"`                // "synced" or "failed"
+	Action          string  `json:"action,omitempty"`       // "created" or "updated" when synced
+	Error           string  `json:"error,omitempty"`        // error code when failed
+	ErrorMessage    string  `json:"errorMessage,omitempty"` // description of the error code when failed
+	RetryOf         string  `json:"retryOf,omitempty"`      // the failed attempt this retried
+	RetriedBy       string  `json:"retriedBy,omitempty"`    // the latest retry of this attempt
+	RetrySkipped    string  `json:"retrySkipped,omitempty"` // why the latest retry skipped this attempt (a RetrySkip reason)
+	Retryable       bool    `json:"retryable,omitempty"`    // set when listed; not stored
+}
+
+// ref returns the book the attempt synced, preferring the Hardcover book it resolved to
+func (a *SyncAttempt) ref() bookRef {
+	return bookRef{ISBN: a.ISBN, Title: a.Title, Author: a.Author, HardcoverBookID: a.HardcoverBookID}
+}
+
+// syncHistoryPath returns the path holding uid's sync attempts
+func syncHistoryPath(userID string) string {
+	return fmt.Sprintf("users/%s/hardcoverSyncHistory", userID)
+}
+
+// syncAndRecord syncs attempt's rating and review of book to Hardcover, records the
+// attempt in the user's sync history and tracks the user_book it wrote. It returns the
+// recorded attempt. A review that settings keep off Hardcover isn't sent.
+func syncAndRecord(ctx context.Context, userID, token string, attempt SyncAttempt, book bookRef, review string, settings HardcoverSyncSettings) (SyncAttempt, error) {
+	attempt.ISBN, attempt.Title, attempt.Author = book.ISBN, book.Title, book.Author
+	if settings.RatingOnly {
+		review = ""
+	}
+	attempt.Review = review != ""
+	result, err := syncRatingToHardcover(ctx, token, book, attempt.Rating, review, settings)
+	attempt.HardcoverBookID, attempt.UserBookID, attempt.Action = result.bookID, result.userBookID, result.action
+	if err == nil {
+		trackUserBook(ctx, userID, result.bookID, result.userBookID, result.action == syncActionCreated)
+	}
+	recordSyncAttempt(ctx, userID, &attempt, err)
+	return attempt, err
+}
+
+// recordSyncAttempt saves attempt with the outcome of err, setting its ID. Failures to
+// save are logged; they don't fail the sync.
+func recordSyncAttempt(ctx context.Context, userID string, attempt *SyncAttempt, err error) {
+	attempt.At = time.Now().Unix()
+	attempt.Outcome = memberSyncSynced
+	if err != nil {
+		attempt.Outcome = memberSyncFailed
+		attempt.Error = hardcoverErrorCode(err)
+		attempt.ErrorMessage = hardcoverErrorMessages[attempt.Error]
+	}
+
+	historyRef := firebaseDB.NewRef(syncHistoryPath(userID))
+	stored := *attempt
+	stored.ID = ""
+	ref, pushErr := firebasePush(ctx, "users", historyRef, stored)
+	if pushErr != nil {
+		slog.WarnContext(ctx, "Failed to record Hardcover sync attempt", "uid", userID, "isbn", attempt.ISBN, "error", pushErr)
+		return
+	}
+	attempt.ID = ref.Key
+
+	if err := trimToNewest(ctx, "users", historyRef, maxSyncHistory); err != nil {
+		slog.WarnContext(ctx, "Failed to prune Hardcover sync history", "uid", userID, "error", err)
+	}
+}
+
+// trimToNewest deletes the oldest children of ref, by key, until keep remain. Push IDs
+// sort oldest first.
+func trimToNewest(ctx context.Context, resource string, ref *db.Ref, keep int) error {
+	var keys map[string]bool
+	if err := firebaseGetShallow(ctx, resource, ref, &keys); err != nil {
+		return err
+	}
+	if len(keys) <= keep {
+		return nil
+	}
+	ids := make([]string, 0, len(keys))
+	for id := range keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	pruned := map[string]interface{}{}
+	for _, id := range ids[:len(ids)-keep] {
+		pruned[id] = nil
+	}
+	return firebaseUpdate(ctx, resource, ref, pruned)
+}
+
+// storedSyncAttempt is a SyncAttempt as read from the database, including the review
+// text that attempts used to store
+type storedSyncAttempt struct {
+	SyncAttempt
+	LegacyReviewText string `json:"reviewText,omitempty"`
+}
+
+// getSyncHistory loads uid's sync attempts, newest first. Attempts recorded before
+// review text and raw errors were dropped from the history are rewritten to refer to
+// the club review and describe only the error code.
+func getSyncHistory(ctx context.Context, userID string) ([]SyncAttempt, error) {
+	historyRef := firebaseDB.NewRef(syncHistoryPath(userID))
+	var stored map[string]storedSyncAttempt
+	if err := firebaseGet(ctx, "users", historyRef, &stored); err != nil {
+		return nil, fmt.Errorf("failed to load sync history: %v", err)
+	}
+	attempts := make([]SyncAttempt, 0, len(stored))
+	legacy := map[string]interface{}{}
+	for id, record := range stored {
+		attempt := record.SyncAttempt
+		attempt.ID = id
+		if record.LegacyReviewText != "" {
+			attempt.Review = true
+			legacy[id+"/reviewText"] = nil
+			legacy[id+"/review"] = true
+		}
+		if attempt.Error != "" && attempt.ErrorMessage != hardcoverErrorMessages[attempt.Error] {
+			attempt.ErrorMessage = hardcoverErrorMessages[attempt.Error]
+			legacy[id+"/errorMessage"] = attempt.ErrorMessage
+		}
+		attempts = append(attempts, attempt)
+	}
+	if len(legacy) > 0 {
+		if err := firebaseUpdate(ctx, "users", historyRef, legacy); err != nil {
+			slog.WarnContext(ctx, "Failed to rewrite legacy Hardcover sync attempts", "uid", userID, "error", err)
+		}
+	}
+	sort.Slice(attempts, func(i, j int) bool { return attempts[i].ID > attempts[j].ID })
+	return attempts, nil
+}
+
+// retryable reports whether the attempt is counted as retryable and retried by default:
+// it can be retried and no retry has skipped it. Skipped attempts would be skipped
+// again until the user changes their settings or review, so they are only retried
+// when asked for by ID.
+func (a *SyncAttempt) retryable() bool {
+	return a.canRetry() && a.RetrySkipped == ""
+}
+
+// canRetry reports whether the attempt failed, has not been retried since and could
+// succeed on retry. A review sent without a club can't be read again.
+func (a *SyncAttempt) canRetry() bool {
+	return a.Outcome == memberSyncFailed && a.RetriedBy == "" && !nonRetryableSyncErrors[a.Error] &&
+		!(a.Review && a.ClubID == "")
+}
+
+// clubReview returns the user's review of the attempt's book in the club's history,
+// matching by ISBN, or by title if the attempt has no ISBN
+func (a *SyncAttempt) clubReview(books []ClubBookRead, userID string) string {
+	for _, book := range books {
+		if a.ISBN != "" && normalizeISBN(book.ISBN) == a.ISBN || a.ISBN == "" && strings.EqualFold(book.Title, a.Title) {
+			if review := book.Reviews[userID]; review != "" {
+				return review
+			}
+		}
+	}
+	return ""
+}
+
+// SyncHistoryResponse lists the caller's recent Hardcover syncs
+type SyncHistoryResponse struct {
+	Attempts  []SyncAttempt `json:"attempts"`  // newest first
+	Retryable int           `json:"retryable"` // failed attempts not yet retried, or skipped by a retry
+}
+
+// getSyncHistoryHandler returns the caller's recent rating and review syncs, so a
+// member can see why a book didn't show up on Hardcover
+func getSyncHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := principalFromContext(ctx).UID
+
+	attempts, err := getSyncHistory(ctx, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load Hardcover sync history", "uid", userID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to load sync history", nil)
+		return
+	}
+
+	response := SyncHistoryResponse{Attempts: attempts}
+	for i := range attempts {
+		if attempts[i].retryable() {
+			attempts[i].Retryable = true
+			response.Retryable++
+		}
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// RetrySyncRequest selects failed sync attempts to retry
+type RetrySyncRequest struct {
+	IDs             []string `json:"ids,omitempty"`             // defaults to every failed attempt not yet retried
+	HardcoverBookID int      `json:"hardcoverBookId,omitempty"` // the book to use, for a single attempt that was ambiguous
+}
+
+func (req *RetrySyncRequest) validate(v *validator) {
+	if len(req.IDs) > maxSyncRetryBatch {
+		v.add("ids", fmt.Sprintf("must have at most %d entries", maxSyncRetryBatch))
+	}
+	for i, id := range req.IDs {
+		v.required(fmt.Sprintf("ids[%d]", i), id)
+		v.maxLength(fmt.Sprintf("ids[%d]", i), id, maxIDLength)
+	}
+	if req.HardcoverBookID < 0 {
+		v.add("hardcoverBookId", "must be a positive number")
+	}
+	if req.HardcoverBookID > 0 && len(req.IDs) != 1 {
+		v.add("hardcoverBookId", "requires exactly one id")
+	}
+}
+
+// RetrySkip is a selected attempt a retry didn't re-run, and why
+type RetrySkip struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"` // "rating_only", "nothing_to_sync" (no rating, and the club review was removed) or "review_too_long"
+}
+
+// RetrySyncResponse reports the new attempts made by a retry
+type RetrySyncResponse struct {
+	Retried   int           `json:"retried"`
+	Synced    int           `json:"synced"`
+	Failed    int           `json:"failed"`
+	Skipped   int           `json:"skipped"`
+	Remaining int           `json:"remaining"` // retryable attempts left for another request
+	Attempts  []SyncAttempt `json:"attempts"`
+	Skips     []RetrySkip   `json:"skips,omitempty"`
+}
+
+// retrySyncHandler re-runs failed sync attempts with the rating they were made with
+// and the user's current club review, at most maxSyncRetryBatch at a time. Each retry
+// is recorded as a new attempt linked to the one it retried.
+func retrySyncHandler(w http.ResponseWriter, r *http.Request) {
+	var req RetrySyncRequest
+	if !decodeJSON(w, r, &req) || !validateRequest(w, r, &req) {
+		return
+	}
+
+	ctx := r.Context()
+	userID := principalFromContext(ctx).UID
+
+	history, err := getSyncHistory(ctx, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load Hardcover sync history", "uid", userID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to load sync history", nil)
+		return
+	}
+	byID := map[string]SyncAttempt{}
+	for _, attempt := range history {
+		byID[attempt.ID] = attempt
+	}
+
+	// By default the oldest retryable attempts are retried; history is newest first
+	var retries []SyncAttempt
+	remaining := 0
+	if len(req.IDs) == 0 {
+		for i := len(history) - 1; i >= 0; i-- {
+			if !history[i].retryable() {
+				continue
+			}
+			if len(retries) == maxSyncRetryBatch {
+				remaining++
+				continue
+			}
+			retries = append(retries, history[i])
+		}
+	}
+	for _, id := range req.IDs {
+		attempt, ok := byID[id]
+		if !ok {
+			writeError(w, r, http.StatusNotFound, errCodeNotFound, fmt.Sprintf("Sync attempt %s not found", id), nil)
+			return
+		}
+		if !attempt.canRetry() {
+			writeError(w, r, http.StatusConflict, errCodeSyncNotRetryable,
+				fmt.Sprintf("Sync attempt %s did not fail, was already retried or failed in a way a retry can't fix", id), nil)
+			return
+		}
+		retries = append(retries, attempt)
+	}
+
+	response := RetrySyncResponse{Remaining: remaining, Attempts: []SyncAttempt{}}
+	if len(retries) == 0 {
+		writeJSON(w, http.StatusOK, response)
+		return
+	}
+
+	token, err := getHardcoverToken(ctx, userID)
+	if errors.Is(err, errHardcoverNotLinked) {
+		writeError(w, r, http.StatusBadRequest, errCodeHardcoverNotLinked, "No Hardcover account linked", nil)
+		return
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Failed to load Hardcover token", "uid", userID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to load Hardcover account", nil)
+		return
+	}
+
+	// Settings are loaded now rather than when the sync was first made, so a retry
+	// honours any privacy changes since. Reviews are read from the club's history, so a
+	// review edited or removed since is synced as it is now.
+	settingsByClub := map[string]HardcoverSyncSettings{}
+	booksByClub := map[string][]ClubBookRead{}
+	for _, original := range retries {
+		if _, ok := settingsByClub[original.ClubID]; !ok {
+			settings, err := loadSyncSettings(ctx, userID, original.ClubID)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to load Hardcover sync settings", "uid", userID, "clubId", original.ClubID, "error", err)
+				writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to load Hardcover sync settings", nil)
+				return
+			}
+			settingsByClub[original.ClubID] = settings
+		}
+		if _, ok := booksByClub[original.ClubID]; original.Review && !settingsByClub[original.ClubID].RatingOnly && !ok {
+			books, err := getClubBooksRead(ctx, original.ClubID)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to load club history", "clubId", original.ClubID, "error", err)
+				writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to load club history", nil)
+				return
+			}
+			booksByClub[original.ClubID] = books
+		}
+	}
+
+	// Retry oldest first, so the history reads in the order the syncs were made
+	historyRef := firebaseDB.NewRef(syncHistoryPath(userID))
+	for i := len(retries) - 1; i >= 0; i-- {
+		if ctx.Err() != nil {
+			slog.WarnContext(ctx, "Stopped Hardcover sync retries", "uid", userID, "retried", response.Retried, "error", ctx.Err())
+			break
+		}
+		original := retries[i]
+		settings := settingsByClub[original.ClubID]
+		review := ""
+		if original.Review && !settings.RatingOnly {
+			review = original.clubReview(booksByClub[original.ClubID], userID)
+		}
+		// Skip for the same reasons a club ratings sync would
+		reason := ""
+		switch {
+		case settings.RatingOnly && original.Rating == 0:
+			reason = "rating_only"
+		case original.Rating == 0 && review == "":
+			reason = "nothing_to_sync"
+		case utf8.RuneCountInString(review) > maxReviewTextLength:
+			reason = "review_too_long"
+		}
+		if reason != "" {
+			response.Skipped++
+			response.Skips = append(response.Skips, RetrySkip{ID: original.ID, Reason: reason})
+			if err := updateSyncAttempt(ctx, historyRef.Child(original.ID), map[string]interface{}{"retrySkipped": reason}); err != nil {
+				slog.WarnContext(ctx, "Failed to record skipped Hardcover sync retry", "uid", userID, "attemptId", original.ID, "error", err)
+			}
+			continue
+		}
+		book := original.ref()
+		if req.HardcoverBookID > 0 {
+			book.HardcoverBookID = req.HardcoverBookID
+		}
+		attempt, err := syncAndRecord(ctx, userID, token, SyncAttempt{
+			Source:  original.Source,
+			ClubID:  original.ClubID,
+			Rating:  original.Rating,
+			RetryOf: original.ID,
+		}, book, review, settings)
+		if err != nil {
+			slog.WarnContext(ctx, "Hardcover sync retry failed", "uid", userID, "attemptId", original.ID, "isbn", original.ISBN, "error", err)
+			response.Failed++
+		} else {
+			response.Synced++
+		}
+		response.Retried++
+		response.Attempts = append(response.Attempts, attempt)
+
+		if attempt.ID != "" {
+			if err := updateSyncAttempt(ctx, historyRef.Child(original.ID), map[string]interface{}{"retriedBy": attempt.ID, "retrySkipped": nil}); err != nil {
+				slog.WarnContext(ctx, "Failed to link Hardcover sync retry", "uid", userID, "attemptId", original.ID, "error", err)
+			}
+		}
+	}
+
+	slog.InfoContext(ctx, "Retried Hardcover syncs", "uid", userID, "retried", response.Retried, "synced", response.Synced,
+		"failed", response.Failed, "skipped", response.Skipped, "remaining", response.Remaining)
+	writeJSON(w, http.StatusOK, response)
+}
+
+// updateSyncAttempt sets fields on the attempt at ref, deleting those set to nil,
+// unless it has been pruned
+func updateSyncAttempt(ctx context.Context, ref *db.Ref, fields map[string]interface{}) error {
+	return firebaseTransaction(ctx, "users", ref, func(node db.TransactionNode) (interface{}, error) {
+		var attempt map[string]interface{}
+		if err := node.Unmarshal(&attempt); err != nil {
+			return nil, err
+		}
+		if attempt == nil {
+			return nil, nil
+		}
+		for field, value := range fields {
+			if value == nil {
+				delete(attempt, field)
+			} else {
+				attempt[field] = value
+			}
+		}
+		return attempt, nil
+	})
+}
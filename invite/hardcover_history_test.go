@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestRetrySyncRecordsSkips(t *testing.T) {
+	useKeyring(t, "k1:"+testKey('a'))
+	if err := storeHardcoverToken(context.Background(), "retrier", "hc_token"); err != nil {
+		t.Fatalf("storeHardcoverToken() error = %v", err)
+	}
+	// A review sync whose review has since been removed from the club's history, so a
+	// retry has nothing to send and never calls Hardcover
+	testDB.set(t, "clubs/retryclub/booksRead", `[{"title": "Emma"}]`)
+	testDB.set(t, "users/retrier/hardcoverSyncHistory/-a1", `{"at": 1, "source": "review", "clubId": "retryclub",
+		"title": "Emma", "review": true, "outcome": "failed", "error": "hardcover_unavailable"}`)
+	caller := testPrincipal("retrier", true)
+
+	retry := func(body string) RetrySyncResponse {
+		t.Helper()
+		w := serveClubRequest(retrySyncHandler, http.MethodPost, body, caller, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("retry %s status = %d (body: %s)", body, w.Code, w.Body)
+		}
+		var response RetrySyncResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return response
+	}
+	retryable := func() int {
+		t.Helper()
+		w := serveClubRequest(getSyncHistoryHandler, http.MethodGet, "", caller, nil)
+		var response SyncHistoryResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to decode history %q: %v", w.Body.String(), err)
+		}
+		return response.Retryable
+	}
+
+	if n := retryable(); n != 1 {
+		t.Fatalf("retryable = %d before retrying, want 1", n)
+	}
+	response := retry(`{}`)
+	if response.Skipped != 1 || response.Retried != 0 || len(response.Skips) != 1 || response.Skips[0].Reason != "nothing_to_sync" {
+		t.Fatalf("retry = %+v, want one nothing_to_sync skip", response)
+	}
+	if skipped := testDB.get("users/retrier/hardcoverSyncHistory/-a1/retrySkipped"); skipped != "nothing_to_sync" {
+		t.Errorf("retrySkipped = %v, want nothing_to_sync", skipped)
+	}
+
+	// The skipped attempt is no longer counted or retried by default
+	if n := retryable(); n != 0 {
+		t.Errorf("retryable = %d after the skip, want 0", n)
+	}
+	if response := retry(`{}`); response.Skipped != 0 || response.Retried != 0 {
+		t.Errorf("default retry = %+v, want nothing retried", response)
+	}
+
+	// but can still be retried by ID, once the user has fixed the cause
+	if response := retry(`{"ids": ["-a1"]}`); response.Skipped != 1 {
+		t.Errorf("retry by ID = %+v, want the attempt skipped again", response)
+	}
+}
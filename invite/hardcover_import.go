@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"unicode/utf8"
+
+	"firebase.google.com/go/v4/db"
+	"github.com/dhvogel/bookclurb-invite/hardcover"
+	"github.com/dhvogel/bookclurb-invite/isbn"
+)
+
+// ClubBookRead is a finished book in clubs/{clubId}/booksRead. Ratings and reviews
+// are keyed by user ID.
+type ClubBookRead struct {
+	Title       string             `json:"title"`
+	Author      string             `json:"author"`
+	ISBN        string             `json:"isbn"`
+	CompletedAt string             `json:"completedAt"`
+	Ratings     map[string]float64 `json:"ratings"`
+	Reviews     map[string]string  `json:"reviews"`
+}
+
+// hardcoverStatusNames are the reading statuses reported to clients
+var hardcoverStatusNames = map[int]string{
+	hardcover.StatusWantToRead:       "want_to_read",
+	hardcover.StatusCurrentlyReading: "currently_reading",
+	hardcover.StatusRead:             "read",
+	hardcover.StatusPaused:           "paused",
+	hardcover.StatusDidNotFinish:     "did_not_finish",
+}
+
+// HardcoverImportItem is a book from the club's history that is on the user's
+// Hardcover shelf, with what Hardcover and the club each have for the user
+type HardcoverImportItem struct {
+	ISBN            string  `json:"isbn"`
+	Title           string  `json:"title"`
+	HardcoverBookID int     `json:"hardcoverBookId"`
+	Status          string  `json:"status"`
+	Rating          float64 `json:"rating,omitempty"` // Hardcover rating, rounded to whole stars
+	Review          string  `json:"review,omitempty"`
+	StartedAt       string  `json:"startedAt,omitempty"`
+	FinishedAt      string  `json:"finishedAt,omitempty"`
+	ClubRating      float64 `json:"clubRating,omitempty"`
+	ClubReview      string  `json:"clubReview,omitempty"`
+	CanImportRating bool    `json:"canImportRating"` // Hardcover has a rating and the club has none
+	CanImportReview bool    `json:"canImportReview"` // Hardcover has a review and the club has none
+}
+
+// HardcoverImportResponse lists the club books that can be back-filled from Hardcover
+type HardcoverImportResponse struct {
+	ClubID   string                `json:"clubId"`
+	Matched  int                   `json:"matched"`
+	Imported int                   `json:"imported"`
+	Items    []HardcoverImportItem `json:"items"`
+}
+
+// ImportFromHardcoverRequest selects which matched books to back-fill
+type ImportFromHardcoverRequest struct {
+	ISBNs     []string `json:"isbns,omitempty"` // defaults to every matched book
+	Overwrite bool     `json:"overwrite,omitempty"`
+}
+
+func (req *ImportFromHardcoverRequest) validate(v *validator) {
+	for i, bookISBN := range req.ISBNs {
+		v.isbn(fmt.Sprintf("isbns[%d]", i), bookISBN)
+	}
+}
+
+// clubStars converts a Hardcover rating (half stars) to the club's whole 1-5 stars
+func clubStars(rating float64) float64 {
+	return math.Min(math.Max(math.Round(rating), 1), 5)
+}
+
+// getClubBooksRead loads the club's reading history
+func getClubBooksRead(ctx context.Context, clubID string) ([]ClubBookRead, error) {
+	var books []ClubBookRead
+	if err := firebaseGet(ctx, "clubs", firebaseDB.NewRef(fmt.Sprintf("clubs/%s/booksRead", clubID)), &books); err != nil {
+		return nil, fmt.Errorf("failed to load books read: %v", err)
+	}
+	return books, nil
+}
+
+// hardcoverImportItems matches the club's books against the user's Hardcover shelf by
+// ISBN, in club history order
+func hardcoverImportItems(ctx context.Context, token, userID string, books []ClubBookRead) ([]HardcoverImportItem, error) {
+	var isbns []string
+	seen := map[string]bool{}
+	for _, book := range books {
+		bookISBN := normalizeISBN(book.ISBN)
+		if isbn.Valid(bookISBN) && !seen[bookISBN] {
+			seen[bookISBN] = true
+			isbns = append(isbns, bookISBN)
+		}
+	}
+
+	bookIDs, err := hardcoverClient.BookIDsByISBN(ctx, token, isbns)
+	if err != nil {
+		return nil, fmt.Errorf("book lookup failed: %w", err)
+	}
+	var ids []int
+	for _, id := range bookIDs {
+		ids = append(ids, id)
+	}
+	userBooks, err := hardcoverClient.UserBooks(ctx, token, ids)
+	if err != nil {
+		return nil, err
+	}
+	shelf := map[int]hardcover.UserBook{}
+	for _, userBook := range userBooks {
+		shelf[userBook.BookID] = userBook
+	}
+
+	items := []HardcoverImportItem{}
+	for _, book := range books {
+		bookISBN := normalizeISBN(book.ISBN)
+		userBook, ok := shelf[bookIDs[bookISBN]]
+		if !ok || !seen[bookISBN] {
+			continue
+		}
+		seen[bookISBN] = false // list each ISBN once
+
+		item := HardcoverImportItem{
+			ISBN:            bookISBN,
+			Title:           book.Title,
+			HardcoverBookID: userBook.BookID,
+			Status:          hardcoverStatusNames[userBook.StatusID],
+			Review:          userBook.Review,
+			ClubRating:      book.Ratings[userID],
+			ClubReview:      book.Reviews[userID],
+		}
+		if userBook.Rating != nil && *userBook.Rating > 0 {
+			item.Rating = clubStars(*userBook.Rating)
+		}
+		// Prefer the latest finished read-through's dates
+		for _, read := range userBook.Reads {
+			if item.StartedAt == "" || read.FinishedAt != "" {
+				item.StartedAt, item.FinishedAt = read.StartedAt, read.FinishedAt
+			}
+			if read.FinishedAt != "" {
+				break
+			}
+		}
+		item.CanImportRating = item.Rating > 0 && item.ClubRating == 0
+		item.CanImportReview = item.Review != "" && item.ClubReview == ""
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// loadHardcoverImport authorizes the caller as a club member and returns their matched
+// Hardcover books, writing an error response and returning false on failure
+func loadHardcoverImport(w http.ResponseWriter, r *http.Request, clubID string) ([]HardcoverImportItem, bool) {
+	ctx := r.Context()
+	userID := principalFromContext(ctx).UID
+	if !authorize(w, r, requireClubRole(clubID, roleMember)) {
+		return nil, false
+	}
+
+	token, err := getHardcoverToken(ctx, userID)
+	if errors.Is(err, errHardcoverNotLinked) {
+		writeError(w, r, http.StatusBadRequest, errCodeHardcoverNotLinked, "No Hardcover account linked", nil)
+		return nil, false
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Failed to load Hardcover token", "uid", userID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to load Hardcover account", nil)
+		return nil, false
+	}
+
+	books, err := getClubBooksRead(ctx, clubID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load club history", "clubId", clubID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to load club history", nil)
+		return nil, false
+	}
+
+	items, err := hardcoverImportItems(ctx, token, userID, books)
+	if err != nil {
+		slog.WarnContext(ctx, "Hardcover library lookup failed", "uid", userID, "clubId", clubID, "error", err)
+		writeHardcoverError(w, r, err)
+		return nil, false
+	}
+	return items, true
+}
+
+// previewHardcoverImportHandler lists the club's books that are on the caller's
+// Hardcover shelf and which ratings and reviews could be back-filled
+func previewHardcoverImportHandler(w http.ResponseWriter, r *http.Request) {
+	clubID := r.PathValue("clubId")
+	items, ok := loadHardcoverImport(w, r, clubID)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, HardcoverImportResponse{ClubID: clubID, Matched: len(items), Items: items})
+}
+
+// importFromHardcoverHandler back-fills the caller's club ratings and reviews from their
+// Hardcover shelf. Existing club ratings and reviews are kept unless overwrite is set.
+func importFromHardcoverHandler(w http.ResponseWriter, r *http.Request) {
+	var req ImportFromHardcoverRequest
+	if !decodeJSON(w, r, &req) || !validateRequest(w, r, &req) {
+		return
+	}
+
+	ctx := r.Context()
+	clubID := r.PathValue("clubId")
+	userID := principalFromContext(ctx).UID
+	items, ok := loadHardcoverImport(w, r, clubID)
+	if !ok {
+		return
+	}
+
+	wanted := map[string]bool{}
+	for _, bookISBN := range req.ISBNs {
+		wanted[normalizeISBN(bookISBN)] = true
+	}
+	imports := map[string]hardcoverImport{}
+	for _, item := range items {
+		if len(wanted) > 0 && !wanted[item.ISBN] {
+			continue
+		}
+		var imp hardcoverImport
+		if item.Rating > 0 && (item.CanImportRating || req.Overwrite) {
+			imp.rating = item.Rating
+		}
+		if item.Review != "" && (item.CanImportReview || req.Overwrite) && utf8.RuneCountInString(item.Review) <= maxReviewTextLength {
+			imp.review = item.Review
+		}
+		if imp != (hardcoverImport{}) {
+			imports[item.ISBN] = imp
+		}
+	}
+
+	imported := 0
+	booksRef := firebaseDB.NewRef(fmt.Sprintf("clubs/%s/booksRead", clubID))
+	err := firebaseTransaction(ctx, "clubs", booksRef, func(node db.TransactionNode) (interface{}, error) {
+		var books []map[string]interface{}
+		if err := node.Unmarshal(&books); err != nil {
+			return nil, err
+		}
+		imported = 0
+		for _, book := range books {
+			bookISBN, _ := book["isbn"].(string)
+			imp, ok := imports[normalizeISBN(bookISBN)]
+			if !ok {
+				continue
+			}
+			changed := false
+			if imp.rating > 0 {
+				changed = setUserEntry(book, "ratings", userID, imp.rating) || changed
+			}
+			if imp.review != "" {
+				changed = setUserEntry(book, "reviews", userID, imp.review) || changed
+			}
+			if changed {
+				imported++
+			}
+		}
+		return books, nil
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to import Hardcover ratings", "uid", userID, "clubId", clubID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to save imported ratings", nil)
+		return
+	}
+
+	// Report the club's values as they are now
+	for i := range items {
+		item := &items[i]
+		imp := imports[item.ISBN]
+		if imp.rating > 0 {
+			item.ClubRating = imp.rating
+		}
+		if imp.review != "" {
+			item.ClubReview = imp.review
+		}
+		item.CanImportRating = item.Rating > 0 && item.ClubRating == 0
+		item.CanImportReview = item.Review != "" && item.ClubReview == ""
+	}
+
+	slog.InfoContext(ctx, "Imported ratings from Hardcover", "uid", userID, "clubId", clubID, "imported", imported)
+	writeJSON(w, http.StatusOK, HardcoverImportResponse{ClubID: clubID, Matched: len(items), Imported: imported, Items: items})
+}
+
+// hardcoverImport is the rating and review to copy onto one club book
+type hardcoverImport struct {
+	rating float64
+	review string
+}
+
+// setUserEntry sets book[field][userID] = value, reporting whether it changed. value
+// is a rating or review; an entry of any other type (such as an object written by an
+// older client) is replaced without comparing, since comparing maps would panic.
+func setUserEntry(book map[string]interface{}, field, userID string, value interface{}) bool {
+	entries, _ := book[field].(map[string]interface{})
+	if entries == nil {
+		entries = map[string]interface{}{}
+		book[field] = entries
+	}
+	switch current := entries[userID].(type) {
+	case float64, string:
+		if current == value {
+			return false
+		}
+	}
+	entries[userID] = value
+	return true
+}
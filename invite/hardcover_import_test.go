@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestSetUserEntry(t *testing.T) {
+	tests := []struct {
+		name        string
+		book        map[string]interface{}
+		value       interface{}
+		wantChanged bool
+	}{
+		{name: "no entries", book: map[string]interface{}{}, value: 4.0, wantChanged: true},
+		{name: "same rating", book: map[string]interface{}{"ratings": map[string]interface{}{"alice": 4.0}}, value: 4.0},
+		{name: "different rating", book: map[string]interface{}{"ratings": map[string]interface{}{"alice": 3.0}}, value: 4.0, wantChanged: true},
+		{name: "other member's rating", book: map[string]interface{}{"ratings": map[string]interface{}{"bob": 4.0}}, value: 4.0, wantChanged: true},
+		{name: "same review", book: map[string]interface{}{"ratings": map[string]interface{}{"alice": "Great"}}, value: "Great"},
+		{name: "stored object", book: map[string]interface{}{"ratings": map[string]interface{}{"alice": map[string]interface{}{"text": "Great"}}},
+			value: 4.0, wantChanged: true},
+		{name: "stored list", book: map[string]interface{}{"ratings": map[string]interface{}{"alice": []interface{}{"Great"}}},
+			value: "Great", wantChanged: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if changed := setUserEntry(tt.book, "ratings", "alice", tt.value); changed != tt.wantChanged {
+				t.Errorf("setUserEntry() = %v, want %v", changed, tt.wantChanged)
+			}
+			if got := tt.book["ratings"].(map[string]interface{})["alice"]; got != tt.value {
+				t.Errorf("entry = %v, want %v", got, tt.value)
+			}
+		})
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"firebase.google.com/go/v4/db"
+	"github.com/dhvogel/bookclurb-invite/hardcover"
+	"github.com/dhvogel/bookclurb-invite/isbn"
+)
+
+// clubRatingsSyncConcurrency is how many members are synced at once. Each member's
+// books are synced one at a time, since Hardcover rate limits each token.
+const clubRatingsSyncConcurrency = 4
+
+// SyncClubRatingsRequest represents the request to sync a club's past ratings and
+// reviews to Hardcover
+type SyncClubRatingsRequest struct {
+	AllMembers bool `json:"allMembers,omitempty"` // sync every linked member (admins only) instead of just the caller
+}
+
+// RatingSyncResult reports the outcome of syncing one member's rating of one book
+type RatingSyncResult struct {
+	UserID  string `json:"userId"`
+	Name    string `json:"name"`
+	ISBN    string `json:"isbn,omitempty"`
+	Title   string `json:"title"`
+	Outcome string `json:"outcome"`          // "synced", "skipped" or "failed"
+	Action  string `json:"action,omitempty"` // "created" or "updated" when synced
+	Reason  string `json:"reason,omitempty"` // why the rating was skipped, or the error code if failed
+}
+
+// ClubRatingsSyncReport is the report of a club ratings sync job
+type ClubRatingsSyncReport struct {
+	ClubID  string             `json:"clubId"`
+	Members int                `json:"members"` // members whose ratings were synced
+	Synced  int                `json:"synced"`
+	Skipped int                `json:"skipped"`
+	Failed  int                `json:"failed"`
+	Results []RatingSyncResult `json:"results"`
+}
+
+// memberRating is a rating and/or review a member left on a club book
+type memberRating struct {
+	book   ClubBookRead
+	rating float64
+	review string
+}
+
+// clubRatingsSyncTimeout bounds a club ratings sync job. At Hardcover's 60 requests a
+// minute per token, a member with a long history takes several minutes.
+const clubRatingsSyncTimeout = time.Hour
+
+// maxClubSyncJobs is how many ratings sync jobs are kept for each club; older ones are pruned
+const maxClubSyncJobs = 20
+
+// ClubRatingsSyncJob is a club ratings sync running in the background, stored under
+// hardcoverSyncJobs/{clubId}/{jobId}. IDs sort oldest first.
+type ClubRatingsSyncJob struct {
+	ID          string                 `json:"id,omitempty"` // the record's key; not stored in it
+	ClubID      string                 `json:"clubId"`
+	Status      string                 `json:"status"` // "running", "completed" or "failed"
+	StartedBy   string                 `json:"startedBy"`
+	AllMembers  bool                   `json:"allMembers,omitempty"`
+	StartedAt   int64                  `json:"startedAt"` // Unix seconds
+	FinishedAt  int64                  `json:"finishedAt,omitempty"`
+	Members     int                    `json:"members"`          // members with ratings to sync
+	MembersDone int                    `json:"membersDone"`      // members synced so far
+	Error       string                 `json:"error,omitempty"`  // why a failed job stopped: "timeout" or "interrupted"
+	Report      *ClubRatingsSyncReport `json:"report,omitempty"` // set when the job finishes, partial if it failed
+}
+
+// syncJobsPath returns the path holding the club's ratings sync jobs
+func syncJobsPath(clubID string) string {
+	return fmt.Sprintf("hardcoverSyncJobs/%s", clubID)
+}
+
+// syncClubRatingsHandler starts a background job syncing every rating and review in the
+// club's reading history to the Hardcover accounts of the members who left them, and
+// returns the job to poll. Members sync their own; verified admins can sync every
+// linked member, e.g. after members link Hardcover late. Reviews are published only
+// by the member who wrote them, so an admin syncs other members' ratings alone.
+func syncClubRatingsHandler(w http.ResponseWriter, r *http.Request) {
+	var req SyncClubRatingsRequest
+	if !decodeOptionalJSON(w, r, &req) {
+		return
+	}
+
+	ctx := r.Context()
+	clubID := r.PathValue("clubId")
+	userID := principalFromContext(ctx).UID
+	policies := []Policy{requireClubRole(clubID, roleMember)}
+	if req.AllMembers {
+		policies = []Policy{requireClubRole(clubID, roleAdmin), requireVerifiedEmail()}
+	}
+	if !authorize(w, r, policies...) {
+		return
+	}
+
+	club, err := getClub(ctx, clubID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load club", "clubId", clubID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to load club", nil)
+		return
+	}
+	books, err := getClubBooksRead(ctx, clubID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load club history", "clubId", clubID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to load club history", nil)
+		return
+	}
+
+	var members []Member
+	var ratings [][]memberRating
+	for _, member := range club.Members {
+		if member.ID == "" || !(req.AllMembers || member.ID == userID) {
+			continue
+		}
+		if memberRatings := memberRatings(books, member.ID, member.ID == userID); len(memberRatings) > 0 {
+			members = append(members, member)
+			ratings = append(ratings, memberRatings)
+		}
+	}
+
+	job := ClubRatingsSyncJob{
+		ClubID:     clubID,
+		Status:     syncJobRunning,
+		StartedBy:  userID,
+		AllMembers: req.AllMembers,
+		StartedAt:  time.Now().Unix(),
+		Members:    len(members),
+	}
+	jobsRef := firebaseDB.NewRef(syncJobsPath(clubID))
+	ref, err := firebasePush(ctx, "hardcover_sync_jobs", jobsRef, job)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to create club ratings sync job", "clubId", clubID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to start sync", nil)
+		return
+	}
+	job.ID = ref.Key
+	if err := trimToNewest(ctx, "hardcover_sync_jobs", jobsRef, maxClubSyncJobs); err != nil {
+		slog.WarnContext(ctx, "Failed to prune club ratings sync jobs", "clubId", clubID, "error", err)
+	}
+
+	backgroundJobs.start(ctx, clubRatingsSyncTimeout, func(ctx context.Context) {
+		runClubRatingsSync(ctx, ref, job, members, ratings)
+	})
+
+	slog.InfoContext(ctx, "Started club ratings sync", "clubId", clubID, "jobId", job.ID, "uid", userID,
+		"allMembers", req.AllMembers, "members", job.Members)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// runClubRatingsSync syncs each member's ratings, recording progress on the job at ref
+// as members finish, then stores the report. If ctx ends first the job fails with the
+// results so far.
+func runClubRatingsSync(ctx context.Context, ref *db.Ref, job ClubRatingsSyncJob, members []Member, ratings [][]memberRating) {
+	// Each member's results go in their own slot so the report keeps club order
+	memberResults := make([][]RatingSyncResult, len(members))
+	sem := make(chan struct{}, clubRatingsSyncConcurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+	for i, member := range members {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+			if ctx.Err() != nil {
+				return
+			}
+			memberResults[i] = syncMemberRatings(ctx, job.ClubID, member, ratings[i])
+
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			if err := firebaseUpdate(ctx, "hardcover_sync_jobs", ref, map[string]interface{}{"membersDone": done}); err != nil {
+				slog.WarnContext(ctx, "Failed to record club ratings sync progress", "clubId", job.ClubID, "jobId", job.ID, "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	report := ClubRatingsSyncReport{ClubID: job.ClubID, Results: []RatingSyncResult{}}
+	for _, results := range memberResults {
+		if len(results) > 0 {
+			report.Members++
+		}
+		for _, result := range results {
+			switch result.Outcome {
+			case memberSyncSynced:
+				report.Synced++
+			case memberSyncSkipped:
+				report.Skipped++
+			case memberSyncFailed:
+				report.Failed++
+			}
+		}
+		report.Results = append(report.Results, results...)
+	}
+
+	job.Status, job.Error = jobOutcome(ctx)
+	job.MembersDone, job.FinishedAt, job.Report = done, time.Now().Unix(), &report
+	updates := map[string]interface{}{
+		"status":      job.Status,
+		"membersDone": job.MembersDone,
+		"finishedAt":  job.FinishedAt,
+		"report":      job.Report,
+	}
+	if job.Error != "" {
+		updates["error"] = job.Error
+	}
+	if err := saveJobResult(ctx, ref, updates); err != nil {
+		slog.ErrorContext(ctx, "Failed to save club ratings sync report", "clubId", job.ClubID, "jobId", job.ID, "error", err)
+	}
+
+	slog.InfoContext(ctx, "Synced club ratings to Hardcover", "clubId", job.ClubID, "jobId", job.ID, "uid", job.StartedBy,
+		"allMembers", job.AllMembers, "status", job.Status, "members", report.Members,
+		"synced", report.Synced, "skipped", report.Skipped, "failed", report.Failed)
+}
+
+// getClubRatingsSyncJobHandler returns a club ratings sync job and, once it finishes,
+// its report. Members can see the jobs they started; admins can see every job.
+func getClubRatingsSyncJobHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	clubID, jobID := r.PathValue("clubId"), r.PathValue("jobId")
+	userID := principalFromContext(ctx).UID
+	if !authorize(w, r, requireClubRole(clubID, roleMember)) {
+		return
+	}
+
+	var job *ClubRatingsSyncJob
+	if err := firebaseGet(ctx, "hardcover_sync_jobs", firebaseDB.NewRef(syncJobsPath(clubID)).Child(jobID), &job); err != nil {
+		slog.ErrorContext(ctx, "Failed to load club ratings sync job", "clubId", clubID, "jobId", jobID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to load sync job", nil)
+		return
+	}
+	if job == nil {
+		writeError(w, r, http.StatusNotFound, errCodeNotFound, "Sync job not found", nil)
+		return
+	}
+	if job.StartedBy != userID && !authorize(w, r, requireClubRole(clubID, roleAdmin)) {
+		return
+	}
+	job.ID = jobID
+	writeJSON(w, http.StatusOK, job)
+}
+
+// memberRatings returns the ratings userID left in the club's history, with their
+// reviews if includeReviews is set
+func memberRatings(books []ClubBookRead, userID string, includeReviews bool) []memberRating {
+	var ratings []memberRating
+	for _, book := range books {
+		rating, review := book.Ratings[userID], book.Reviews[userID]
+		if !includeReviews {
+			review = ""
+		}
+		if rating > 0 || review != "" {
+			ratings = append(ratings, memberRating{book: book, rating: rating, review: review})
+		}
+	}
+	return ratings
+}
+
+// syncMemberRatings syncs one member's ratings in order, recording each attempt in the
+// member's sync history. Once Hardcover rejects the member's token the remaining
+// ratings fail without further calls.
+func syncMemberRatings(ctx context.Context, clubID string, member Member, ratings []memberRating) []RatingSyncResult {
+	results := make([]RatingSyncResult, 0, len(ratings))
+	token, err := getHardcoverToken(ctx, member.ID)
+	if err != nil && !errors.Is(err, errHardcoverNotLinked) {
+		err = fmt.Errorf("%w: %v", errHardcoverTokenUnavailable, err)
+	}
+	var settings HardcoverSyncSettings
+	if err == nil {
+		if settings, err = loadSyncSettings(ctx, member.ID, clubID); err != nil {
+			err = fmt.Errorf("%w: %v", errSyncSettingsUnavailable, err)
+		}
+	}
+
+	for _, item := range ratings {
+		ref := bookRef{ISBN: normalizeISBN(item.book.ISBN), Title: item.book.Title, Author: item.book.Author}
+		if !isbn.Valid(ref.ISBN) {
+			ref.ISBN = ""
+		}
+		result := RatingSyncResult{UserID: member.ID, Name: member.Name, ISBN: ref.ISBN, Title: item.book.Title}
+
+		switch {
+		case errors.Is(err, errHardcoverNotLinked):
+			result.Outcome, result.Reason = memberSyncSkipped, "not_linked"
+		case err != nil:
+			result.Outcome, result.Reason = memberSyncFailed, hardcoverErrorCode(err)
+		case ref.ISBN == "" && ref.Title == "":
+			result.Outcome, result.Reason = memberSyncSkipped, "no_isbn_or_title"
+		case settings.RatingOnly && item.rating == 0:
+			result.Outcome, result.Reason = memberSyncSkipped, "rating_only"
+		case !settings.RatingOnly && utf8.RuneCountInString(item.review) > maxReviewTextLength:
+			result.Outcome, result.Reason = memberSyncSkipped, "review_too_long"
+		default:
+			attempt, syncErr := syncAndRecord(ctx, member.ID, token, SyncAttempt{
+				Source: syncSourceClubRatings,
+				ClubID: clubID,
+				Rating: item.rating,
+			}, ref, item.review, settings)
+			if syncErr != nil {
+				slog.WarnContext(ctx, "Hardcover rating sync failed", "uid", member.ID, "isbn", ref.ISBN, "title", ref.Title, "error", syncErr)
+				result.Outcome, result.Reason = memberSyncFailed, hardcoverErrorCode(syncErr)
+				if errors.Is(syncErr, hardcover.ErrUnauthorized) {
+					err = syncErr
+				}
+				break
+			}
+			result.Outcome, result.Action = memberSyncSynced, attempt.Action
+		}
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMemberRatings(t *testing.T) {
+	books := []ClubBookRead{
+		{Title: "Dune", Ratings: map[string]float64{"alice": 5}, Reviews: map[string]string{"alice": "Loved it"}},
+		{Title: "Emma", Reviews: map[string]string{"alice": "Not for me"}},
+		{Title: "Middlemarch", Ratings: map[string]float64{"alice": 3}},
+		{Title: "Beloved", Ratings: map[string]float64{"bob": 4}},
+	}
+
+	type item struct {
+		Title  string
+		Rating float64
+		Review string
+	}
+	summarize := func(ratings []memberRating) []item {
+		var items []item
+		for _, r := range ratings {
+			items = append(items, item{r.book.Title, r.rating, r.review})
+		}
+		return items
+	}
+
+	tests := []struct {
+		name           string
+		includeReviews bool
+		want           []item
+	}{
+		{
+			name: "own ratings and reviews", includeReviews: true,
+			want: []item{{"Dune", 5, "Loved it"}, {"Emma", 0, "Not for me"}, {"Middlemarch", 3, ""}},
+		},
+		{
+			// An admin syncing another member leaves their reviews, and books they only
+			// reviewed, for them to sync
+			name: "another member's ratings", includeReviews: false,
+			want: []item{{"Dune", 5, ""}, {"Middlemarch", 3, ""}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summarize(memberRatings(books, "alice", tt.includeReviews)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("memberRatings() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
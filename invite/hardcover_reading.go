@@ -0,0 +1,493 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"firebase.google.com/go/v4/db"
+	"github.com/dhvogel/bookclurb-invite/hardcover"
+	"github.com/dhvogel/bookclurb-invite/isbn"
+)
+
+// pagesPerChapter estimates the pages in a chapter for schedules kept in chapters,
+// matching the web app's progress tracker
+const pagesPerChapter = 12
+
+// dateLayout is the YYYY-MM-DD format used for schedule and finish dates
+const dateLayout = "2006-01-02"
+
+// ClubBook is a book the club is reading
+type ClubBook struct {
+	Title     string           `json:"title"`
+	Author    string           `json:"author"`
+	ISBN      string           `json:"isbn"`
+	PageCount float64          `json:"pageCount"`
+	Progress  *ReadingProgress `json:"progress"`
+	Schedule  []ScheduleEntry  `json:"schedule"`
+}
+
+// ReadingProgress is the club's saved progress through its current book. The chapter
+// fields predate tracking progress in pages.
+type ReadingProgress struct {
+	CurrentPages   *float64 `json:"currentPages"`
+	TotalPages     float64  `json:"totalPages"`
+	CurrentChapter *float64 `json:"currentChapter"`
+	TotalChapters  float64  `json:"totalChapters"`
+}
+
+// ScheduleEntry is the reading target for one meeting
+type ScheduleEntry struct {
+	Date    string  `json:"date"` // YYYY-MM-DD
+	Pages   float64 `json:"pages"`
+	Chapter float64 `json:"chapter"`
+}
+
+// pages converts the entry's target to pages
+func (e ScheduleEntry) pages() int {
+	if e.Pages > 0 {
+		return int(e.Pages)
+	}
+	return int(e.Chapter) * pagesPerChapter
+}
+
+// readingProgress returns the club's current and total pages, using the same rules as
+// the web app: saved progress first, then the target of the most recent past meeting.
+// total is 0 when the book's length is unknown.
+func (b *ClubBook) readingProgress(today string) (current, total int) {
+	progress := b.Progress
+	if progress == nil {
+		progress = &ReadingProgress{}
+	}
+
+	switch {
+	case progress.TotalPages > 0:
+		total = int(progress.TotalPages)
+	case progress.TotalChapters > 0:
+		total = int(progress.TotalChapters) * pagesPerChapter
+	default:
+		total = int(b.PageCount)
+	}
+
+	switch {
+	case progress.CurrentPages != nil:
+		current = int(*progress.CurrentPages)
+	case progress.CurrentChapter != nil:
+		current = int(*progress.CurrentChapter) * pagesPerChapter
+	default:
+		latest := ""
+		for _, entry := range b.Schedule {
+			if entry.Date < today && entry.Date > latest {
+				latest = entry.Date
+				current = entry.pages()
+			}
+		}
+	}
+	return current, total
+}
+
+// startedAt returns when the club started the book, taken as the date of its first
+// scheduled meeting, or "" if the book has no schedule. A first meeting after today
+// means the club is starting the book now.
+func (b *ClubBook) startedAt(today string) string {
+	first := ""
+	for _, entry := range b.Schedule {
+		if entry.Date != "" && (first == "" || entry.Date < first) {
+			first = entry.Date
+		}
+	}
+	if first > today {
+		return today
+	}
+	return first
+}
+
+// SyncClubReadingRequest represents the request to sync a club's current book to its
+// members' Hardcover accounts
+type SyncClubReadingRequest struct {
+	Completed       bool   `json:"completed,omitempty"`       // mark the book read even if progress is short of the end
+	FinishedAt      string `json:"finishedAt,omitempty"`      // YYYY-MM-DD, defaults to today
+	HardcoverBookID int    `json:"hardcoverBookId,omitempty"` // a candidate the admin picked, skipping the lookup
+}
+
+func (req *SyncClubReadingRequest) validate(v *validator) {
+	v.date("finishedAt", req.FinishedAt)
+	if req.HardcoverBookID < 0 {
+		v.add("hardcoverBookId", "must be a positive number")
+	}
+}
+
+// Reading statuses reported by the club reading sync
+const (
+	readingStatusCurrentlyReading = "currently_reading"
+	readingStatusRead             = "read"
+)
+
+// Outcomes of syncing one member
+const (
+	memberSyncSynced  = "synced"
+	memberSyncSkipped = "skipped"
+	memberSyncFailed  = "failed"
+)
+
+// MemberSyncResult reports the outcome of syncing one club member
+type MemberSyncResult struct {
+	UserID  string `json:"userId"`
+	Name    string `json:"name"`
+	Outcome string `json:"outcome"`          // "synced", "skipped" or "failed"
+	Action  string `json:"action,omitempty"` // "created" or "updated" when synced
+	Reason  string `json:"reason,omitempty"` // why the member was skipped, or the error code if failed
+}
+
+// ClubReadingSyncReport is the report of a club reading sync job
+type ClubReadingSyncReport struct {
+	ClubID        string             `json:"clubId"`
+	ISBN          string             `json:"isbn"`
+	Status        string             `json:"status"` // "currently_reading" or "read"
+	ProgressPages int                `json:"progressPages,omitempty"`
+	TotalPages    int                `json:"totalPages,omitempty"`
+	Synced        int                `json:"synced"`
+	Skipped       int                `json:"skipped"`
+	Failed        int                `json:"failed"`
+	Members       []MemberSyncResult `json:"members"`
+}
+
+// clubReadingSyncTimeout bounds a club reading sync job. Each member takes a handful of
+// Hardcover requests, so even a large club finishes in a few minutes.
+const clubReadingSyncTimeout = 30 * time.Minute
+
+// ClubReadingSyncJob is a club reading sync running in the background, stored under
+// hardcoverReadingSyncJobs/{clubId}/{jobId}. IDs sort oldest first.
+type ClubReadingSyncJob struct {
+	ID          string                 `json:"id,omitempty"` // the record's key; not stored in it
+	ClubID      string                 `json:"clubId"`
+	Status      string                 `json:"status"` // "running", "completed" or "failed"
+	StartedBy   string                 `json:"startedBy"`
+	StartedAt   int64                  `json:"startedAt"` // Unix seconds
+	FinishedAt  int64                  `json:"finishedAt,omitempty"`
+	Members     int                    `json:"members"`          // members to sync
+	MembersDone int                    `json:"membersDone"`      // members synced so far
+	Error       string                 `json:"error,omitempty"`  // why a failed job stopped: "timeout" or "interrupted"
+	Report      *ClubReadingSyncReport `json:"report,omitempty"` // set when the job finishes, partial if it failed
+}
+
+// readingSyncJobsPath returns the path holding the club's reading sync jobs
+func readingSyncJobsPath(clubID string) string {
+	return fmt.Sprintf("hardcoverReadingSyncJobs/%s", clubID)
+}
+
+// readingSync is what every member's Hardcover shelf is brought in line with
+type readingSync struct {
+	clubID        string
+	book          bookRef
+	bookID        int   // picked by the admin, resolved before the job starts, or by the first linked member's lookup
+	lookupErr     error // a failed lookup is not retried for every member
+	statusID      int
+	progressPages int
+	startedAt     string // YYYY-MM-DD for new read-throughs; empty if unknown
+	finishedAt    string
+}
+
+// syncClubReadingHandler starts a background job marking the club's current book as
+// currently reading, with the club's page progress, on the Hardcover account of every
+// linked member, and returns the job to poll. Once the club has finished the book (or
+// the request says so) it is marked read instead. The book and progress are read
+// before responding, so the club may move on to its next book while the job runs.
+// A book that isn't on Hardcover, or matches several books, fails the request rather
+// than every member; the admin retries with hardcoverBookId set to a candidate.
+func syncClubReadingHandler(w http.ResponseWriter, r *http.Request) {
+	var req SyncClubReadingRequest
+	if !decodeJSON(w, r, &req) || !validateRequest(w, r, &req) {
+		return
+	}
+
+	ctx := r.Context()
+	clubID := r.PathValue("clubId")
+	if !authorize(w, r, requireClubRole(clubID, roleAdmin), requireVerifiedEmail()) {
+		return
+	}
+
+	club, err := getClub(ctx, clubID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load club", "clubId", clubID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to load club", nil)
+		return
+	}
+	if club.CurrentBook == nil {
+		writeError(w, r, http.StatusConflict, errCodeNoCurrentBook, "The club has no current book to sync", nil)
+		return
+	}
+	// Without a valid ISBN the book is looked up by title and author
+	bookISBN := normalizeISBN(club.CurrentBook.ISBN)
+	if !isbn.Valid(bookISBN) {
+		bookISBN = ""
+	}
+	if bookISBN == "" && club.CurrentBook.Title == "" && req.HardcoverBookID == 0 {
+		writeError(w, r, http.StatusConflict, errCodeNoCurrentBook, "The club's current book has neither a valid ISBN nor a title to look it up by", nil)
+		return
+	}
+
+	today := time.Now().UTC().Format(dateLayout)
+	current, total := club.CurrentBook.readingProgress(today)
+	plan := &readingSync{
+		clubID:        clubID,
+		book:          bookRef{ISBN: bookISBN, Title: club.CurrentBook.Title, Author: club.CurrentBook.Author, HardcoverBookID: req.HardcoverBookID},
+		bookID:        req.HardcoverBookID,
+		statusID:      hardcover.StatusCurrentlyReading,
+		progressPages: current,
+		startedAt:     club.CurrentBook.startedAt(today),
+	}
+	report := ClubReadingSyncReport{
+		ClubID:        clubID,
+		ISBN:          bookISBN,
+		Status:        readingStatusCurrentlyReading,
+		ProgressPages: current,
+		TotalPages:    total,
+		Members:       []MemberSyncResult{},
+	}
+	if req.Completed || (total > 0 && current >= total) {
+		plan.statusID = hardcover.StatusRead
+		plan.finishedAt = req.FinishedAt
+		if plan.finishedAt == "" {
+			plan.finishedAt = today
+		}
+		report.Status = readingStatusRead
+	}
+
+	userID := principalFromContext(ctx).UID
+	var members []Member
+	userIDs := []string{userID} // whose token to look the book up with, the caller's first
+	for _, member := range club.Members {
+		if member.ID == "" {
+			continue
+		}
+		members = append(members, member)
+		if member.ID != userID {
+			userIDs = append(userIDs, member.ID)
+		}
+	}
+
+	if plan.bookID == 0 {
+		bookID, err := resolveClubBook(ctx, userIDs, plan.book)
+		var ambiguous *ambiguousBookError
+		switch {
+		case errors.Is(err, hardcover.ErrNotFound) || errors.As(err, &ambiguous):
+			slog.InfoContext(ctx, "Club reading sync book lookup failed", "clubId", clubID, "isbn", bookISBN, "error", err)
+			writeHardcoverError(w, r, err)
+			return
+		case err != nil:
+			// The token or Hardcover may recover; each member looks the book up again
+			slog.WarnContext(ctx, "Club reading sync book lookup failed, retrying per member", "clubId", clubID, "isbn", bookISBN, "error", err)
+		default:
+			plan.bookID = bookID
+		}
+	}
+	job := ClubReadingSyncJob{
+		ClubID:    clubID,
+		Status:    syncJobRunning,
+		StartedBy: userID,
+		StartedAt: time.Now().Unix(),
+		Members:   len(members),
+	}
+	jobsRef := firebaseDB.NewRef(readingSyncJobsPath(clubID))
+	ref, err := firebasePush(ctx, "hardcover_sync_jobs", jobsRef, job)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to create club reading sync job", "clubId", clubID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to start sync", nil)
+		return
+	}
+	job.ID = ref.Key
+	if err := trimToNewest(ctx, "hardcover_sync_jobs", jobsRef, maxClubSyncJobs); err != nil {
+		slog.WarnContext(ctx, "Failed to prune club reading sync jobs", "clubId", clubID, "error", err)
+	}
+
+	backgroundJobs.start(ctx, clubReadingSyncTimeout, func(ctx context.Context) {
+		runClubReadingSync(ctx, ref, job, plan, members, report)
+	})
+
+	slog.InfoContext(ctx, "Started club reading sync", "clubId", clubID, "jobId", job.ID, "uid", userID,
+		"isbn", bookISBN, "status", report.Status, "members", job.Members)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// runClubReadingSync syncs each member in turn, recording progress on the job at ref,
+// then stores the report. Members share plan's book lookup, so they are not synced
+// concurrently. If ctx ends first the job fails with the results so far.
+func runClubReadingSync(ctx context.Context, ref *db.Ref, job ClubReadingSyncJob, plan *readingSync, members []Member, report ClubReadingSyncReport) {
+	for _, member := range members {
+		if ctx.Err() != nil {
+			break
+		}
+		result := MemberSyncResult{UserID: member.ID, Name: member.Name}
+		action, skipReason, err := syncMemberReading(ctx, member.ID, plan)
+		switch {
+		case err != nil:
+			slog.WarnContext(ctx, "Hardcover reading sync failed", "clubId", job.ClubID, "uid", member.ID, "isbn", report.ISBN, "error", err)
+			result.Outcome = memberSyncFailed
+			result.Reason = hardcoverErrorCode(err)
+			report.Failed++
+		case skipReason != "":
+			result.Outcome = memberSyncSkipped
+			result.Reason = skipReason
+			report.Skipped++
+		default:
+			result.Outcome = memberSyncSynced
+			result.Action = action
+			report.Synced++
+		}
+		report.Members = append(report.Members, result)
+
+		if err := firebaseUpdate(ctx, "hardcover_sync_jobs", ref, map[string]interface{}{"membersDone": len(report.Members)}); err != nil {
+			slog.WarnContext(ctx, "Failed to record club reading sync progress", "clubId", job.ClubID, "jobId", job.ID, "error", err)
+		}
+	}
+
+	job.Status, job.Error = jobOutcome(ctx)
+	job.MembersDone, job.FinishedAt, job.Report = len(report.Members), time.Now().Unix(), &report
+	updates := map[string]interface{}{
+		"status":      job.Status,
+		"membersDone": job.MembersDone,
+		"finishedAt":  job.FinishedAt,
+		"report":      job.Report,
+	}
+	if job.Error != "" {
+		updates["error"] = job.Error
+	}
+	if err := saveJobResult(ctx, ref, updates); err != nil {
+		slog.ErrorContext(ctx, "Failed to save club reading sync report", "clubId", job.ClubID, "jobId", job.ID, "error", err)
+	}
+
+	slog.InfoContext(ctx, "Synced club reading status to Hardcover", "clubId", job.ClubID, "jobId", job.ID, "isbn", report.ISBN,
+		"status", report.Status, "jobStatus", job.Status, "synced", report.Synced, "skipped", report.Skipped, "failed", report.Failed)
+}
+
+// getClubReadingSyncJobHandler returns a club reading sync job and, once it finishes,
+// its report. Only club admins start these syncs, so only they can see them.
+func getClubReadingSyncJobHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	clubID, jobID := r.PathValue("clubId"), r.PathValue("jobId")
+	if !authorize(w, r, requireClubRole(clubID, roleAdmin)) {
+		return
+	}
+
+	var job *ClubReadingSyncJob
+	if err := firebaseGet(ctx, "hardcover_sync_jobs", firebaseDB.NewRef(readingSyncJobsPath(clubID)).Child(jobID), &job); err != nil {
+		slog.ErrorContext(ctx, "Failed to load club reading sync job", "clubId", clubID, "jobId", jobID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to load sync job", nil)
+		return
+	}
+	if job == nil {
+		writeError(w, r, http.StatusNotFound, errCodeNotFound, "Sync job not found", nil)
+		return
+	}
+	job.ID = jobID
+	writeJSON(w, http.StatusOK, job)
+}
+
+// resolveClubBook looks ref up on Hardcover with the token of the first linked user in
+// userIDs. It returns 0 without an error if none of them is linked.
+func resolveClubBook(ctx context.Context, userIDs []string, ref bookRef) (int, error) {
+	for _, userID := range userIDs {
+		token, err := getHardcoverToken(ctx, userID)
+		if errors.Is(err, errHardcoverNotLinked) {
+			continue
+		} else if err != nil {
+			return 0, fmt.Errorf("%w: %v", errHardcoverTokenUnavailable, err)
+		}
+		return resolveHardcoverBook(ctx, token, ref)
+	}
+	return 0, nil
+}
+
+// syncMemberReading brings one member's Hardcover shelf in line with plan. It returns
+// the action taken, or the reason the member was skipped.
+func syncMemberReading(ctx context.Context, userID string, plan *readingSync) (action, skipReason string, err error) {
+	token, err := getHardcoverToken(ctx, userID)
+	if errors.Is(err, errHardcoverNotLinked) {
+		return "", "not_linked", nil
+	} else if err != nil {
+		return "", "", fmt.Errorf("%w: %v", errHardcoverTokenUnavailable, err)
+	}
+
+	if plan.lookupErr != nil {
+		return "", "", plan.lookupErr
+	}
+	if plan.bookID == 0 {
+		bookID, err := resolveHardcoverBook(ctx, token, plan.book)
+		if err != nil {
+			// Only a missing or ambiguous book is the same for every member
+			var ambiguous *ambiguousBookError
+			err = fmt.Errorf("book lookup failed: %w", err)
+			if errors.Is(err, hardcover.ErrNotFound) || errors.As(err, &ambiguous) {
+				plan.lookupErr = err
+			}
+			return "", "", err
+		}
+		plan.bookID = bookID
+	}
+
+	userBook, err := hardcoverClient.FindUserBook(ctx, token, plan.bookID)
+	switch {
+	case errors.Is(err, hardcover.ErrNotFound):
+		// Books added to the shelf get the member's and club's review privacy
+		settings, err := loadSyncSettings(ctx, userID, plan.clubID)
+		if err != nil {
+			return "", "", fmt.Errorf("%w: %v", errSyncSettingsUnavailable, err)
+		}
+		input := hardcover.UserBookInput{BookID: plan.bookID, StatusID: plan.statusID}
+		settings.apply(&input)
+		id, err := hardcoverClient.InsertUserBook(ctx, token, input)
+		if err != nil {
+			return "", "", err
+		}
+		userBook = &hardcover.UserBook{ID: id, BookID: plan.bookID, StatusID: plan.statusID}
+		action = syncActionCreated
+		trackUserBook(ctx, userID, plan.bookID, id, true)
+	case err != nil:
+		return "", "", err
+	case userBook.StatusID == hardcover.StatusRead && !hasUnfinishedRead(userBook):
+		// The member already finished the book; don't move it back to currently reading
+		// or record a second finish
+		return "", "already_read", nil
+	default:
+		if userBook.StatusID != plan.statusID {
+			if err := hardcoverClient.UpdateUserBook(ctx, token, userBook.ID, hardcover.UserBookInput{StatusID: plan.statusID}); err != nil {
+				return "", "", err
+			}
+		}
+		action = syncActionUpdated
+	}
+
+	return action, "", syncReadProgress(ctx, token, userBook, plan)
+}
+
+// hasUnfinishedRead reports whether the user_book has a read-through in progress
+func hasUnfinishedRead(userBook *hardcover.UserBook) bool {
+	for _, read := range userBook.Reads {
+		if read.FinishedAt == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// syncReadProgress records the club's progress, and finish date when done, on the
+// member's read-through in progress, starting one if there is none
+func syncReadProgress(ctx context.Context, token string, userBook *hardcover.UserBook, plan *readingSync) error {
+	input := hardcover.UserBookReadInput{ProgressPages: plan.progressPages, FinishedAt: plan.finishedAt}
+	for _, read := range userBook.Reads {
+		if read.FinishedAt == "" {
+			return hardcoverClient.UpdateUserBookRead(ctx, token, read.ID, input)
+		}
+	}
+
+	input.StartedAt = plan.startedAt
+	if plan.finishedAt != "" && input.StartedAt > plan.finishedAt {
+		input.StartedAt = plan.finishedAt
+	}
+	_, err := hardcoverClient.InsertUserBookRead(ctx, token, userBook.ID, input)
+	return err
+}
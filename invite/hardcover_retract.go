@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/dhvogel/bookclurb-invite/hardcover"
+)
+
+// Actions reported when retracting a review
+const (
+	retractActionCleared    = "cleared"      // the review (and rating) were removed from the user_book
+	retractActionDeleted    = "deleted"      // the user_book was removed from the shelf
+	retractActionNotOnShelf = "not_on_shelf" // there was nothing to retract
+	retractActionUntracked  = "untracked"    // the book on the shelf isn't the one BookClurb synced, so it was left alone
+)
+
+// TrackedUserBook is a Hardcover user_book written by a sync, stored under
+// users/{uid}/hardcoverUserBooks/{hardcoverBookId} so a retraction touches the same record
+type TrackedUserBook struct {
+	UserBookID int  `json:"userBookId"`
+	Added      bool `json:"added,omitempty"` // BookClurb put the book on the shelf, so it may remove it
+}
+
+// trackedUserBookPath returns the path of uid's tracked user_book for bookID
+func trackedUserBookPath(userID string, bookID int) string {
+	return fmt.Sprintf("users/%s/hardcoverUserBooks/%d", userID, bookID)
+}
+
+// trackUserBook remembers the user_book a sync wrote. Added is only ever set, so a
+// later update of a book BookClurb added still allows removing it. Failures are logged.
+func trackUserBook(ctx context.Context, userID string, bookID, userBookID int, added bool) {
+	updates := map[string]interface{}{"userBookId": userBookID}
+	if added {
+		updates["added"] = true
+	}
+	if err := firebaseUpdate(ctx, "users", firebaseDB.NewRef(trackedUserBookPath(userID, bookID)), updates); err != nil {
+		slog.WarnContext(ctx, "Failed to track Hardcover user_book", "uid", userID, "bookId", bookID, "error", err)
+	}
+}
+
+// getTrackedUserBook returns the user_book tracked for bookID, or nil if there is none
+func getTrackedUserBook(ctx context.Context, userID string, bookID int) (*TrackedUserBook, error) {
+	var tracked *TrackedUserBook
+	if err := firebaseGet(ctx, "users", firebaseDB.NewRef(trackedUserBookPath(userID, bookID)), &tracked); err != nil {
+		return nil, fmt.Errorf("failed to load tracked user_book: %v", err)
+	}
+	if tracked != nil && tracked.UserBookID == 0 {
+		return nil, nil
+	}
+	return tracked, nil
+}
+
+// RetractReviewRequest represents the request to remove a synced review from Hardcover
+type RetractReviewRequest struct {
+	ISBN       string `json:"isbn,omitempty"`       // optional if title or hardcoverBookId is given
+	KeepRating bool   `json:"keepRating,omitempty"` // remove only the review
+	DeleteBook bool   `json:"deleteBook,omitempty"` // remove the book from the shelf if BookClurb added it
+	BookMatch
+}
+
+func (req *RetractReviewRequest) validate(v *validator) {
+	req.BookMatch.validate(v, req.ISBN)
+	if req.KeepRating && req.DeleteBook {
+		v.add("deleteBook", "cannot be combined with keepRating")
+	}
+}
+
+// RetractReviewResponse represents the response from retracting a review
+type RetractReviewResponse struct {
+	Success bool   `json:"success"`
+	Action  string `json:"action"` // "cleared", "deleted", "not_on_shelf" or "untracked"
+}
+
+// retractReviewHandler removes the caller's synced review, and rating unless
+// keepRating is set, from Hardcover. With deleteBook, a book BookClurb put on the
+// shelf is removed entirely; books the member shelved themselves are only cleared.
+func retractReviewHandler(w http.ResponseWriter, r *http.Request) {
+	var req RetractReviewRequest
+	if !decodeJSON(w, r, &req) || !validateRequest(w, r, &req) {
+		return
+	}
+
+	ctx := r.Context()
+	userID := principalFromContext(ctx).UID
+
+	token, err := getHardcoverToken(ctx, userID)
+	if errors.Is(err, errHardcoverNotLinked) {
+		writeError(w, r, http.StatusBadRequest, errCodeHardcoverNotLinked, "No Hardcover account linked", nil)
+		return
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Failed to load Hardcover token", "uid", userID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to load Hardcover account", nil)
+		return
+	}
+
+	bookID, err := resolveHardcoverBook(ctx, token, req.BookMatch.ref(req.ISBN))
+	if err != nil {
+		slog.WarnContext(ctx, "Hardcover retraction failed", "uid", userID, "isbn", req.ISBN, "error", err)
+		writeHardcoverError(w, r, err)
+		return
+	}
+	tracked, err := getTrackedUserBook(ctx, userID, bookID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load tracked Hardcover user_book", "uid", userID, "bookId", bookID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to load Hardcover sync state", nil)
+		return
+	}
+
+	action, err := retractUserBook(ctx, token, bookID, tracked, !req.KeepRating, req.DeleteBook)
+	if err != nil {
+		slog.WarnContext(ctx, "Hardcover retraction failed", "uid", userID, "bookId", bookID, "error", err)
+		writeHardcoverError(w, r, err)
+		return
+	}
+
+	if action != retractActionCleared && tracked != nil {
+		if err := firebaseUpdate(ctx, "users", firebaseDB.NewRef(fmt.Sprintf("users/%s/hardcoverUserBooks", userID)), map[string]interface{}{
+			fmt.Sprint(bookID): nil,
+		}); err != nil {
+			slog.WarnContext(ctx, "Failed to untrack Hardcover user_book", "uid", userID, "bookId", bookID, "error", err)
+		}
+	}
+	scrubSyncHistoryReviews(ctx, userID, bookID, normalizeISBN(req.ISBN))
+
+	slog.InfoContext(ctx, "Retracted review from Hardcover", "uid", userID, "bookId", bookID, "action", action)
+	writeJSON(w, http.StatusOK, RetractReviewResponse{Success: true, Action: action})
+}
+
+// retractUserBook clears or deletes the user_book a sync tracked for bookID. Only that
+// record is touched, and only while it is still the user's user_book for the book; one
+// the member shelved themselves, or removed and shelved again, is left alone.
+func retractUserBook(ctx context.Context, token string, bookID int, tracked *TrackedUserBook, clearRating, deleteBook bool) (string, error) {
+	if tracked == nil {
+		return retractActionUntracked, nil
+	}
+	userBook, err := hardcoverClient.FindUserBook(ctx, token, bookID)
+	if errors.Is(err, hardcover.ErrNotFound) {
+		return retractActionNotOnShelf, nil
+	} else if err != nil {
+		return "", err
+	}
+	if userBook.ID != tracked.UserBookID {
+		return retractActionUntracked, nil
+	}
+
+	action := retractActionCleared
+	if deleteBook && tracked.Added {
+		action, err = retractActionDeleted, hardcoverClient.DeleteUserBook(ctx, token, tracked.UserBookID)
+	} else {
+		err = hardcoverClient.ClearUserBookReview(ctx, token, tracked.UserBookID, clearRating)
+	}
+	if errors.Is(err, hardcover.ErrNotFound) {
+		// Removed on Hardcover since the lookup
+		return retractActionNotOnShelf, nil
+	} else if err != nil {
+		return "", err
+	}
+	return action, nil
+}
+
+// scrubSyncHistoryReviews drops the club review reference from the user's sync attempts
+// for bookID, or bookISBN if the attempt's lookup failed, so a retry doesn't send the
+// retracted review again. Failures are logged.
+func scrubSyncHistoryReviews(ctx context.Context, userID string, bookID int, bookISBN string) {
+	attempts, err := getSyncHistory(ctx, userID)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to load Hardcover sync history", "uid", userID, "error", err)
+		return
+	}
+	updates := map[string]interface{}{}
+	for _, attempt := range attempts {
+		sameBook := attempt.HardcoverBookID == bookID || (bookISBN != "" && attempt.ISBN == bookISBN)
+		if sameBook && attempt.Review {
+			updates[attempt.ID+"/review"] = nil
+		}
+	}
+	if len(updates) == 0 {
+		return
+	}
+	if err := firebaseUpdate(ctx, "users", firebaseDB.NewRef(syncHistoryPath(userID)), updates); err != nil {
+		slog.WarnContext(ctx, "Failed to remove reviews from Hardcover sync history", "uid", userID, "bookId", bookID, "error", err)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/dhvogel/bookclurb-invite/hardcover"
+)
+
+// Review privacy levels for HardcoverSyncSettings.ReviewPrivacy. Empty keeps the
+// Hardcover account's default.
+const (
+	reviewPrivacyPublic    = "public"
+	reviewPrivacyFollowers = "followers"
+	reviewPrivacyPrivate   = "private"
+)
+
+// errSyncSettingsUnavailable wraps failures to read a member's or club's sync settings
+var errSyncSettingsUnavailable = errors.New("failed to load Hardcover sync settings")
+
+// reviewPrivacySettingIDs maps privacy levels to Hardcover privacy settings, which
+// also rank them from least to most private
+var reviewPrivacySettingIDs = map[string]int{
+	"":                     0,
+	reviewPrivacyPublic:    hardcover.PrivacyPublic,
+	reviewPrivacyFollowers: hardcover.PrivacyFollowers,
+	reviewPrivacyPrivate:   hardcover.PrivacyPrivate,
+}
+
+// HardcoverSyncSettings control how reviews are synced to Hardcover. A member's
+// settings are stored under users/{uid}/hardcoverSyncSettings and a club's under
+// clubs/{clubId}/hardcoverSyncSettings; syncs of a club's books use the more
+// private of the two.
+type HardcoverSyncSettings struct {
+	ReviewPrivacy string `json:"reviewPrivacy,omitempty"` // "public", "followers" or "private"; the account default if empty
+	Spoilers      bool   `json:"spoilers,omitempty"`      // flag synced reviews as containing spoilers
+	RatingOnly    bool   `json:"ratingOnly,omitempty"`    // sync ratings but never review text
+}
+
+func (s *HardcoverSyncSettings) validate(v *validator) {
+	if _, ok := reviewPrivacySettingIDs[s.ReviewPrivacy]; !ok {
+		v.add("reviewPrivacy", "must be public, followers or private")
+	}
+}
+
+// strictest combines two sets of settings, keeping the more private choice of each
+func (s HardcoverSyncSettings) strictest(other HardcoverSyncSettings) HardcoverSyncSettings {
+	if reviewPrivacySettingIDs[other.ReviewPrivacy] > reviewPrivacySettingIDs[s.ReviewPrivacy] {
+		s.ReviewPrivacy = other.ReviewPrivacy
+	}
+	s.Spoilers = s.Spoilers || other.Spoilers
+	s.RatingOnly = s.RatingOnly || other.RatingOnly
+	return s
+}
+
+// apply sets the privacy and spoiler flag on a user_book write, and drops the review
+// if only ratings are synced
+func (s HardcoverSyncSettings) apply(input *hardcover.UserBookInput) {
+	input.PrivacySettingID = reviewPrivacySettingIDs[s.ReviewPrivacy]
+	if s.RatingOnly {
+		input.Review = ""
+	}
+	if input.Review != "" {
+		spoilers := s.Spoilers
+		input.ReviewHasSpoilers = &spoilers
+	}
+}
+
+// getSyncSettings loads the settings stored under path; missing settings are empty
+func getSyncSettings(ctx context.Context, resource, path string) (HardcoverSyncSettings, error) {
+	var settings *HardcoverSyncSettings
+	if err := firebaseGet(ctx, resource, firebaseDB.NewRef(path+"/hardcoverSyncSettings"), &settings); err != nil {
+		return HardcoverSyncSettings{}, fmt.Errorf("failed to load Hardcover sync settings: %v", err)
+	}
+	if settings == nil {
+		return HardcoverSyncSettings{}, nil
+	}
+	return *settings, nil
+}
+
+// loadSyncSettings returns the settings for syncing userID's reviews of a book read in
+// clubID, or the user's own settings if clubID is empty
+func loadSyncSettings(ctx context.Context, userID, clubID string) (HardcoverSyncSettings, error) {
+	settings, err := getSyncSettings(ctx, "users", fmt.Sprintf("users/%s", userID))
+	if err != nil || clubID == "" {
+		return settings, err
+	}
+	clubSettings, err := getSyncSettings(ctx, "clubs", fmt.Sprintf("clubs/%s", clubID))
+	if err != nil {
+		return settings, err
+	}
+	return settings.strictest(clubSettings), nil
+}
+
+// saveSyncSettings replaces the settings stored under path
+func saveSyncSettings(ctx context.Context, resource, path string, settings HardcoverSyncSettings) error {
+	return firebaseUpdate(ctx, resource, firebaseDB.NewRef(path), map[string]interface{}{"hardcoverSyncSettings": settings})
+}
+
+// getMySyncSettingsHandler returns the caller's Hardcover sync settings
+func getMySyncSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := principalFromContext(ctx).UID
+
+	settings, err := getSyncSettings(ctx, "users", fmt.Sprintf("users/%s", userID))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load Hardcover sync settings", "uid", userID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to load Hardcover sync settings", nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, settings)
+}
+
+// updateMySyncSettingsHandler replaces the caller's Hardcover sync settings
+func updateMySyncSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	var settings HardcoverSyncSettings
+	if !decodeJSON(w, r, &settings) || !validateRequest(w, r, &settings) {
+		return
+	}
+
+	ctx := r.Context()
+	userID := principalFromContext(ctx).UID
+	if err := saveSyncSettings(ctx, "users", fmt.Sprintf("users/%s", userID), settings); err != nil {
+		slog.ErrorContext(ctx, "Failed to save Hardcover sync settings", "uid", userID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to save Hardcover sync settings", nil)
+		return
+	}
+
+	slog.InfoContext(ctx, "Updated Hardcover sync settings", "uid", userID, "reviewPrivacy", settings.ReviewPrivacy,
+		"spoilers", settings.Spoilers, "ratingOnly", settings.RatingOnly)
+	writeJSON(w, http.StatusOK, settings)
+}
+
+// getClubSyncSettingsHandler returns the club's Hardcover sync settings to its members
+func getClubSyncSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	clubID := r.PathValue("clubId")
+	if !authorize(w, r, requireClubRole(clubID, roleMember)) {
+		return
+	}
+
+	settings, err := getSyncSettings(ctx, "clubs", fmt.Sprintf("clubs/%s", clubID))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load Hardcover sync settings", "clubId", clubID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to load Hardcover sync settings", nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, settings)
+}
+
+// updateClubSyncSettingsHandler replaces the club's Hardcover sync settings. They
+// apply on top of each member's own settings when syncing the club's books.
+func updateClubSyncSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	var settings HardcoverSyncSettings
+	if !decodeJSON(w, r, &settings) || !validateRequest(w, r, &settings) {
+		return
+	}
+
+	ctx := r.Context()
+	clubID := r.PathValue("clubId")
+	if !authorize(w, r, requireClubRole(clubID, roleAdmin), requireVerifiedEmail()) {
+		return
+	}
+
+	if err := saveSyncSettings(ctx, "clubs", fmt.Sprintf("clubs/%s", clubID), settings); err != nil {
+		slog.ErrorContext(ctx, "Failed to save Hardcover sync settings", "clubId", clubID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to save Hardcover sync settings", nil)
+		return
+	}
+
+	slog.InfoContext(ctx, "Updated club Hardcover sync settings", "clubId", clubID, "uid", principalFromContext(ctx).UID,
+		"reviewPrivacy", settings.ReviewPrivacy, "spoilers", settings.Spoilers, "ratingOnly", settings.RatingOnly)
+	writeJSON(w, http.StatusOK, settings)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const healthCheckTimeout = 3 * time.Second
+
+// HealthCheckResult represents the outcome of a single dependency check
+type HealthCheckResult struct {
+	Status    string `json:"status"`
+	Required  bool   `json:"required"`
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"` // "unavailable"; the cause is only logged
+}
+
+// ReadinessResponse represents the response from the readiness endpoint
+type ReadinessResponse struct {
+	Status string                       `json:"status"`
+	Checks map[string]HealthCheckResult `json:"checks"`
+}
+
+// healthCheck is a named dependency check run by readyzHandler
+type healthCheck struct {
+	name     string
+	required bool
+	check    func(ctx context.Context) error
+}
+
+// readinessChecks returns the dependency checks for this instance.
+// The Hardcover check is only included when READYZ_CHECK_HARDCOVER=true and never fails readiness.
+func readinessChecks() []healthCheck {
+	checks := []healthCheck{
+		{name: "firebase", required: true, check: checkFirebase},
+		{name: "mailer", required: true, check: checkMailer},
+	}
+	if getEnv("READYZ_CHECK_HARDCOVER", "false") == "true" {
+		checks = append(checks, healthCheck{name: "hardcover", required: false, check: checkHardcover})
+	}
+	return checks
+}
+
+// checkFirebase verifies the Realtime Database is reachable with the service credentials
+func checkFirebase(ctx context.Context) error {
+	var value interface{}
+	return firebaseGet(ctx, "healthcheck", firebaseDB.NewRef("healthcheck"), &value)
+}
+
+// checkMailer verifies SMTP credentials were configured at startup
+func checkMailer(ctx context.Context) error {
+	if mailer == nil {
+		return fmt.Errorf("email credentials not configured")
+	}
+	return nil
+}
+
+// checkHardcover verifies the Hardcover API answers; any non-5xx response counts as reachable
+func checkHardcover(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, hardcoverClient.Endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := hardcoverClient.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// healthzHandler reports liveness; it only confirms the process is serving requests
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// readyzHandler runs the dependency checks and returns 503 if any required check fails
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	checks := readinessChecks()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	response := ReadinessResponse{
+		Status: "ready",
+		Checks: make(map[string]HealthCheckResult, len(checks)),
+	}
+
+	for _, hc := range checks {
+		wg.Add(1)
+		go func(hc healthCheck) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := hc.check(ctx)
+			result := HealthCheckResult{
+				Status:    "ok",
+				Required:  hc.required,
+				LatencyMs: time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				// /readyz is unauthenticated, so dependency errors stay in the logs
+				result.Status = "error"
+				result.Error = "unavailable"
+				slog.WarnContext(r.Context(), "Readiness check failed", "check", hc.name, "required", hc.required,
+					"latencyMs", result.LatencyMs, "error", redactString(err.Error()))
+			}
+
+			mu.Lock()
+			response.Checks[hc.name] = result
+			if err != nil && hc.required {
+				response.Status = "not_ready"
+			}
+			mu.Unlock()
+		}(hc)
+	}
+	wg.Wait()
+
+	status := http.StatusOK
+	if response.Status != "ready" {
+		status = http.StatusServiceUnavailable
+		slog.WarnContext(r.Context(), "Instance not ready")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadyzHandlerHidesErrors(t *testing.T) {
+	// Tests run without SMTP credentials, so the mailer check fails
+	w := httptest.NewRecorder()
+	readyzHandler(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	var response ReadinessResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Status != "not_ready" {
+		t.Errorf("status = %q, want not_ready", response.Status)
+	}
+	want := map[string]HealthCheckResult{
+		"firebase": {Status: "ok", Required: true},
+		"mailer":   {Status: "error", Required: true, Error: "unavailable"},
+	}
+	for name, wantResult := range want {
+		got := response.Checks[name]
+		got.LatencyMs = 0
+		if got != wantResult {
+			t.Errorf("checks[%s] = %+v, want %+v", name, got, wantResult)
+		}
+	}
+}
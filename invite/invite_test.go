@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestSendClubInvite(t *testing.T) {
+	tests := []struct {
+		name       string
+		invite     string // stored invite record, or "" for none
+		email      string
+		caller     *Principal
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			// Every check passed; tests have no mailer configured
+			name: "matching invite", invite: `{"email": "friend@example.com", "status": "pending"}`,
+			email: "friend@example.com", caller: testPrincipal("admin", true),
+			wantStatus: http.StatusServiceUnavailable, wantCode: errCodeEmailUnavailable,
+		},
+		{
+			name: "email differs in case", invite: `{"email": "Friend@Example.com", "status": "pending"}`,
+			email: "friend@example.com", caller: testPrincipal("admin", true),
+			wantStatus: http.StatusServiceUnavailable, wantCode: errCodeEmailUnavailable,
+		},
+		{
+			name: "missing invite", email: "friend@example.com", caller: testPrincipal("admin", true),
+			wantStatus: http.StatusNotFound, wantCode: errCodeInviteNotFound,
+		},
+		{
+			name: "different email", invite: `{"email": "friend@example.com", "status": "pending"}`,
+			email: "someone@example.com", caller: testPrincipal("admin", true),
+			wantStatus: http.StatusConflict, wantCode: errCodeInviteEmailMismatch,
+		},
+		{
+			name: "invite without email", invite: `{"status": "pending"}`,
+			email: "friend@example.com", caller: testPrincipal("admin", true),
+			wantStatus: http.StatusConflict, wantCode: errCodeInviteEmailMismatch,
+		},
+		{
+			name: "caller is a member", invite: `{"email": "friend@example.com", "status": "pending"}`,
+			email: "friend@example.com", caller: testPrincipal("member", true),
+			wantStatus: http.StatusForbidden, wantCode: errCodeForbidden,
+		},
+		{
+			name: "unverified admin", invite: `{"email": "friend@example.com", "status": "pending"}`,
+			email: "friend@example.com", caller: testPrincipal("admin", false),
+			wantStatus: http.StatusForbidden, wantCode: errCodeEmailNotVerified,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clubID := fmt.Sprintf("send%d", i)
+			seedClub(t, clubID, false, Member{ID: "admin", Role: roleAdmin}, Member{ID: "member"})
+			if tt.invite != "" {
+				testDB.set(t, fmt.Sprintf("club_invites/%s/invite1", clubID), tt.invite)
+			}
+
+			body := fmt.Sprintf(`{"email": %q, "clubName": "Club", "inviteId": "invite1"}`, tt.email)
+			w := serveClubRequest(sendClubInvite, http.MethodPost, body, tt.caller, map[string]string{"clubId": clubID})
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body: %s)", w.Code, tt.wantStatus, w.Body)
+			}
+			if code := errorCode(t, w); code != tt.wantCode {
+				t.Errorf("error code = %q, want %q", code, tt.wantCode)
+			}
+			if tt.invite == "" && testDB.get(fmt.Sprintf("club_invites/%s/invite1", clubID)) != nil {
+				t.Errorf("a missing invite was created")
+			}
+		})
+	}
+}
+
+func TestAcceptClubInvite(t *testing.T) {
+	tests := []struct {
+		name       string
+		invite     string // stored invite record, or "" for none
+		caller     *Principal
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name: "matching email", invite: `{"email": "friend@example.com", "status": "sent"}`,
+			caller: testPrincipal("friend", true), wantStatus: http.StatusOK,
+		},
+		{
+			name: "email differs in case", invite: `{"email": "FRIEND@example.com", "status": "sent"}`,
+			caller: testPrincipal("friend", true), wantStatus: http.StatusOK,
+		},
+		{
+			name: "different email", invite: `{"email": "someone@example.com", "status": "sent"}`,
+			caller: testPrincipal("friend", true), wantStatus: http.StatusForbidden, wantCode: errCodeForbidden,
+		},
+		{
+			name: "invite without email", invite: `{"status": "sent"}`,
+			caller: testPrincipal("friend", true), wantStatus: http.StatusForbidden, wantCode: errCodeForbidden,
+		},
+		{
+			name: "missing invite", caller: testPrincipal("friend", true),
+			wantStatus: http.StatusNotFound, wantCode: errCodeInviteNotFound,
+		},
+		{
+			name: "invite not sent", invite: `{"email": "friend@example.com", "status": "pending"}`,
+			caller: testPrincipal("friend", true), wantStatus: http.StatusConflict, wantCode: errCodeInviteInactive,
+		},
+		{
+			name: "invite already accepted", invite: `{"email": "friend@example.com", "status": "accepted"}`,
+			caller: testPrincipal("friend", true), wantStatus: http.StatusConflict, wantCode: errCodeInviteInactive,
+		},
+		{
+			name: "unverified email", invite: `{"email": "friend@example.com", "status": "sent"}`,
+			caller: testPrincipal("friend", false), wantStatus: http.StatusForbidden, wantCode: errCodeEmailNotVerified,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clubID := fmt.Sprintf("accept%d", i)
+			seedClub(t, clubID, false, Member{ID: "admin", Role: roleAdmin})
+			if tt.invite != "" {
+				testDB.set(t, fmt.Sprintf("club_invites/%s/invite1", clubID), tt.invite)
+			}
+
+			w := serveClubRequest(acceptClubInvite, http.MethodPost, "", tt.caller,
+				map[string]string{"clubId": clubID, "inviteId": "invite1"})
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body: %s)", w.Code, tt.wantStatus, w.Body)
+			}
+			if code := errorCode(t, w); code != tt.wantCode {
+				t.Errorf("error code = %q, want %q", code, tt.wantCode)
+			}
+
+			want := map[string]string{"admin": roleAdmin}
+			if tt.wantStatus == http.StatusOK {
+				want["friend"] = roleMember
+				if status := testDB.get(fmt.Sprintf("club_invites/%s/invite1/status", clubID)); status != "accepted" {
+					t.Errorf("invite status = %v, want accepted", status)
+				}
+			}
+			checkMembership(t, clubID, want)
+		})
+	}
+}
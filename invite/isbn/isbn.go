@@ -0,0 +1,129 @@
+// Package isbn validates and converts International Standard Book Numbers.
+package isbn
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalid is matched by every validation error returned by this package
+	ErrInvalid = errors.New("isbn: invalid")
+	// ErrNoISBN10 is returned by To10 for valid ISBN-13s starting with 979, which have
+	// no ISBN-10 form. It does not match ErrInvalid.
+	ErrNoISBN10 = errors.New("isbn: no ISBN-10 form")
+)
+
+// Normalize strips the hyphens and spaces found in printed ISBNs and upper-cases an
+// ISBN-10 "x" check digit. It does not validate.
+func Normalize(s string) string {
+	s = strings.NewReplacer("-", "", " ", "", "‐", "", "‑", "").Replace(strings.TrimSpace(s))
+	return strings.ToUpper(s)
+}
+
+// Validate returns an error matching ErrInvalid that says why s is not a valid
+// ISBN-10 or ISBN-13. s is normalized first.
+func Validate(s string) error {
+	s = Normalize(s)
+	switch len(s) {
+	case 10:
+		for i, c := range s {
+			if (c < '0' || c > '9') && !(c == 'X' && i == 9) {
+				return fmt.Errorf("%w: %q has a character other than digits and a final X", ErrInvalid, s)
+			}
+		}
+		if checkDigit10(s[:9]) != s[9] {
+			return fmt.Errorf("%w: %q fails the ISBN-10 checksum", ErrInvalid, s)
+		}
+	case 13:
+		for _, c := range s {
+			if c < '0' || c > '9' {
+				return fmt.Errorf("%w: %q has a character other than digits", ErrInvalid, s)
+			}
+		}
+		if !strings.HasPrefix(s, "978") && !strings.HasPrefix(s, "979") {
+			return fmt.Errorf("%w: %q does not start with 978 or 979", ErrInvalid, s)
+		}
+		if checkDigit13(s[:12]) != s[12] {
+			return fmt.Errorf("%w: %q fails the ISBN-13 checksum", ErrInvalid, s)
+		}
+	default:
+		return fmt.Errorf("%w: %q is not 10 or 13 characters long", ErrInvalid, s)
+	}
+	return nil
+}
+
+// Valid reports whether s is a valid ISBN-10 or ISBN-13
+func Valid(s string) bool {
+	return Validate(s) == nil
+}
+
+// To13 returns the normalized ISBN-13 form of s
+func To13(s string) (string, error) {
+	if err := Validate(s); err != nil {
+		return "", err
+	}
+	s = Normalize(s)
+	if len(s) == 13 {
+		return s, nil
+	}
+	body := "978" + s[:9]
+	return body + string(checkDigit13(body)), nil
+}
+
+// To10 returns the normalized ISBN-10 form of s. ISBN-13s starting with 979 have no
+// ISBN-10 form and return an error matching ErrNoISBN10.
+func To10(s string) (string, error) {
+	if err := Validate(s); err != nil {
+		return "", err
+	}
+	s = Normalize(s)
+	if len(s) == 10 {
+		return s, nil
+	}
+	if !strings.HasPrefix(s, "978") {
+		return "", fmt.Errorf("%w: %q starts with 979", ErrNoISBN10, s)
+	}
+	body := s[3:12]
+	return body + string(checkDigit10(body)), nil
+}
+
+// Forms returns every normalized form of s: the ISBN-13, then the ISBN-10 if it has one
+func Forms(s string) ([]string, error) {
+	isbn13, err := To13(s)
+	if err != nil {
+		return nil, err
+	}
+	forms := []string{isbn13}
+	if isbn10, err := To10(isbn13); err == nil {
+		forms = append(forms, isbn10)
+	}
+	return forms, nil
+}
+
+// checkDigit10 computes the ISBN-10 check digit for the first nine digits
+func checkDigit10(body string) byte {
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(body[i]-'0') * (10 - i)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return 'X'
+	}
+	return byte('0' + check)
+}
+
+// checkDigit13 computes the ISBN-13 check digit for the first twelve digits
+func checkDigit13(body string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += int(body[i]-'0') * weight
+	}
+	return byte('0' + (10-sum%10)%10)
+}
@@ -0,0 +1,149 @@
+package isbn
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{in: "978-0-306-40615-7", want: "9780306406157"},
+		{in: " 0 306 40615 2 ", want: "0306406152"},
+		{in: "0‐8044‑2957‐x", want: "080442957X"}, // Unicode hyphens and a lowercase check digit
+		{in: "9780306406157", want: "9780306406157"},
+		{in: "", want: ""},
+		{in: "not-an-isbn", want: "NOTANISBN"},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		isbn  string
+		valid bool
+	}{
+		{name: "ISBN-10", isbn: "0306406152", valid: true},
+		{name: "ISBN-10 hyphenated", isbn: "0-306-40615-2", valid: true},
+		{name: "ISBN-10 with X check digit", isbn: "080442957X", valid: true},
+		{name: "ISBN-10 with lowercase x", isbn: "080442957x", valid: true},
+		{name: "ISBN-13", isbn: "9780306406157", valid: true},
+		{name: "ISBN-13 hyphenated", isbn: "978-0-306-40615-7", valid: true},
+		{name: "ISBN-13 starting with 979", isbn: "9791090636071", valid: true},
+		{name: "ISBN-10 bad checksum", isbn: "0306406153"},
+		{name: "ISBN-10 X not last", isbn: "08044295X7"},
+		{name: "ISBN-10 X where a digit is due", isbn: "030640615X"},
+		{name: "ISBN-13 bad checksum", isbn: "9780306406158"},
+		{name: "ISBN-13 with X", isbn: "978030640615X"},
+		{name: "ISBN-13 wrong prefix", isbn: "9770306406157"},
+		{name: "letters", isbn: "03064O6152"},
+		{name: "too short", isbn: "030640615"},
+		{name: "between lengths", isbn: "978030640615"},
+		{name: "empty", isbn: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.isbn)
+			if (err == nil) != tt.valid {
+				t.Fatalf("Validate(%q) = %v, want valid %v", tt.isbn, err, tt.valid)
+			}
+			if err != nil && !errors.Is(err, ErrInvalid) {
+				t.Errorf("Validate(%q) = %v, want an error matching ErrInvalid", tt.isbn, err)
+			}
+			if Valid(tt.isbn) != tt.valid {
+				t.Errorf("Valid(%q) = %v, want %v", tt.isbn, !tt.valid, tt.valid)
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name      string
+		isbn      string
+		want13    string
+		want10    string
+		want10Err error
+		wantForms []string
+	}{
+		{
+			name: "from ISBN-10", isbn: "0-306-40615-2",
+			want13: "9780306406157", want10: "0306406152", wantForms: []string{"9780306406157", "0306406152"},
+		},
+		{
+			name: "from ISBN-13", isbn: "978-0-306-40615-7",
+			want13: "9780306406157", want10: "0306406152", wantForms: []string{"9780306406157", "0306406152"},
+		},
+		{
+			// The X check digit only exists in ISBN-10s; the ISBN-13 gets a digit
+			name: "X check digit to ISBN-13", isbn: "080442957x",
+			want13: "9780804429573", want10: "080442957X", wantForms: []string{"9780804429573", "080442957X"},
+		},
+		{
+			name: "ISBN-13 to X check digit", isbn: "9780804429573",
+			want13: "9780804429573", want10: "080442957X", wantForms: []string{"9780804429573", "080442957X"},
+		},
+		{
+			name: "979 has no ISBN-10", isbn: "979-10-90636-07-1",
+			want13: "9791090636071", want10Err: ErrNoISBN10, wantForms: []string{"9791090636071"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := To13(tt.isbn); err != nil || got != tt.want13 {
+				t.Errorf("To13(%q) = %q, %v; want %q", tt.isbn, got, err, tt.want13)
+			}
+
+			got10, err := To10(tt.isbn)
+			if tt.want10Err != nil {
+				if !errors.Is(err, tt.want10Err) || errors.Is(err, ErrInvalid) {
+					t.Errorf("To10(%q) error = %v, want %v and not ErrInvalid", tt.isbn, err, tt.want10Err)
+				}
+			} else if err != nil || got10 != tt.want10 {
+				t.Errorf("To10(%q) = %q, %v; want %q", tt.isbn, got10, err, tt.want10)
+			}
+
+			if forms, err := Forms(tt.isbn); err != nil || !reflect.DeepEqual(forms, tt.wantForms) {
+				t.Errorf("Forms(%q) = %v, %v; want %v", tt.isbn, forms, err, tt.wantForms)
+			}
+		})
+	}
+}
+
+func TestConvertInvalid(t *testing.T) {
+	for _, s := range []string{"0306406153", "9780306406158", "abc", ""} {
+		if _, err := To13(s); !errors.Is(err, ErrInvalid) {
+			t.Errorf("To13(%q) error = %v, want ErrInvalid", s, err)
+		}
+		if _, err := To10(s); !errors.Is(err, ErrInvalid) {
+			t.Errorf("To10(%q) error = %v, want ErrInvalid", s, err)
+		}
+		if _, err := Forms(s); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Forms(%q) error = %v, want ErrInvalid", s, err)
+		}
+	}
+}
+
+// Every ISBN-10 converts to an ISBN-13 and back unchanged, including X check digits
+func TestRoundTrip(t *testing.T) {
+	for body := 0; body < 1000; body++ {
+		prefix := []byte("030640000")
+		prefix[6], prefix[7], prefix[8] = byte('0'+body/100), byte('0'+body/10%10), byte('0'+body%10)
+		isbn10 := string(prefix) + string(checkDigit10(string(prefix)))
+
+		isbn13, err := To13(isbn10)
+		if err != nil {
+			t.Fatalf("To13(%q) error = %v", isbn10, err)
+		}
+		if back, err := To10(isbn13); err != nil || back != isbn10 {
+			t.Fatalf("To10(%q) = %q, %v; want %q", isbn13, back, err, isbn10)
+		}
+	}
+}
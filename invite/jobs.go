@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"firebase.google.com/go/v4/db"
+)
+
+// backgroundJobs runs work that outlives the request that started it, such as club
+// reading and ratings syncs, and stops it when the server shuts down
+var backgroundJobs = newJobRunner()
+
+// jobRunner tracks background jobs so shutdown can cancel them and wait for them to
+// record how far they got
+type jobRunner struct {
+	ctx    context.Context // cancelled on shutdown
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newJobRunner() *jobRunner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &jobRunner{ctx: ctx, cancel: cancel}
+}
+
+// start runs job in the background with a context that keeps ctx's values (trace and
+// log attributes) but not its cancellation, so a client disconnecting doesn't stop the
+// job. The job's context is cancelled after timeout or on shutdown.
+func (j *jobRunner) start(ctx context.Context, timeout time.Duration, job func(ctx context.Context)) {
+	jobCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	stop := context.AfterFunc(j.ctx, cancel)
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		defer stop()
+		defer cancel()
+		job(jobCtx)
+	}()
+}
+
+// shutdown cancels running jobs and waits for them to return, or for ctx to be done
+func (j *jobRunner) shutdown(ctx context.Context) error {
+	j.cancel()
+	done := make(chan struct{})
+	go func() {
+		j.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Statuses of jobs stored for clients to poll
+const (
+	syncJobRunning   = "running"
+	syncJobCompleted = "completed"
+	syncJobFailed    = "failed"
+)
+
+// jobOutcome returns the final status of a job run with ctx and, if it failed, why:
+// "timeout" or "interrupted" (by shutdown)
+func jobOutcome(ctx context.Context) (status, reason string) {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return syncJobFailed, "timeout"
+	case ctx.Err() != nil:
+		return syncJobFailed, "interrupted"
+	}
+	return syncJobCompleted, ""
+}
+
+// saveJobResult stores a finished job's fields at ref. The job's context may be done,
+// but the outcome should still be saved.
+func saveJobResult(ctx context.Context, ref *db.Ref, updates map[string]interface{}) error {
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	return firebaseUpdate(saveCtx, "hardcover_sync_jobs", ref, updates)
+}
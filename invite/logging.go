@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Cloud Logging special fields, see
+// https://cloud.google.com/logging/docs/structured-logging#special-payload-fields
+const (
+	logTraceKey   = "logging.googleapis.com/trace"
+	logSpanKey    = "logging.googleapis.com/spanId"
+	logSampledKey = "logging.googleapis.com/trace_sampled"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+type logContextKey int
+
+const (
+	requestIDKey logContextKey = iota
+	traceInfoKey
+)
+
+// traceInfo holds the Cloud Trace context of the current request
+type traceInfo struct {
+	TraceID string
+	SpanID  string
+	Sampled bool
+}
+
+var (
+	// logProjectID is used to build fully qualified trace names for Cloud Logging
+	logProjectID string
+
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	// Bearer credentials and JWTs (Firebase ID tokens, Hardcover API tokens)
+	tokenPattern = regexp.MustCompile(`(?i)bearer\s+[A-Za-z0-9\-._~+/]+=*|eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]*`)
+
+	sensitiveLogKeys = map[string]bool{
+		"email":         true,
+		"token":         true,
+		"password":      true,
+		"authorization": true,
+		"apitoken":      true,
+	}
+)
+
+// setupLogging installs a JSON slog logger compatible with Cloud Logging as the default logger.
+// LOG_LEVEL selects the minimum level (debug, info, warn, error) and LOG_REDACT=false disables
+// redaction of emails and tokens for local debugging.
+func setupLogging() {
+	level := slog.LevelInfo
+	switch strings.ToLower(getEnv("LOG_LEVEL", "info")) {
+	case "debug":
+		level = slog.LevelDebug
+	case "warn", "warning":
+		level = slog.LevelWarn
+	case "error":
+		level = slog.LevelError
+	}
+
+	redact := getEnv("LOG_REDACT", "true") != "false"
+
+	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 {
+				switch a.Key {
+				case slog.LevelKey:
+					return slog.String("severity", cloudSeverity(a.Value.Any().(slog.Level)))
+				case slog.MessageKey:
+					a.Key = "message"
+				}
+			}
+			if redact {
+				a = redactAttr(a)
+			}
+			return a
+		},
+	})
+
+	slog.SetDefault(slog.New(contextHandler{handler}))
+}
+
+// fatal logs an error and exits, replacing log.Fatal during startup
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// cloudSeverity maps slog levels to Cloud Logging severity names
+func cloudSeverity(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return "ERROR"
+	case level >= slog.LevelWarn:
+		return "WARNING"
+	case level >= slog.LevelInfo:
+		return "INFO"
+	default:
+		return "DEBUG"
+	}
+}
+
+// redactAttr masks emails and tokens in attribute values
+func redactAttr(a slog.Attr) slog.Attr {
+	if sensitiveLogKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, "[REDACTED]")
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, redactString(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, redactString(err.Error()))
+		}
+	}
+	return a
+}
+
+// redactString replaces email addresses and bearer tokens in free-form text
+func redactString(s string) string {
+	s = tokenPattern.ReplaceAllString(s, "[REDACTED_TOKEN]")
+	return emailPattern.ReplaceAllString(s, "[REDACTED_EMAIL]")
+}
+
+// contextHandler adds the request ID and trace fields stored in the context to every record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := requestIDFromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("requestId", id))
+	}
+	if info := traceInfoFromContext(ctx); info.TraceID != "" {
+		if logProjectID != "" {
+			r.AddAttrs(slog.String(logTraceKey, fmt.Sprintf("projects/%s/traces/%s", logProjectID, info.TraceID)))
+		}
+		if info.SpanID != "" {
+			r.AddAttrs(slog.String(logSpanKey, info.SpanID))
+		}
+		r.AddAttrs(slog.Bool(logSampledKey, info.Sampled))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// requestIDFromContext returns the request ID attached by requestLogger, if any
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// traceInfoFromContext prefers the active OpenTelemetry span and falls back to the
+// X-Cloud-Trace-Context header captured by requestLogger
+func traceInfoFromContext(ctx context.Context) traceInfo {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		return traceInfo{
+			TraceID: sc.TraceID().String(),
+			SpanID:  sc.SpanID().String(),
+			Sampled: sc.IsSampled(),
+		}
+	}
+	info, _ := ctx.Value(traceInfoKey).(traceInfo)
+	return info
+}
+
+// newRequestID generates a random request ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// parseCloudTraceContext parses the X-Cloud-Trace-Context header (TRACE_ID/SPAN_ID;o=OPTIONS)
+func parseCloudTraceContext(header string) traceInfo {
+	var info traceInfo
+	if header == "" {
+		return info
+	}
+
+	traceAndSpan, options, _ := strings.Cut(header, ";")
+	info.TraceID, info.SpanID, _ = strings.Cut(traceAndSpan, "/")
+	info.Sampled = options == "o=1"
+	return info
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.size += n
+	return n, err
+}
+
+// requestLogger attaches a request ID and trace context to the request and logs its completion
+func requestLogger(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := r.Header.Get(requestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+
+		ctx := context.WithValue(r.Context(), requestIDKey, requestID)
+		ctx = context.WithValue(ctx, traceInfoKey, parseCloudTraceContext(r.Header.Get("X-Cloud-Trace-Context")))
+		r = r.WithContext(ctx)
+
+		rec := &statusRecorder{ResponseWriter: w}
+		handler.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		} else if rec.status >= http.StatusBadRequest {
+			level = slog.LevelWarn
+		}
+
+		slog.Log(ctx, level, "request completed",
+			slog.Group("httpRequest",
+				slog.String("requestMethod", r.Method),
+				slog.String("requestUrl", r.URL.Path),
+				slog.Int("status", rec.status),
+				slog.Int("responseSize", rec.size),
+				slog.String("userAgent", r.UserAgent()),
+				slog.String("latency", fmt.Sprintf("%.3fs", time.Since(start).Seconds())),
+			),
+		)
+	})
+}
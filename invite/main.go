@@ -1,22 +1,26 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"regexp"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
-	"firebase.google.com/go"
-	"firebase.google.com/go/auth"
-	"firebase.google.com/go/db"
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+	"firebase.google.com/go/v4/db"
+	"github.com/dhvogel/bookclurb-invite/hardcover"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/api/option"
 	gomail "gopkg.in/gomail.v2"
 )
@@ -30,11 +34,14 @@ var (
 	emailUser    string
 )
 
-const (
-	hardcoverAPIURL = "https://api.hardcover.app/v1/graphql"
-)
+var hardcoverClient *hardcover.Client
+
+// setup initializes logging, Firebase, email and the Hardcover client from the
+// environment. It runs from main rather than init so tests can build the package
+// without a Firebase project.
+func setup() {
+	setupLogging()
 
-func init() {
 	ctx := context.Background()
 
 	// Initialize Firebase Admin SDK
@@ -42,7 +49,7 @@ func init() {
 	// Get Firebase database URL from environment variable
 	databaseURL := getEnv("FIREBASE_DATABASE_URL", "")
 	if databaseURL == "" {
-		log.Fatal("FIREBASE_DATABASE_URL environment variable is required")
+		fatal("FIREBASE_DATABASE_URL environment variable is required")
 	}
 
 	// Get Firebase project ID (required to match the project that issued the tokens)
@@ -52,12 +59,14 @@ func init() {
 		// Extract project ID from database URL (format: https://PROJECT_ID-default-rtdb.firebaseio.com)
 		if matches := regexp.MustCompile(`https://([^-]+)-.*\.firebaseio\.com`).FindStringSubmatch(databaseURL); len(matches) > 1 {
 			firebaseProjectID = matches[1]
-			log.Printf("Auto-detected Firebase project ID from database URL: %s", firebaseProjectID)
+			slog.Info("Auto-detected Firebase project ID from database URL", "projectId", firebaseProjectID)
 		} else {
-			log.Fatal("FIREBASE_PROJECT_ID environment variable is required (or set FIREBASE_DATABASE_URL in correct format)")
+			fatal("FIREBASE_PROJECT_ID environment variable is required (or set FIREBASE_DATABASE_URL in correct format)")
 		}
 	}
 
+	logProjectID = getEnv("GOOGLE_CLOUD_PROJECT", firebaseProjectID)
+
 	// Build Firebase config with explicit project ID
 	firebaseConfig := &firebase.Config{
 		ProjectID:   firebaseProjectID,
@@ -74,19 +83,19 @@ func init() {
 	}
 
 	if err != nil {
-		log.Fatalf("Error initializing Firebase app: %v", err)
+		fatal("Error initializing Firebase app", "error", err)
 	}
 
 	// Initialize Firebase Auth
 	firebaseAuth, err = firebaseApp.Auth(ctx)
 	if err != nil {
-		log.Fatalf("Error initializing Firebase Auth: %v", err)
+		fatal("Error initializing Firebase Auth", "error", err)
 	}
 
 	// Initialize Firebase Realtime Database
 	firebaseDB, err = firebaseApp.Database(ctx)
 	if err != nil {
-		log.Fatalf("Error initializing Firebase Database: %v", err)
+		fatal("Error initializing Firebase Database", "error", err)
 	}
 
 	// Get email configuration from environment variables
@@ -94,14 +103,30 @@ func init() {
 	emailPassword := getEnv("EMAIL_PASSWORD", "")
 	baseURL = getEnv("BASE_URL", "")
 	if baseURL == "" {
-		log.Fatal("BASE_URL environment variable is required")
+		fatal("BASE_URL environment variable is required")
 	}
 
+	loadCORSConfig()
+	loadMetricsConfig()
+	loadAppCheckConfig()
+	loadEncryptionConfig()
+
+	// Initialize Hardcover client
+	hardcoverClient = hardcover.NewClient(getEnv("HARDCOVER_API_URL", hardcover.DefaultEndpoint))
+	hardcoverClient.Instrument = instrumentHardcover
+	// Record an HTTP client span per attempt and send traceparent to Hardcover
+	hardcoverClient.HTTPClient.Transport = otelhttp.NewTransport(hardcoverClient.HTTPClient.Transport)
+	hardcoverClient.SetRateLimits(
+		getEnvInt("HARDCOVER_TOKEN_RATE_LIMIT", hardcover.DefaultTokenRequestsPerMinute), hardcover.DefaultTokenBurst,
+		getEnvInt("HARDCOVER_GLOBAL_RATE_LIMIT", 0), getEnvInt("HARDCOVER_GLOBAL_BURST", 20),
+	)
+	loadBookCacheConfig()
+
 	// Initialize email sender
 	if emailUser != "" && emailPassword != "" {
 		mailer = gomail.NewDialer("smtp.gmail.com", 587, emailUser, emailPassword)
 	} else {
-		log.Println("Warning: Email credentials not set. Email sending will fail.")
+		slog.Warn("Email credentials not set. Email sending will fail.")
 	}
 }
 
@@ -113,13 +138,36 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvInt reads an integer environment variable, exiting if it is not a number
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		fatal("Invalid integer environment variable", "key", key, "value", value)
+	}
+	return n
+}
+
 // InviteRequest represents the incoming request data
 type InviteRequest struct {
 	Email       string `json:"email"`
 	ClubID      string `json:"clubId"`
 	ClubName    string `json:"clubName"`
 	InviterName string `json:"inviterName"`
-	InviteID    string `json:"inviteId"` // Optional: ID from frontend to update status
+	InviteID    string `json:"inviteId"` // ID of the invite record the frontend created
+}
+
+func (req *InviteRequest) validate(v *validator) {
+	v.email("email", req.Email)
+	v.required("clubId", req.ClubID)
+	v.required("clubName", req.ClubName)
+	v.maxLength("clubName", req.ClubName, maxNameLength)
+	v.maxLength("inviterName", req.InviterName, maxNameLength)
+	// Required to generate the signup link
+	v.required("inviteId", req.InviteID)
 }
 
 // InviteResponse represents the response
@@ -137,172 +185,65 @@ type Member struct {
 
 // Club represents club data from Firebase
 type Club struct {
-	Members []Member `json:"members"`
-}
-
-// Hardcover API types
-type HardcoverGraphQLRequest struct {
-	Query     string                 `json:"query"`
-	Variables map[string]interface{} `json:"variables,omitempty"`
-}
-
-type HardcoverGraphQLResponse struct {
-	Data   map[string]interface{} `json:"data"`
-	Errors []struct {
-		Message string `json:"message"`
-	} `json:"errors,omitempty"`
-}
-
-type HardcoverMeData struct {
-	Me interface{} `json:"me"`
-}
-
-type HardcoverUser struct {
-	ID            interface{} `json:"id"`
-	Username      string      `json:"username"`
-	CachedImage   interface{} `json:"cached_image"`
-	CachedImageURL string     `json:"cachedImageUrl,omitempty"`
-}
-
-type HardcoverEdition struct {
-	ID   int `json:"id"`
-	Book struct {
-		ID int `json:"id"`
-	} `json:"book"`
+	Members     []Member  `json:"members"`
+	CurrentBook *ClubBook `json:"currentBook"`
 }
 
+// UserData is the service-managed part of a user record
 type UserData struct {
-	HardcoverApiToken string `json:"hardcoverApiToken"`
-}
-
-// corsHandler handles CORS for browser requests (minimal, Cloud Run IAM handles auth)
-func corsHandler(handler http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		origin := r.Header.Get("Origin")
-		
-		// Handle OPTIONS preflight request
-		if r.Method == http.MethodOptions {
-			if origin != "" {
-				w.Header().Set("Access-Control-Allow-Origin", origin)
-				w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-				w.Header().Set("Access-Control-Max-Age", "3600")
-			}
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		// Set CORS headers if origin is present (browser request)
-		if origin != "" {
-			w.Header().Set("Access-Control-Allow-Origin", origin)
-			w.Header().Set("Access-Control-Allow-Credentials", "true")
-		}
-		
-		// Security headers
-		w.Header().Set("X-Content-Type-Options", "nosniff")
-		w.Header().Set("X-Frame-Options", "DENY")
-		w.Header().Set("X-XSS-Protection", "1; mode=block")
-
-		handler(w, r)
-	}
+	HardcoverApiToken       string           `json:"hardcoverApiToken"` // legacy plaintext token
+	HardcoverTokenEncrypted *EncryptedSecret `json:"hardcoverTokenEncrypted,omitempty"`
+	HardcoverLink           *HardcoverLink   `json:"hardcoverLink,omitempty"`
 }
 
 // sendClubInvite handles the HTTP request to send club invites
 func sendClubInvite(w http.ResponseWriter, r *http.Request) {
 	// Only allow POST requests
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !requireMethod(w, r, http.MethodPost) {
 		return
 	}
 
 	// Parse the request body
 	var req InviteRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	ctx := r.Context()
-
-	// Require Firebase ID token for authentication
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		log.Printf("Authentication failed: Missing Authorization header")
-		http.Error(w, "Missing Authorization header", http.StatusUnauthorized)
-		return
-	}
-
-	// Extract token (assuming format: "Bearer <token>")
-	token := authHeader
-	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-		token = authHeader[7:]
-	}
-
-	if token == "" || token == authHeader {
-		log.Printf("Authentication failed: Invalid Authorization header format (length: %d)", len(authHeader))
-		http.Error(w, "Invalid Authorization header format", http.StatusUnauthorized)
-		return
+	// On /v1 routes the club ID comes from the path
+	if clubID := r.PathValue("clubId"); clubID != "" {
+		req.ClubID = clubID
 	}
 
-	log.Printf("Verifying Firebase token (token length: %d)", len(token))
-
-	// Verify the token and get user info
-	verifiedToken, err := firebaseAuth.VerifyIDToken(ctx, token)
-	if err != nil {
-		log.Printf("Firebase token verification failed: %v", err)
-		http.Error(w, fmt.Sprintf("Unauthorized: %v", err), http.StatusUnauthorized)
+	if !validateRequest(w, r, &req) {
 		return
 	}
 
-	log.Printf("Firebase token verified successfully for user: %s", verifiedToken.UID)
-
-	userID := verifiedToken.UID
-
-	// Validate input
-	if req.Email == "" || req.ClubID == "" || req.ClubName == "" {
-		http.Error(w, "Missing required fields: email, clubId, or clubName", http.StatusBadRequest)
-		return
-	}
+	ctx := r.Context()
+	principal := principalFromContext(ctx)
 
-	// Validate email format
-	emailRegex := regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`)
-	if !emailRegex.MatchString(req.Email) {
-		http.Error(w, "Invalid email address format", http.StatusBadRequest)
+	// Check if user is a verified admin of the club
+	slog.DebugContext(ctx, "Checking club admin", "uid", principal.UID, "clubId", req.ClubID)
+	if !authorize(w, r, requireVerifiedEmail(), requireClubRole(req.ClubID, roleAdmin)) {
 		return
 	}
 
-	// Check if user is admin of the club
-	log.Printf("Checking if user %s is admin of club %s", userID, req.ClubID)
-	clubRef := firebaseDB.NewRef(fmt.Sprintf("clubs/%s", req.ClubID))
-	var club Club
-	if err := clubRef.Get(ctx, &club); err != nil {
-		log.Printf("Failed to access club data: %v", err)
-		// Check if it's an auth error
-		if strings.Contains(err.Error(), "401") || strings.Contains(err.Error(), "Unauthorized") {
-			http.Error(w, fmt.Sprintf("Database access denied. Service account may not have permission to read Firebase Realtime Database. Error: %v", err), http.StatusInternalServerError)
-		} else {
-			http.Error(w, fmt.Sprintf("Club not found: %v", err), http.StatusNotFound)
-		}
+	// The invite must already exist (the frontend creates it) and be for the address
+	// being emailed, so the status updates below never create a record and the signup
+	// link can only be accepted by the invited address
+	inviteRef := firebaseDB.NewRef(fmt.Sprintf("club_invites/%s/%s", req.ClubID, req.InviteID))
+	var invite Invite
+	if err := firebaseGet(ctx, "club_invites", inviteRef, &invite); err != nil {
+		slog.ErrorContext(ctx, "Failed to load invite", "clubId", req.ClubID, "inviteId", req.InviteID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to load invite", nil)
 		return
 	}
-
-	// Check if user is admin
-	isAdmin := false
-	for _, member := range club.Members {
-		if member.ID == userID && member.Role == "admin" {
-			isAdmin = true
-			break
-		}
-	}
-
-	if !isAdmin {
-		http.Error(w, "Only admins can send invites", http.StatusForbidden)
+	if invite.Status == "" {
+		writeError(w, r, http.StatusNotFound, errCodeInviteNotFound, "Invite not found", nil)
 		return
 	}
-
-	// Require inviteId to generate the signup link
-	if req.InviteID == "" {
-		http.Error(w, "InviteID is required", http.StatusBadRequest)
+	if invite.Email == "" || !strings.EqualFold(invite.Email, req.Email) {
+		slog.InfoContext(ctx, "Invite email does not match request", "clubId", req.ClubID, "inviteId", req.InviteID)
+		writeError(w, r, http.StatusConflict, errCodeInviteEmailMismatch, "Email does not match the invite", nil)
 		return
 	}
 
@@ -312,7 +253,7 @@ func sendClubInvite(w http.ResponseWriter, r *http.Request) {
 
 	// Create email
 	if mailer == nil {
-		http.Error(w, "Email service not configured", http.StatusInternalServerError)
+		writeError(w, r, http.StatusServiceUnavailable, errCodeEmailUnavailable, "Email service not configured", nil)
 		return
 	}
 
@@ -327,21 +268,22 @@ func sendClubInvite(w http.ResponseWriter, r *http.Request) {
 	msg.AddAlternative("text/plain", emailText)
 
 	// Send email
-	if err := mailer.DialAndSend(msg); err != nil {
-		log.Printf("Error sending email: %v", err)
+	_, span := startClientSpan(ctx, "smtp.send", attribute.String("email.backend", "smtp"))
+	err := mailer.DialAndSend(msg)
+	endSpan(span, err)
+	recordEmailSend("smtp", err)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error sending invite email", "clubId", req.ClubID, "inviteId", req.InviteID, "error", err)
 		
-		// Update invite status to 'failed' if inviteId was provided
-		if req.InviteID != "" {
-			updateInviteStatus(ctx, req.ClubID, req.InviteID, "failed", err.Error())
-		}
+		updateInviteStatus(ctx, req.ClubID, req.InviteID, "failed", err.Error())
 		
-		http.Error(w, fmt.Sprintf("Failed to send invite email: %v", err), http.StatusInternalServerError)
+		writeError(w, r, http.StatusBadGateway, errCodeEmailFailed, "Failed to send invite email", nil)
 		return
 	}
 
 	// Update invite status to 'sent' (invite was already created by frontend)
 	updateInviteStatus(ctx, req.ClubID, req.InviteID, "sent", "")
-	log.Printf("Updated invite %s status to sent", req.InviteID)
+	slog.InfoContext(ctx, "Invite sent", "clubId", req.ClubID, "inviteId", req.InviteID)
 
 	// Return success response
 	response := InviteResponse{
@@ -349,9 +291,7 @@ func sendClubInvite(w http.ResponseWriter, r *http.Request) {
 		Message: "Invite sent successfully",
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	writeJSON(w, http.StatusOK, response)
 }
 
 // generateEmailHTML generates the HTML email template
@@ -436,7 +376,8 @@ If you didn't expect this invite, you can safely ignore this email.
 Happy reading!`, clubName, inviterName, clubName, signupLink)
 }
 
-// updateInviteStatus updates the status of an invite in Firebase
+// updateInviteStatus updates the status of an invite in Firebase. Callers must have
+// checked that the invite exists, since an update would otherwise create it.
 func updateInviteStatus(ctx context.Context, clubID, inviteID, status, errorMsg string) {
 	inviteRef := firebaseDB.NewRef(fmt.Sprintf("club_invites/%s/%s", clubID, inviteID))
 	updates := map[string]interface{}{
@@ -449,8 +390,8 @@ func updateInviteStatus(ctx context.Context, clubID, inviteID, status, errorMsg
 	if errorMsg != "" {
 		updates["error"] = errorMsg
 	}
-	if err := inviteRef.Update(ctx, updates); err != nil {
-		log.Printf("Warning: Failed to update invite status: %v", err)
+	if err := firebaseUpdate(ctx, "club_invites", inviteRef, updates); err != nil {
+		slog.WarnContext(ctx, "Failed to update invite status", "clubId", clubID, "inviteId", inviteID, "error", err)
 	}
 }
 
@@ -474,6 +415,11 @@ type ValidateInviteRequest struct {
 	ClubID   string `json:"clubId"`
 }
 
+func (req *ValidateInviteRequest) validate(v *validator) {
+	v.required("inviteId", req.InviteID)
+	v.required("clubId", req.ClubID)
+}
+
 // ValidateInviteResponse represents the response from validation
 type ValidateInviteResponse struct {
 	Valid     bool   `json:"valid"`
@@ -484,331 +430,132 @@ type ValidateInviteResponse struct {
 	Email     string `json:"email,omitempty"`
 }
 
-// Helper function to extract Firebase token from Authorization header
-func extractFirebaseToken(r *http.Request) (string, error) {
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		return "", fmt.Errorf("missing Authorization header")
-	}
-
-	token := authHeader
-	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-		token = authHeader[7:]
-	}
-
-	if token == "" || token == authHeader {
-		return "", fmt.Errorf("invalid Authorization header format")
-	}
+// errHardcoverNotLinked is returned when the user has no Hardcover token saved
+var errHardcoverNotLinked = errors.New("hardcover token not found for user")
 
-	return token, nil
-}
+// errHardcoverTokenUnavailable wraps failures to read or decrypt a saved Hardcover token
+var errHardcoverTokenUnavailable = errors.New("failed to load Hardcover token")
 
-// Helper function to verify Firebase token and get user ID
-func verifyFirebaseToken(ctx context.Context, token string) (string, error) {
-	verifiedToken, err := firebaseAuth.VerifyIDToken(ctx, token)
-	if err != nil {
-		return "", fmt.Errorf("token verification failed: %v", err)
-	}
-	return verifiedToken.UID, nil
+// hardcoverTokenAAD binds an encrypted Hardcover token to its user
+func hardcoverTokenAAD(userID string) string {
+	return fmt.Sprintf("users/%s/hardcoverTokenEncrypted", userID)
 }
 
-// Helper function to get Hardcover token from Firebase for a user
+// Helper function to get Hardcover token from Firebase for a user. Encrypted tokens
+// are decrypted, and re-encrypted if they were written with a rotated-out key.
 func getHardcoverToken(ctx context.Context, userID string) (string, error) {
 	userRef := firebaseDB.NewRef(fmt.Sprintf("users/%s", userID))
 	var userData UserData
-	if err := userRef.Get(ctx, &userData); err != nil {
+	if err := firebaseGet(ctx, "users", userRef, &userData); err != nil {
 		return "", fmt.Errorf("failed to get user data: %v", err)
 	}
-	if userData.HardcoverApiToken == "" {
-		return "", fmt.Errorf("hardcover token not found for user")
-	}
-	return userData.HardcoverApiToken, nil
-}
 
-// Helper function to clean a token by removing "Bearer " prefix if present
-func cleanHardcoverToken(token string) string {
-	return strings.TrimPrefix(strings.TrimSpace(token), "Bearer ")
-}
-
-// Make a GraphQL request to the Hardcover API
-func makeHardcoverRequest(ctx context.Context, token string, query string, variables map[string]interface{}) (*HardcoverGraphQLResponse, error) {
-	cleanToken := cleanHardcoverToken(token)
-
-	reqBody := HardcoverGraphQLRequest{
-		Query:     query,
-		Variables: variables,
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %v", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", hardcoverAPIURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+	if userData.HardcoverTokenEncrypted != nil {
+		token, stale, err := decryptSecret(userData.HardcoverTokenEncrypted, hardcoverTokenAAD(userID))
+		if err != nil {
+			return "", fmt.Errorf("failed to decrypt hardcover token: %w", err)
+		}
+		if stale {
+			if err := storeHardcoverToken(ctx, userID, token); err != nil {
+				slog.WarnContext(ctx, "Failed to re-encrypt Hardcover token", "uid", userID, "error", err)
+			}
+		}
+		return token, nil
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", cleanToken))
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %v", err)
+	// Tokens saved by the browser before encryption was introduced are encrypted, and
+	// the plaintext copy cleared, the first time they are read
+	if userData.HardcoverApiToken == "" {
+		return "", errHardcoverNotLinked
 	}
-	defer resp.Body.Close()
+	migrateLegacyHardcoverToken(ctx, userID, userData.HardcoverApiToken)
+	return userData.HardcoverApiToken, nil
+}
 
-	body, err := io.ReadAll(resp.Body)
+// storeHardcoverToken encrypts and saves a user's Hardcover token, removing any
+// plaintext copy. extra fields are written in the same update.
+func storeHardcoverToken(ctx context.Context, userID, token string, extra ...map[string]interface{}) error {
+	secret, err := encryptSecret(token, hardcoverTokenAAD(userID))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %v", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+		return err
 	}
-
-	var result HardcoverGraphQLResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %v", err)
+	updates := map[string]interface{}{
+		"hardcoverTokenEncrypted": secret,
+		"hardcoverApiToken":       nil,
 	}
-
-	if len(result.Errors) > 0 {
-		errorMessages := make([]string, len(result.Errors))
-		for i, e := range result.Errors {
-			errorMessages[i] = e.Message
+	for _, fields := range extra {
+		for key, value := range fields {
+			updates[key] = value
 		}
-		return nil, fmt.Errorf("graphql errors: %s", strings.Join(errorMessages, ", "))
 	}
+	userRef := firebaseDB.NewRef(fmt.Sprintf("users/%s", userID))
+	return firebaseUpdate(ctx, "users", userRef, updates)
+}
 
-	return &result, nil
+// Helper function to clean a token by removing "Bearer " prefix if present
+func cleanHardcoverToken(token string) string {
+	return strings.TrimPrefix(strings.TrimSpace(token), "Bearer ")
 }
 
 // Test if a Hardcover API token is valid and get user info
-func testHardcoverToken(ctx context.Context, token string) (map[string]interface{}, error) {
-	query := `
-		query {
-			me {
-				id
-				username
-				cached_image
-			}
-		}
-	`
-
-	result, err := makeHardcoverRequest(ctx, token, query, nil)
+func testHardcoverToken(ctx context.Context, token string) (*TestHardcoverTokenResponse, error) {
+	user, err := hardcoverClient.Me(ctx, token)
 	if err != nil {
 		return nil, err
 	}
 
-	meData, ok := result.Data["me"]
-	if !ok {
-		return nil, fmt.Errorf("unexpected response format: no 'me' field")
-	}
-
-	// Handle both array and object formats
-	var userInfo map[string]interface{}
-	if meArray, ok := meData.([]interface{}); ok && len(meArray) > 0 {
-		if userMap, ok := meArray[0].(map[string]interface{}); ok {
-			userInfo = userMap
-		}
-	} else if userMap, ok := meData.(map[string]interface{}); ok {
-		userInfo = userMap
-	}
-
-	if userInfo == nil {
-		return nil, fmt.Errorf("unexpected response format")
-	}
-
-	// Extract cached_image URL if present
-	cachedImageURL := ""
-	if cachedImage, ok := userInfo["cached_image"]; ok {
-		if imgMap, ok := cachedImage.(map[string]interface{}); ok {
-			if url, ok := imgMap["url"].(string); ok {
-				cachedImageURL = url
-			}
-		}
-	}
-
-	response := map[string]interface{}{
-		"valid": true,
-		"user": map[string]interface{}{
-			"id":            fmt.Sprintf("%v", userInfo["id"]),
-			"username":      userInfo["username"],
-			"cachedImageUrl": cachedImageURL,
+	return &TestHardcoverTokenResponse{
+		Valid: true,
+		User: HardcoverTokenUser{
+			ID:             strconv.Itoa(user.ID),
+			Username:       user.Username,
+			CachedImageURL: user.CachedImage.URL,
 		},
-	}
-
-	return response, nil
+	}, nil
 }
 
-// Lookup book by ISBN to get Hardcover book ID
-func lookupBookByIsbn(ctx context.Context, token string, isbn string) (int, error) {
-	// Normalize ISBN: remove hyphens and spaces
-	normalizedIsbn := regexp.MustCompile(`[-\s]`).ReplaceAllString(isbn, "")
-	isbnLength := len(normalizedIsbn)
-
-	// Determine which field(s) to try based on ISBN length
-	var isbnFields []string
-	if isbnLength == 10 {
-		isbnFields = []string{"isbn_10"}
-	} else if isbnLength == 13 {
-		isbnFields = []string{"isbn_13"}
-	} else {
-		isbnFields = []string{"isbn_13", "isbn_10"}
-	}
-
-	// Try the appropriate ISBN field(s)
-	for _, isbnField := range isbnFields {
-		query := fmt.Sprintf(`
-			query LookupBook($isbn: String!) {
-				editions(where: {%s: {_eq: $isbn}}) {
-					id
-					book {
-						id
-					}
-				}
-			}
-		`, isbnField)
-
-		result, err := makeHardcoverRequest(ctx, token, query, map[string]interface{}{
-			"isbn": normalizedIsbn,
-		})
-
-		if err != nil {
-			log.Printf("Error with %s field: %v", isbnField, err)
-			continue
-		}
-
-		editionsData, ok := result.Data["editions"]
-		if !ok {
-			continue
-		}
-
-		var editions []HardcoverEdition
-		if editionsArray, ok := editionsData.([]interface{}); ok {
-			for _, ed := range editionsArray {
-				if edMap, ok := ed.(map[string]interface{}); ok {
-					var edition HardcoverEdition
-					if id, ok := edMap["id"].(float64); ok {
-						edition.ID = int(id)
-					}
-					if book, ok := edMap["book"].(map[string]interface{}); ok {
-						if bookID, ok := book["id"].(float64); ok {
-							edition.Book.ID = int(bookID)
-						}
-					}
-					if edition.Book.ID > 0 {
-						editions = append(editions, edition)
-					}
-				}
-			}
-		}
-
-		if len(editions) > 0 && editions[0].Book.ID > 0 {
-			return editions[0].Book.ID, nil
-		}
-	}
-
-	// Try _or query as fallback
-	query := `
-		query LookupBook($isbn: String!) {
-			editions(where: {_or: [{isbn_13: {_eq: $isbn}}, {isbn_10: {_eq: $isbn}}]}) {
-				id
-				book {
-					id
-				}
-			}
-		}
-	`
-
-	result, err := makeHardcoverRequest(ctx, token, query, map[string]interface{}{
-		"isbn": normalizedIsbn,
-	})
-
-	if err == nil {
-		if editionsData, ok := result.Data["editions"]; ok {
-			if editionsArray, ok := editionsData.([]interface{}); ok && len(editionsArray) > 0 {
-				if edMap, ok := editionsArray[0].(map[string]interface{}); ok {
-					if book, ok := edMap["book"].(map[string]interface{}); ok {
-						if bookID, ok := book["id"].(float64); ok {
-							return int(bookID), nil
-						}
-					}
-				}
-			}
-		}
-	}
+// Actions reported for a sync: whether the user_book was added or already on the shelf
+const (
+	syncActionCreated = "created"
+	syncActionUpdated = "updated"
+)
 
-	return 0, fmt.Errorf("book not found in Hardcover by ISBN")
+// ratingSync is the outcome of syncRatingToHardcover
+type ratingSync struct {
+	bookID     int    // the Hardcover book, set even if the user_book update failed
+	userBookID int    // the user's shelf entry for it
+	action     string // syncActionCreated or syncActionUpdated
 }
 
-// Sync rating and review to Hardcover
-func syncRatingToHardcover(ctx context.Context, token string, isbn string, rating float64, reviewText string) error {
-	// Step 1: Lookup book by ISBN
-	bookID, err := lookupBookByIsbn(ctx, token, isbn)
+// Sync rating and review to Hardcover, with the review privacy and spoiler flag from settings
+func syncRatingToHardcover(ctx context.Context, token string, book bookRef, rating float64, reviewText string, settings HardcoverSyncSettings) (ratingSync, error) {
+	// Step 1: Lookup book by ISBN, falling back to title and author
+	bookID, err := resolveHardcoverBook(ctx, token, book)
 	if err != nil {
-		return fmt.Errorf("book lookup failed: %v", err)
-	}
-
-	// Step 2: Create user_book relationship with rating, review (if provided), status_id: 3, and read_count: 1
-	var query string
-	var variables map[string]interface{}
-
-	if reviewText != "" {
-		query = `
-			mutation CreateUserBook($bookId: Int!, $rating: numeric!, $review: String!) {
-				insert_user_book(object: {book_id: $bookId, rating: $rating, review: $review, status_id: 3, read_count: 1}) {
-					id
-				}
-			}
-		`
-		variables = map[string]interface{}{
-			"bookId": bookID,
-			"rating": rating,
-			"review": reviewText,
-		}
-	} else {
-		query = `
-			mutation CreateUserBook($bookId: Int!, $rating: numeric!) {
-				insert_user_book(object: {book_id: $bookId, rating: $rating, status_id: 3, read_count: 1}) {
-					id
-				}
-			}
-		`
-		variables = map[string]interface{}{
-			"bookId": bookID,
-			"rating": rating,
-		}
-	}
-
-	result, err := makeHardcoverRequest(ctx, token, query, variables)
+		return ratingSync{}, fmt.Errorf("book lookup failed: %w", err)
+	}
+
+	// Step 2: Update the user's existing user_book, or create one with status read and read_count: 1
+	input := hardcover.UserBookInput{
+		BookID:    bookID,
+		Rating:    rating,
+		Review:    reviewText,
+		StatusID:  hardcover.StatusRead,
+		ReadCount: 1,
+	}
+	settings.apply(&input)
+	// Zero fields are left as they are on Hardcover, so a rating-only sync has to clear
+	// a review synced before the setting was turned on
+	input.ClearReview = settings.RatingOnly
+	userBookID, created, err := hardcoverClient.UpsertUserBook(ctx, token, input)
 	if err != nil {
-		// If error is about duplicate/unique constraint, that's okay
-		errStr := err.Error()
-		if strings.Contains(errStr, "already exists") || strings.Contains(errStr, "duplicate") || strings.Contains(errStr, "unique") {
-			return nil
-		}
-		return err
+		return ratingSync{bookID: bookID}, err
 	}
-
-	// Check if creation was successful
-	if insertData, ok := result.Data["insert_user_book"]; ok {
-		var userBookID interface{}
-		if insertArray, ok := insertData.([]interface{}); ok && len(insertArray) > 0 {
-			if userBookMap, ok := insertArray[0].(map[string]interface{}); ok {
-				userBookID = userBookMap["id"]
-			}
-		} else if userBookMap, ok := insertData.(map[string]interface{}); ok {
-			userBookID = userBookMap["id"]
-		}
-
-		if userBookID != nil {
-			return nil
-		}
+	result := ratingSync{bookID: bookID, userBookID: userBookID, action: syncActionUpdated}
+	if created {
+		result.action = syncActionCreated
 	}
-
-	return fmt.Errorf("failed to create user_book relationship")
+	return result, nil
 }
 
 // TestHardcoverTokenRequest represents the request to test a Hardcover token
@@ -818,255 +565,237 @@ type TestHardcoverTokenRequest struct {
 
 // TestHardcoverTokenResponse represents the response from testing a token
 type TestHardcoverTokenResponse struct {
-	Valid bool                   `json:"valid"`
-	User  map[string]interface{} `json:"user,omitempty"`
-	Error string                 `json:"error,omitempty"`
+	Valid bool               `json:"valid"`
+	User  HardcoverTokenUser `json:"user"`
+}
+
+// HardcoverTokenUser is the Hardcover account a token belongs to
+type HardcoverTokenUser struct {
+	ID             string `json:"id"`
+	Username       string `json:"username"`
+	CachedImageURL string `json:"cachedImageUrl"`
 }
 
 // testHardcoverTokenHandler handles the HTTP request to test a Hardcover token
 func testHardcoverTokenHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !requireMethod(w, r, http.MethodPost) {
 		return
 	}
 
 	var req TestHardcoverTokenRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
-		return
-	}
-
-	if req.Token == "" {
-		http.Error(w, "Token is required", http.StatusBadRequest)
+	if !decodeJSON(w, r, &req) || !validateRequest(w, r, &req) {
 		return
 	}
 
 	ctx := r.Context()
 	result, err := testHardcoverToken(ctx, req.Token)
 	if err != nil {
-		response := TestHardcoverTokenResponse{
-			Valid: false,
-			Error: err.Error(),
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(response)
+		slog.InfoContext(ctx, "Hardcover token test failed", "error", err)
+		writeHardcoverError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(result)
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (req *TestHardcoverTokenRequest) validate(v *validator) {
+	v.required("token", req.Token)
+	v.maxLength("token", req.Token, maxTokenLength)
 }
 
 // SyncRatingRequest represents the request to sync a rating to Hardcover
 type SyncRatingRequest struct {
-	ISBN      string  `json:"isbn"`
+	ISBN      string  `json:"isbn,omitempty"` // optional if title or hardcoverBookId is given
 	Rating    float64 `json:"rating"`
 	ReviewText string `json:"reviewText,omitempty"`
+	ClubID    string  `json:"clubId,omitempty"` // the club the book was read in, whose sync settings also apply
+	BookMatch
+}
+
+func (req *SyncRatingRequest) validate(v *validator) {
+	req.BookMatch.validate(v, req.ISBN)
+	v.rating("rating", req.Rating)
+	v.maxLength("reviewText", req.ReviewText, maxReviewTextLength)
+	v.maxLength("clubId", req.ClubID, maxIDLength)
 }
 
 // SyncRatingResponse represents the response from syncing a rating
 type SyncRatingResponse struct {
 	Success bool   `json:"success"`
-	Error   string `json:"error,omitempty"`
+	Action  string `json:"action"` // "created" or "updated", or "skipped" if only ratings are synced and there is none
 }
 
 // syncRatingToHardcoverHandler handles the HTTP request to sync a rating to Hardcover
 func syncRatingToHardcoverHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !requireMethod(w, r, http.MethodPost) {
 		return
 	}
 
-	ctx := r.Context()
-
-	// Verify Firebase token
-	firebaseToken, err := extractFirebaseToken(r)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+	var req SyncRatingRequest
+	if !decodeJSON(w, r, &req) || !validateRequest(w, r, &req) {
 		return
 	}
 
-	userID, err := verifyFirebaseToken(ctx, firebaseToken)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
-		return
-	}
+	ctx := r.Context()
+	userID := principalFromContext(ctx).UID
 
 	// Get Hardcover token from Firebase
 	hardcoverToken, err := getHardcoverToken(ctx, userID)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Hardcover token not found: %v", err), http.StatusBadRequest)
+	if errors.Is(err, errHardcoverNotLinked) {
+		writeError(w, r, http.StatusBadRequest, errCodeHardcoverNotLinked, "No Hardcover account linked", nil)
 		return
-	}
-
-	var req SyncRatingRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Failed to load Hardcover token", "uid", userID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to load Hardcover account", nil)
 		return
 	}
 
-	if req.ISBN == "" {
-		http.Error(w, "ISBN is required", http.StatusBadRequest)
+	settings, err := loadSyncSettings(ctx, userID, req.ClubID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load Hardcover sync settings", "uid", userID, "clubId", req.ClubID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to load Hardcover sync settings", nil)
 		return
 	}
-
-	if req.Rating < 0 || req.Rating > 5 {
-		http.Error(w, "Rating must be between 0 and 5", http.StatusBadRequest)
+	if settings.RatingOnly && req.Rating == 0 {
+		writeJSON(w, http.StatusOK, SyncRatingResponse{Success: true, Action: memberSyncSkipped})
 		return
 	}
 
-	err = syncRatingToHardcover(ctx, hardcoverToken, req.ISBN, req.Rating, req.ReviewText)
+	attempt, err := syncAndRecord(ctx, userID, hardcoverToken, SyncAttempt{
+		Source: syncSourceRating,
+		ClubID: req.ClubID,
+		Rating: req.Rating,
+	}, req.BookMatch.ref(req.ISBN), req.ReviewText, settings)
 	if err != nil {
-		response := SyncRatingResponse{
-			Success: false,
-			Error:   err.Error(),
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(response)
+		slog.WarnContext(ctx, "Hardcover sync failed", "uid", userID, "isbn", req.ISBN, "error", err)
+		writeHardcoverError(w, r, err)
 		return
 	}
 
 	response := SyncRatingResponse{
 		Success: true,
+		Action:  attempt.Action,
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	writeJSON(w, http.StatusOK, response)
 }
 
 // SyncReviewRequest represents the request to sync a review to Hardcover
 type SyncReviewRequest struct {
-	ISBN      string  `json:"isbn"`
+	ISBN      string  `json:"isbn,omitempty"` // optional if title or hardcoverBookId is given
 	ReviewText string `json:"reviewText"`
 	Rating    float64 `json:"rating"`
+	ClubID    string  `json:"clubId,omitempty"` // the club the book was read in, whose sync settings also apply
+	BookMatch
+}
+
+func (req *SyncReviewRequest) validate(v *validator) {
+	req.BookMatch.validate(v, req.ISBN)
+	v.required("reviewText", req.ReviewText)
+	v.maxLength("reviewText", req.ReviewText, maxReviewTextLength)
+	v.rating("rating", req.Rating)
+	v.maxLength("clubId", req.ClubID, maxIDLength)
 }
 
 // SyncReviewResponse represents the response from syncing a review
 type SyncReviewResponse struct {
 	Success bool   `json:"success"`
-	Error   string `json:"error,omitempty"`
+	Action  string `json:"action"` // "created" or "updated", or "skipped" if only ratings are synced and there is none
 }
 
 // syncReviewToHardcoverHandler handles the HTTP request to sync a review to Hardcover
 func syncReviewToHardcoverHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !requireMethod(w, r, http.MethodPost) {
 		return
 	}
 
-	ctx := r.Context()
-
-	// Verify Firebase token
-	firebaseToken, err := extractFirebaseToken(r)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+	var req SyncReviewRequest
+	if !decodeJSON(w, r, &req) || !validateRequest(w, r, &req) {
 		return
 	}
 
-	userID, err := verifyFirebaseToken(ctx, firebaseToken)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
-		return
-	}
+	ctx := r.Context()
+	userID := principalFromContext(ctx).UID
 
 	// Get Hardcover token from Firebase
 	hardcoverToken, err := getHardcoverToken(ctx, userID)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Hardcover token not found: %v", err), http.StatusBadRequest)
-		return
-	}
-
-	var req SyncReviewRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+	if errors.Is(err, errHardcoverNotLinked) {
+		writeError(w, r, http.StatusBadRequest, errCodeHardcoverNotLinked, "No Hardcover account linked", nil)
 		return
-	}
-
-	if req.ISBN == "" {
-		http.Error(w, "ISBN is required", http.StatusBadRequest)
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Failed to load Hardcover token", "uid", userID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to load Hardcover account", nil)
 		return
 	}
 
-	if req.ReviewText == "" {
-		http.Error(w, "Review text is required", http.StatusBadRequest)
+	settings, err := loadSyncSettings(ctx, userID, req.ClubID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load Hardcover sync settings", "uid", userID, "clubId", req.ClubID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to load Hardcover sync settings", nil)
 		return
 	}
-
-	if req.Rating < 0 || req.Rating > 5 {
-		http.Error(w, "Rating must be between 0 and 5", http.StatusBadRequest)
+	if settings.RatingOnly && req.Rating == 0 {
+		writeJSON(w, http.StatusOK, SyncReviewResponse{Success: true, Action: memberSyncSkipped})
 		return
 	}
 
-	err = syncRatingToHardcover(ctx, hardcoverToken, req.ISBN, req.Rating, req.ReviewText)
+	attempt, err := syncAndRecord(ctx, userID, hardcoverToken, SyncAttempt{
+		Source: syncSourceReview,
+		ClubID: req.ClubID,
+		Rating: req.Rating,
+	}, req.BookMatch.ref(req.ISBN), req.ReviewText, settings)
 	if err != nil {
-		response := SyncReviewResponse{
-			Success: false,
-			Error:   err.Error(),
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(response)
+		slog.WarnContext(ctx, "Hardcover sync failed", "uid", userID, "isbn", req.ISBN, "error", err)
+		writeHardcoverError(w, r, err)
 		return
 	}
 
 	response := SyncReviewResponse{
 		Success: true,
+		Action:  attempt.Action,
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	writeJSON(w, http.StatusOK, response)
 }
 
 // validateInvite handles the HTTP request to validate an invite ID
 func validateInvite(w http.ResponseWriter, r *http.Request) {
-	// Only allow POST requests
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	// GET on /v1 routes, POST on the legacy route
+	if !requireMethod(w, r, http.MethodGet, http.MethodPost) {
 		return
 	}
 
-	// Parse the request body
 	var req ValidateInviteRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+	if r.Method == http.MethodGet {
+		req.ClubID = r.PathValue("clubId")
+		req.InviteID = r.PathValue("inviteId")
+	} else if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	ctx := r.Context()
-
-	// Validate input
-	if req.InviteID == "" || req.ClubID == "" {
-		http.Error(w, "Missing required fields: inviteId or clubId", http.StatusBadRequest)
+	if !validateRequest(w, r, &req) {
 		return
 	}
 
+	ctx := r.Context()
+
 	// Look up the invite in Firebase
 	inviteRef := firebaseDB.NewRef(fmt.Sprintf("club_invites/%s/%s", req.ClubID, req.InviteID))
 	var invite Invite
-	if err := inviteRef.Get(ctx, &invite); err != nil {
-		log.Printf("Invite not found: %v", err)
-		response := ValidateInviteResponse{
-			Valid:   false,
-			Message: "Invite not found",
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(response)
+	if err := firebaseGet(ctx, "club_invites", inviteRef, &invite); err != nil {
+		slog.ErrorContext(ctx, "Failed to load invite", "clubId", req.ClubID, "inviteId", req.InviteID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to load invite", nil)
+		return
+	}
+
+	// Missing records decode as an empty invite
+	if invite.Status == "" {
+		slog.InfoContext(ctx, "Invite not found", "clubId", req.ClubID, "inviteId", req.InviteID)
+		writeError(w, r, http.StatusNotFound, errCodeInviteNotFound, "Invite not found", nil)
 		return
 	}
 
 	// Check if invite is active (status must be "sent")
 	if invite.Status != "sent" {
-		response := ValidateInviteResponse{
-			Valid:   false,
-			Message: fmt.Sprintf("Invite is not active. Status: %s", invite.Status),
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(response)
+		writeError(w, r, http.StatusConflict, errCodeInviteInactive, "Invite is not active", map[string]string{"status": invite.Status})
 		return
 	}
 
@@ -1080,42 +809,128 @@ func validateInvite(w http.ResponseWriter, r *http.Request) {
 		Email:       invite.Email,
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	writeJSON(w, http.StatusOK, response)
+}
+
+// AcceptInviteResponse represents the response from accepting an invite
+type AcceptInviteResponse struct {
+	Success bool   `json:"success"`
+	ClubID  string `json:"clubId"`
+}
+
+// acceptClubInvite adds the signed-in user to the club of an active invite. The caller
+// must have verified the email address the invite was sent to.
+func acceptClubInvite(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	principal := principalFromContext(ctx)
+	clubID := r.PathValue("clubId")
+	inviteID := r.PathValue("inviteId")
+
+	if !authorize(w, r, requireVerifiedEmail()) {
+		return
+	}
+
+	inviteRef := firebaseDB.NewRef(fmt.Sprintf("club_invites/%s/%s", clubID, inviteID))
+	var invite Invite
+	if err := firebaseGet(ctx, "club_invites", inviteRef, &invite); err != nil {
+		slog.ErrorContext(ctx, "Failed to load invite", "clubId", clubID, "inviteId", inviteID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to load invite", nil)
+		return
+	}
+	if invite.Status == "" {
+		writeError(w, r, http.StatusNotFound, errCodeInviteNotFound, "Invite not found", nil)
+		return
+	}
+	if invite.Status != "sent" {
+		writeError(w, r, http.StatusConflict, errCodeInviteInactive, "Invite is not active", map[string]string{"status": invite.Status})
+		return
+	}
+	// Invites without an email cannot be matched to the caller, so they are rejected
+	// rather than accepted by anyone holding the link
+	if invite.Email == "" || !strings.EqualFold(invite.Email, principal.Email) {
+		slog.InfoContext(ctx, "Invite email does not match caller", "uid", principal.UID, "clubId", clubID, "inviteId", inviteID)
+		writeError(w, r, http.StatusForbidden, errCodeForbidden, "This invite was sent to a different email address", nil)
+		return
+	}
+
+	if err := addClubMember(ctx, clubID, memberFromPrincipal(principal)); err != nil {
+		slog.ErrorContext(ctx, "Failed to add club member", "uid", principal.UID, "clubId", clubID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, errCodeInternal, "Failed to join club", nil)
+		return
+	}
+
+	if err := firebaseUpdate(ctx, "club_invites", inviteRef, map[string]interface{}{
+		"status":     "accepted",
+		"acceptedAt": time.Now().UnixMilli(),
+		"acceptedBy": principal.UID,
+		"updatedAt":  time.Now().Unix(),
+	}); err != nil {
+		slog.WarnContext(ctx, "Failed to mark invite accepted", "clubId", clubID, "inviteId", inviteID, "error", err)
+	}
+
+	slog.InfoContext(ctx, "Invite accepted", "uid", principal.UID, "clubId", clubID, "inviteId", inviteID)
+	writeJSON(w, http.StatusOK, AcceptInviteResponse{Success: true, ClubID: clubID})
 }
 
 func main() {
+	setup()
+
 	// Use PORT environment variable, or default to 8080
 	port := "8080"
 	if p := os.Getenv("PORT"); p != "" {
 		port = p
 	}
 
-	// Setup HTTP routes with CORS protection
-	http.HandleFunc("/SendClubInvite", corsHandler(sendClubInvite))
-	http.HandleFunc("/ValidateInvite", corsHandler(validateInvite))
-	
-	// TODO: Move Hardcover integration to its own dedicated service with API gateway
-	// This will improve separation of concerns, allow independent scaling, and provide
-	// better rate limiting and monitoring capabilities for the Hardcover API integration.
-	http.HandleFunc("/TestHardcoverToken", corsHandler(testHardcoverTokenHandler))
-	http.HandleFunc("/SyncRatingToHardcover", corsHandler(syncRatingToHardcoverHandler))
-	http.HandleFunc("/SyncReviewToHardcover", corsHandler(syncReviewToHardcoverHandler))
-	
-	// Health check endpoint for Cloud Run
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/" {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("OK"))
-			return
-		}
-		http.NotFound(w, r)
-	})
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := setupTracing(ctx)
+	if err != nil {
+		fatal("Failed to initialize tracing", "error", err)
+	}
+
+	server := &http.Server{
+		Addr:    ":" + port,
+		Handler: otelhttp.NewHandler(requestLogger(newRouter()), "http.server"),
+	}
 
 	// Start HTTP server
-	log.Printf("Starting server on port %s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatalf("Failed to start server: %v\n", err)
+	go func() {
+		slog.Info("Starting server", "port", port)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal("Failed to start server", "error", err)
+		}
+	}()
+
+	// Metrics get their own listener when METRICS_PORT is set, so they stay off the public port
+	var metricsServer *http.Server
+	if metricsPort != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("GET /metrics", metricsHandler())
+		metricsServer = &http.Server{Addr: ":" + metricsPort, Handler: metricsMux}
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fatal("Failed to start metrics server", "error", err)
+			}
+		}()
+	}
+
+	// Cloud Run sends SIGTERM before stopping an instance; drain requests and flush spans
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Server shutdown failed", "error", err)
+	}
+	if err := backgroundJobs.shutdown(shutdownCtx); err != nil {
+		slog.Error("Background jobs did not stop", "error", err)
+	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Metrics server shutdown failed", "error", err)
+		}
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Tracing shutdown failed", "error", err)
 	}
 }
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"firebase.google.com/go/v4/db"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bookclurb_http_requests_total",
+		Help: "HTTP requests handled, by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bookclurb_http_request_duration_seconds",
+		Help:    "HTTP request latency, by route and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	emailSendsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bookclurb_email_sends_total",
+		Help: "Invite email send attempts, by backend and outcome.",
+	}, []string{"backend", "outcome"})
+
+	hardcoverRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bookclurb_hardcover_request_duration_seconds",
+		Help:    "Hardcover GraphQL call latency, by operation and result class.",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"operation", "result"})
+
+	hardcoverBookCacheTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bookclurb_hardcover_book_cache_total",
+		Help: "ISBN to Hardcover book ID lookups, by cache result (hit, negative_hit, store_hit, miss).",
+	}, []string{"result"})
+
+	appCheckResultsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bookclurb_app_check_results_total",
+		Help: "Firebase App Check token checks, by route and result (valid, missing, invalid).",
+	}, []string{"route", "result"})
+
+	firebaseOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bookclurb_firebase_operation_duration_seconds",
+		Help:    "Firebase Realtime Database latency, by operation, resource and outcome.",
+		Buckets: []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+	}, []string{"operation", "resource", "outcome"})
+)
+
+// Where /metrics is exposed, set with METRICS_PORT and METRICS_TOKEN. With neither
+// set the endpoint is not served, since the main port is public.
+var (
+	metricsPort  string // serve /metrics on its own listener, which Cloud Run does not route to
+	metricsToken string // require this bearer token for /metrics
+)
+
+// loadMetricsConfig reads METRICS_PORT and METRICS_TOKEN
+func loadMetricsConfig() {
+	metricsPort = getEnv("METRICS_PORT", "")
+	metricsToken = getEnv("METRICS_TOKEN", "")
+	switch {
+	case metricsPort != "":
+		slog.Info("Serving metrics on a separate port", "port", metricsPort, "token", metricsToken != "")
+	case metricsToken != "":
+		slog.Info("Serving metrics behind a bearer token")
+	default:
+		slog.Info("Metrics endpoint disabled; set METRICS_PORT or METRICS_TOKEN to enable it")
+	}
+}
+
+// metricsHandler serves the Prometheus metrics, requiring metricsToken if it is set
+func metricsHandler() http.Handler {
+	handler := promhttp.Handler()
+	if metricsToken == "" {
+		return handler
+	}
+	want := []byte("Bearer " + metricsToken)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			writeError(w, r, http.StatusUnauthorized, errCodeUnauthorized, "Missing or invalid metrics token", nil)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// instrumentHardcover traces Hardcover GraphQL calls and records their latency by
+// operation and result class
+func instrumentHardcover(ctx context.Context, operation string) (context.Context, func(string, error)) {
+	ctx, span := startClientSpan(ctx, "hardcover."+operation,
+		attribute.String("graphql.operation.name", operation),
+		attribute.String("server.address", hardcoverHost()),
+	)
+	start := time.Now()
+	return ctx, func(result string, err error) {
+		hardcoverRequestDuration.WithLabelValues(operation, result).Observe(time.Since(start).Seconds())
+		span.SetAttributes(attribute.String("hardcover.result", result))
+		endSpan(span, err)
+	}
+}
+
+// hardcoverHost returns the host of the configured Hardcover endpoint
+func hardcoverHost() string {
+	if u, err := url.Parse(hardcoverClient.Endpoint); err == nil {
+		return u.Host
+	}
+	return ""
+}
+
+// recordEmailSend counts an invite email send attempt
+func recordEmailSend(backend string, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+	emailSendsTotal.WithLabelValues(backend, outcome).Inc()
+}
+
+// firebaseGet reads a Realtime Database reference and records its latency
+func firebaseGet(ctx context.Context, resource string, ref *db.Ref, v interface{}) error {
+	ctx, span := startClientSpan(ctx, "firebase.read "+resource, attribute.String("db.system", "firebase"), attribute.String("db.collection.name", resource))
+	start := time.Now()
+	err := ref.Get(ctx, v)
+	observeFirebase("read", resource, start, err)
+	endSpan(span, err)
+	return err
+}
+
+// firebaseGetShallow reads the keys under a Realtime Database reference, without their
+// values, and records its latency
+func firebaseGetShallow(ctx context.Context, resource string, ref *db.Ref, v interface{}) error {
+	ctx, span := startClientSpan(ctx, "firebase.read "+resource, attribute.String("db.system", "firebase"), attribute.String("db.collection.name", resource))
+	start := time.Now()
+	err := ref.GetShallow(ctx, v)
+	observeFirebase("read", resource, start, err)
+	endSpan(span, err)
+	return err
+}
+
+// firebaseUpdate updates a Realtime Database reference and records its latency
+func firebaseUpdate(ctx context.Context, resource string, ref *db.Ref, updates map[string]interface{}) error {
+	ctx, span := startClientSpan(ctx, "firebase.write "+resource, attribute.String("db.system", "firebase"), attribute.String("db.collection.name", resource))
+	start := time.Now()
+	err := ref.Update(ctx, updates)
+	observeFirebase("write", resource, start, err)
+	endSpan(span, err)
+	return err
+}
+
+// firebaseDelete removes a Realtime Database reference and records its latency
+func firebaseDelete(ctx context.Context, resource string, ref *db.Ref) error {
+	ctx, span := startClientSpan(ctx, "firebase.write "+resource, attribute.String("db.system", "firebase"), attribute.String("db.collection.name", resource))
+	start := time.Now()
+	err := ref.Delete(ctx)
+	observeFirebase("write", resource, start, err)
+	endSpan(span, err)
+	return err
+}
+
+// firebasePush adds a child with a generated, chronologically sorted key under a
+// Realtime Database reference and records its latency
+func firebasePush(ctx context.Context, resource string, ref *db.Ref, v interface{}) (*db.Ref, error) {
+	ctx, span := startClientSpan(ctx, "firebase.write "+resource, attribute.String("db.system", "firebase"), attribute.String("db.collection.name", resource))
+	start := time.Now()
+	child, err := ref.Push(ctx, v)
+	observeFirebase("write", resource, start, err)
+	endSpan(span, err)
+	return child, err
+}
+
+// firebaseTransaction runs a Realtime Database transaction and records its latency
+func firebaseTransaction(ctx context.Context, resource string, ref *db.Ref, fn db.UpdateFn) error {
+	ctx, span := startClientSpan(ctx, "firebase.transaction "+resource, attribute.String("db.system", "firebase"), attribute.String("db.collection.name", resource))
+	start := time.Now()
+	err := ref.Transaction(ctx, fn)
+	observeFirebase("transaction", resource, start, err)
+	endSpan(span, err)
+	return err
+}
+
+// recordBookCacheLookup counts an ISBN lookup in the Hardcover book cache
+func recordBookCacheLookup(result string) {
+	hardcoverBookCacheTotal.WithLabelValues(result).Inc()
+}
+
+// recordAppCheck counts an App Check token check
+func recordAppCheck(route, result string) {
+	appCheckResultsTotal.WithLabelValues(route, result).Inc()
+}
+
+func observeFirebase(operation, resource string, start time.Time, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	firebaseOperationDuration.WithLabelValues(operation, resource, outcome).Observe(time.Since(start).Seconds())
+}
+
+// instrumentHandler records request counts and latencies under a fixed route label
+// and names the server span after the route
+func instrumentHandler(route string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		span := trace.SpanFromContext(r.Context())
+		span.SetName(r.Method + " " + route)
+		span.SetAttributes(attribute.String("http.route", route))
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		handler(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		httpRequestsTotal.WithLabelValues(route, r.Method, strconv.Itoa(rec.status)).Inc()
+		httpRequestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	}
+}
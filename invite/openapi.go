@@ -0,0 +1,201 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// apiVersion is reported in the OpenAPI document
+const apiVersion = "1.0.0"
+
+var pathParamPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+// openAPIDocument builds an OpenAPI 3 document from the route table, so the spec
+// always matches what is actually registered
+func openAPIDocument(routes []apiRoute) map[string]interface{} {
+	schemas := map[string]interface{}{}
+	paths := map[string]interface{}{}
+
+	for _, route := range routes {
+		pathItem, ok := paths[route.Path].(map[string]interface{})
+		if !ok {
+			pathItem = map[string]interface{}{}
+			paths[route.Path] = pathItem
+		}
+
+		pathParams := pathParamPattern.FindAllStringSubmatch(route.Path, -1)
+		var parameters []interface{}
+		excluded := map[string]bool{}
+		for _, match := range pathParams {
+			excluded[match[1]] = true
+			parameters = append(parameters, map[string]interface{}{
+				"name":     match[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]string{"type": "string"},
+			})
+		}
+
+		status := route.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		operation := map[string]interface{}{
+			"operationId": route.OperationID,
+			"summary":     route.Summary,
+			"tags":        []string{route.Tag},
+			"responses": map[string]interface{}{
+				strconv.Itoa(status): map[string]interface{}{
+					"description": "Success",
+					"content":     jsonContent(schemaFor(reflect.TypeOf(route.Response), schemas, nil)),
+				},
+				"default": map[string]interface{}{
+					"description": "Error",
+					"content":     jsonContent(schemaFor(reflect.TypeOf(ErrorResponse{}), schemas, nil)),
+				},
+			},
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+		if route.Request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": !route.Optional,
+				"content":  jsonContent(schemaFor(reflect.TypeOf(route.Request), schemas, excluded)),
+			}
+		}
+		if route.Auth {
+			operation["security"] = []interface{}{map[string]interface{}{"firebaseIdToken": []string{}}}
+		}
+		if route.Legacy != "" {
+			operation["description"] = "Also available at the legacy route `POST " + route.Legacy + "`."
+		}
+
+		pathItem[strings.ToLower(route.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "Book Clurb Invite Service",
+			"version": apiVersion,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"firebaseIdToken": map[string]interface{}{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+					"description":  "Firebase ID token of the signed-in user",
+				},
+			},
+		},
+	}
+}
+
+func jsonContent(schema interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schema},
+	}
+}
+
+// schemaFor returns a JSON schema for t. Named structs are registered under
+// components/schemas and referenced, unless some of their fields are excluded
+// (e.g. body fields supplied by path parameters), in which case they are inlined.
+func schemaFor(t reflect.Type, schemas map[string]interface{}, excluded map[string]bool) interface{} {
+	if t == nil {
+		return map[string]interface{}{}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaFor(t.Elem(), schemas, nil)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaFor(t.Elem(), schemas, nil)}
+	case reflect.Struct:
+		if len(excluded) == 0 && t.Name() != "" {
+			if _, ok := schemas[t.Name()]; !ok {
+				// Reserve the name first so recursive types terminate
+				schemas[t.Name()] = map[string]interface{}{}
+				schemas[t.Name()] = structSchema(t, schemas, nil)
+			}
+			return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+		}
+		return structSchema(t, schemas, excluded)
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// structSchema builds an object schema from json struct tags; fields without omitempty are required
+func structSchema(t reflect.Type, schemas map[string]interface{}, excluded map[string]bool) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if excluded[name] {
+			continue
+		}
+		if field.Anonymous && field.Tag.Get("json") == "" && field.Type.Kind() == reflect.Struct {
+			// Embedded structs contribute their fields, as in encoding/json
+			embedded := structSchema(field.Type, schemas, excluded)
+			for embeddedName, property := range embedded["properties"].(map[string]interface{}) {
+				properties[embeddedName] = property
+			}
+			if embeddedRequired, ok := embedded["required"].([]string); ok {
+				required = append(required, embeddedRequired...)
+			}
+			continue
+		}
+
+		properties[name] = schemaFor(field.Type, schemas, nil)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// openAPIHandler serves the generated OpenAPI document
+func openAPIHandler(routes []apiRoute) http.HandlerFunc {
+	doc := openAPIDocument(routes)
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, doc)
+	}
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// apiRoute describes a versioned API operation. The route table drives both
+// mux registration and the OpenAPI document.
+type apiRoute struct {
+	Method      string
+	Path        string
+	OperationID string
+	Summary     string
+	Tag         string
+	Auth        bool        // requires a Firebase ID token
+	AppCheck    bool        // checks the Firebase App Check token per APP_CHECK_MODE unless APP_CHECK_ROUTES overrides it
+	Request     interface{} // JSON request body type, nil if none
+	Optional    bool        // the request body may be omitted
+	Response    interface{} // JSON success response type
+	Status      int         // success status, 200 if unset
+	Legacy      string      // legacy RPC-style alias, if any
+	Handler     http.HandlerFunc
+}
+
+// apiRoutes returns the /v1 API
+func apiRoutes() []apiRoute {
+	return []apiRoute{
+		{
+			Method:      http.MethodPost,
+			Path:        "/v1/clubs/{clubId}/invites",
+			OperationID: "sendClubInvite",
+			Summary:     "Send the invite email for a pending club invite",
+			Tag:         "invites",
+			Auth:        true,
+			Request:     InviteRequest{},
+			Response:    InviteResponse{},
+			Legacy:      "/SendClubInvite",
+			Handler:     sendClubInvite,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/v1/clubs/{clubId}/invites/{inviteId}",
+			OperationID: "getClubInvite",
+			Summary:     "Validate an invite and return its details",
+			Tag:         "invites",
+			AppCheck:    true,
+			Response:    ValidateInviteResponse{},
+			Legacy:      "/ValidateInvite",
+			Handler:     validateInvite,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/v1/clubs/{clubId}/invites/{inviteId}/accept",
+			OperationID: "acceptClubInvite",
+			Summary:     "Join the club of an active invite sent to the caller's verified email",
+			Tag:         "invites",
+			Auth:        true,
+			AppCheck:    true,
+			Response:    AcceptInviteResponse{},
+			Handler:     acceptClubInvite,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/v1/clubs/{clubId}/members",
+			OperationID: "joinClub",
+			Summary:     "Join a public club",
+			Tag:         "clubs",
+			Auth:        true,
+			AppCheck:    true,
+			Response:    ClubMembershipResponse{},
+			Handler:     joinClubHandler,
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/v1/clubs/{clubId}/members/me",
+			OperationID: "leaveClub",
+			Summary:     "Leave a club",
+			Tag:         "clubs",
+			Auth:        true,
+			Response:    ClubMembershipResponse{},
+			Handler:     leaveClubHandler,
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/v1/clubs/{clubId}/members/{userId}/role",
+			OperationID: "updateClubMemberRole",
+			Summary:     "Make a member an admin or a plain member",
+			Tag:         "clubs",
+			Auth:        true,
+			Request:     UpdateMemberRoleRequest{},
+			Response:    ClubMembershipResponse{},
+			Handler:     updateMemberRoleHandler,
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/v1/clubs/{clubId}",
+			OperationID: "deleteClub",
+			Summary:     "Delete a club and remove it from its members' clubs",
+			Tag:         "clubs",
+			Auth:        true,
+			Response:    DeleteClubResponse{},
+			Handler:     deleteClubHandler,
+		},
+		// TODO: Move Hardcover integration to its own dedicated service with API gateway
+		// This will improve separation of concerns, allow independent scaling, and provide
+		// better rate limiting and monitoring capabilities for the Hardcover API integration.
+		{
+			Method:      http.MethodPost,
+			Path:        "/v1/hardcover/token/test",
+			OperationID: "testHardcoverToken",
+			Summary:     "Check a Hardcover API token and return the Hardcover user",
+			Tag:         "hardcover",
+			AppCheck:    true,
+			Request:     TestHardcoverTokenRequest{},
+			Response:    TestHardcoverTokenResponse{},
+			Legacy:      "/TestHardcoverToken",
+			Handler:     testHardcoverTokenHandler,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/v1/me/hardcover",
+			OperationID: "getHardcoverLink",
+			Summary:     "Get the user's Hardcover link status",
+			Tag:         "hardcover",
+			Auth:        true,
+			Response:    HardcoverLinkResponse{},
+			Handler:     getHardcoverLinkHandler,
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/v1/me/hardcover",
+			OperationID: "linkHardcover",
+			Summary:     "Verify a Hardcover API token and link it to the user",
+			Tag:         "hardcover",
+			Auth:        true,
+			Request:     LinkHardcoverRequest{},
+			Response:    HardcoverLinkResponse{},
+			Handler:     linkHardcoverHandler,
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/v1/me/hardcover",
+			OperationID: "unlinkHardcover",
+			Summary:     "Unlink the user's Hardcover account and delete the stored token",
+			Tag:         "hardcover",
+			Auth:        true,
+			Response:    HardcoverLinkResponse{},
+			Handler:     unlinkHardcoverHandler,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/v1/me/hardcover/ratings",
+			OperationID: "syncRatingToHardcover",
+			Summary:     "Sync a rating (and optional review) to the user's Hardcover account",
+			Tag:         "hardcover",
+			Auth:        true,
+			Request:     SyncRatingRequest{},
+			Response:    SyncRatingResponse{},
+			Legacy:      "/SyncRatingToHardcover",
+			Handler:     syncRatingToHardcoverHandler,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/v1/me/hardcover/reviews",
+			OperationID: "syncReviewToHardcover",
+			Summary:     "Sync a review and rating to the user's Hardcover account",
+			Tag:         "hardcover",
+			Auth:        true,
+			Request:     SyncReviewRequest{},
+			Response:    SyncReviewResponse{},
+			Legacy:      "/SyncReviewToHardcover",
+			Handler:     syncReviewToHardcoverHandler,
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/v1/me/hardcover/reviews",
+			OperationID: "retractReviewFromHardcover",
+			Summary:     "Remove a synced review, and optionally the rating or the whole book, from the user's Hardcover account",
+			Tag:         "hardcover",
+			Auth:        true,
+			Request:     RetractReviewRequest{},
+			Response:    RetractReviewResponse{},
+			Handler:     retractReviewHandler,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/v1/me/hardcover/sync-history",
+			OperationID: "getSyncHistory",
+			Summary:     "List the user's recent rating and review syncs to Hardcover, newest first",
+			Tag:         "hardcover",
+			Auth:        true,
+			Response:    SyncHistoryResponse{},
+			Handler:     getSyncHistoryHandler,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/v1/me/hardcover/sync-history/retry",
+			OperationID: "retryHardcoverSync",
+			Summary:     "Retry the user's failed Hardcover syncs",
+			Tag:         "hardcover",
+			Auth:        true,
+			Request:     RetrySyncRequest{},
+			Response:    RetrySyncResponse{},
+			Handler:     retrySyncHandler,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/v1/me/hardcover/settings",
+			OperationID: "getHardcoverSyncSettings",
+			Summary:     "Get the user's review privacy, spoiler and rating-only settings for Hardcover sync",
+			Tag:         "hardcover",
+			Auth:        true,
+			Response:    HardcoverSyncSettings{},
+			Handler:     getMySyncSettingsHandler,
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/v1/me/hardcover/settings",
+			OperationID: "updateHardcoverSyncSettings",
+			Summary:     "Replace the user's Hardcover sync settings",
+			Tag:         "hardcover",
+			Auth:        true,
+			Request:     HardcoverSyncSettings{},
+			Response:    HardcoverSyncSettings{},
+			Handler:     updateMySyncSettingsHandler,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/v1/clubs/{clubId}/hardcover/reading",
+			OperationID: "syncClubReadingToHardcover",
+			Summary:     "Start a background job marking the club's current book as currently reading, or read, for every linked member",
+			Tag:         "hardcover",
+			Auth:        true,
+			Request:     SyncClubReadingRequest{},
+			Response:    ClubReadingSyncJob{},
+			Status:      http.StatusAccepted,
+			Handler:     syncClubReadingHandler,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/v1/clubs/{clubId}/hardcover/reading/jobs/{jobId}",
+			OperationID: "getClubReadingSyncJob",
+			Summary:     "Get the status of a club reading sync job and, once it finishes, its report",
+			Tag:         "hardcover",
+			Auth:        true,
+			Response:    ClubReadingSyncJob{},
+			Handler:     getClubReadingSyncJobHandler,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/v1/clubs/{clubId}/hardcover/ratings",
+			OperationID: "syncClubRatingsToHardcover",
+			Summary:     "Start a background job syncing the ratings and reviews in the club's history to the caller's, or every linked member's, Hardcover account",
+			Tag:         "hardcover",
+			Auth:        true,
+			Request:     SyncClubRatingsRequest{},
+			Optional:    true,
+			Response:    ClubRatingsSyncJob{},
+			Status:      http.StatusAccepted,
+			Handler:     syncClubRatingsHandler,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/v1/clubs/{clubId}/hardcover/ratings/jobs/{jobId}",
+			OperationID: "getClubRatingsSyncJob",
+			Summary:     "Get the status of a club ratings sync job and, once it finishes, its report",
+			Tag:         "hardcover",
+			Auth:        true,
+			Response:    ClubRatingsSyncJob{},
+			Handler:     getClubRatingsSyncJobHandler,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/v1/clubs/{clubId}/hardcover/settings",
+			OperationID: "getClubHardcoverSyncSettings",
+			Summary:     "Get the club's Hardcover sync settings, which apply on top of each member's own",
+			Tag:         "hardcover",
+			Auth:        true,
+			Response:    HardcoverSyncSettings{},
+			Handler:     getClubSyncSettingsHandler,
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/v1/clubs/{clubId}/hardcover/settings",
+			OperationID: "updateClubHardcoverSyncSettings",
+			Summary:     "Replace the club's Hardcover sync settings (admins only)",
+			Tag:         "hardcover",
+			Auth:        true,
+			Request:     HardcoverSyncSettings{},
+			Response:    HardcoverSyncSettings{},
+			Handler:     updateClubSyncSettingsHandler,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/v1/clubs/{clubId}/hardcover/import",
+			OperationID: "previewHardcoverImport",
+			Summary:     "List the club's past books on the caller's Hardcover shelf with ratings and reviews to back-fill",
+			Tag:         "hardcover",
+			Auth:        true,
+			Response:    HardcoverImportResponse{},
+			Handler:     previewHardcoverImportHandler,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/v1/clubs/{clubId}/hardcover/import",
+			OperationID: "importFromHardcover",
+			Summary:     "Back-fill the caller's club ratings and reviews from their Hardcover shelf",
+			Tag:         "hardcover",
+			Auth:        true,
+			Request:     ImportFromHardcoverRequest{},
+			Response:    HardcoverImportResponse{},
+			Handler:     importFromHardcoverHandler,
+		},
+	}
+}
+
+// newRouter registers the API, legacy aliases and operational endpoints
+func newRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+	routes := apiRoutes()
+	checkAppCheckRoutes(routes)
+
+	methodsByPath := map[string][]string{}
+	for _, route := range routes {
+		handler := route.Handler
+		if route.Auth {
+			handler = requireAuth(handler)
+		}
+		if mode := appCheckModeFor(route); mode != appCheckOff {
+			handler = requireAppCheck(route.Path, mode, handler)
+		}
+
+		mux.HandleFunc(route.Method+" "+route.Path, instrumentHandler(route.Path, corsHandler(handler, route.Method)))
+		methodsByPath[route.Path] = append(methodsByPath[route.Path], route.Method)
+
+		if route.Legacy != "" {
+			mux.HandleFunc(route.Legacy, instrumentHandler(route.Legacy, corsHandler(handler, http.MethodPost)))
+		}
+	}
+
+	// Method-less fallbacks answer CORS preflights and return the JSON 405 envelope
+	// instead of the mux's plain-text response
+	for path, methods := range methodsByPath {
+		sort.Strings(methods)
+		mux.HandleFunc(path, instrumentHandler(path, corsHandler(methodNotAllowed(methods), methods...)))
+	}
+
+	mux.HandleFunc("GET /v1/openapi.json", instrumentHandler("/v1/openapi.json", corsHandler(openAPIHandler(routes), http.MethodGet)))
+
+	// Prometheus metrics, on the public port only behind a token
+	if metricsPort == "" && metricsToken != "" {
+		mux.Handle("GET /metrics", metricsHandler())
+	}
+
+	// Liveness and readiness probes
+	mux.HandleFunc("/healthz", instrumentHandler("/healthz", healthzHandler))
+	mux.HandleFunc("/readyz", instrumentHandler("/readyz", readyzHandler))
+
+	// Legacy health check endpoint for Cloud Run
+	mux.HandleFunc("/", instrumentHandler("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("OK"))
+			return
+		}
+		notFoundHandler(w, r)
+	}))
+
+	return mux
+}
+
+// methodNotAllowed rejects requests whose method has no registered handler
+func methodNotAllowed(methods []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", strings.Join(methods, ", "))
+		writeError(w, r, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "Method not allowed", nil)
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dhvogel/bookclurb-invite/isbn"
+)
+
+// maxRequestBodyBytes bounds JSON request bodies. The largest legitimate body is a
+// review of up to maxReviewTextLength characters.
+const maxRequestBodyBytes = 64 << 10
+
+// Field limits enforced by request validation
+const (
+	maxReviewTextLength = 10000
+	maxNameLength       = 200
+	maxTitleLength      = 500
+	maxTokenLength      = 4096
+	maxIDLength         = 128
+)
+
+var emailAddressPattern = regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`)
+
+// errTrailingJSON is returned when a request body holds more than one JSON value
+var errTrailingJSON = errors.New("request body must contain a single JSON object")
+
+// FieldError describes one invalid request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrorDetails is returned in APIError.Details for validation_failed errors
+type ValidationErrorDetails struct {
+	Fields []FieldError `json:"fields"`
+}
+
+// validator collects field errors so a response can report every failing field at once
+type validator struct {
+	errors []FieldError
+}
+
+// add records an error for field
+func (v *validator) add(field, message string) {
+	v.errors = append(v.errors, FieldError{Field: field, Message: message})
+}
+
+// required checks that value is not blank
+func (v *validator) required(field, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		v.add(field, "is required")
+		return false
+	}
+	return true
+}
+
+// email checks that a required value looks like an email address
+func (v *validator) email(field, value string) {
+	if v.required(field, value) && !emailAddressPattern.MatchString(value) {
+		v.add(field, "must be a valid email address")
+	}
+}
+
+// isbn checks that a required value is an ISBN-10 or ISBN-13 with a correct check
+// digit; hyphens and spaces are ignored
+func (v *validator) isbn(field, value string) {
+	if !v.required(field, value) {
+		return
+	}
+	switch normalized := isbn.Normalize(value); {
+	case len(normalized) != 10 && len(normalized) != 13:
+		v.add(field, "must be a 10- or 13-digit ISBN")
+	case !isbn.Valid(normalized):
+		v.add(field, "is not a valid ISBN (check digit does not match)")
+	}
+}
+
+// rating checks that value is within the Hardcover rating range
+func (v *validator) rating(field string, value float64) {
+	if value < 0 || value > 5 {
+		v.add(field, "must be between 0 and 5")
+	}
+}
+
+// maxLength checks that value has at most max characters
+func (v *validator) maxLength(field, value string, max int) {
+	if utf8.RuneCountInString(value) > max {
+		v.add(field, fmt.Sprintf("must be at most %d characters", max))
+	}
+}
+
+// date checks that an optional value is a YYYY-MM-DD date
+func (v *validator) date(field, value string) {
+	if value == "" {
+		return
+	}
+	if _, err := time.Parse(dateLayout, value); err != nil {
+		v.add(field, "must be a date in YYYY-MM-DD format")
+	}
+}
+
+// normalizeISBN strips the separators commonly found in printed ISBNs and converts
+// valid ISBN-10s to ISBN-13, so both forms of a book compare equal
+func normalizeISBN(s string) string {
+	if isbn13, err := isbn.To13(s); err == nil {
+		return isbn13
+	}
+	return isbn.Normalize(s)
+}
+
+// validatable is implemented by request types with field-level rules
+type validatable interface {
+	validate(v *validator)
+}
+
+// decodeJSON strictly decodes a size-limited JSON request body into dst, writing a 400 or
+// 413 error and returning false if the body is malformed, too large or has unknown fields
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(dst)
+	if err == nil && decoder.Decode(&struct{}{}) != io.EOF {
+		err = errTrailingJSON
+	}
+	if err == nil {
+		return true
+	}
+
+	slog.InfoContext(r.Context(), "Rejected request body", "error", err)
+
+	var maxBytesErr *http.MaxBytesError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		writeError(w, r, http.StatusRequestEntityTooLarge, errCodeRequestTooLarge,
+			fmt.Sprintf("Request body must not exceed %d bytes", maxBytesErr.Limit), nil)
+	case errors.As(err, &typeErr):
+		writeValidationError(w, r, []FieldError{{Field: typeErr.Field, Message: "must be a " + jsonTypeName(typeErr.Type.String())}})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		writeValidationError(w, r, []FieldError{{Field: field, Message: "is not a recognized field"}})
+	case errors.As(err, &syntaxErr):
+		writeError(w, r, http.StatusBadRequest, errCodeInvalidRequest,
+			fmt.Sprintf("Request body is not valid JSON (at byte %d)", syntaxErr.Offset), nil)
+	case errors.Is(err, errTrailingJSON):
+		writeError(w, r, http.StatusBadRequest, errCodeInvalidRequest, "Request body must contain a single JSON object", nil)
+	case errors.Is(err, io.EOF):
+		writeError(w, r, http.StatusBadRequest, errCodeInvalidRequest, "Request body must not be empty", nil)
+	default:
+		writeError(w, r, http.StatusBadRequest, errCodeInvalidRequest, "Invalid request body", nil)
+	}
+	return false
+}
+
+// decodeOptionalJSON is decodeJSON for requests whose fields are all optional, treating
+// an empty body like {}
+func decodeOptionalJSON(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	body := bufio.NewReader(r.Body)
+	if _, err := body.Peek(1); err == io.EOF {
+		return true
+	}
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{body, r.Body}
+	return decodeJSON(w, r, dst)
+}
+
+// validateRequest runs the field rules of req, writing a validation_failed error listing
+// every failing field and returning false if any fail
+func validateRequest(w http.ResponseWriter, r *http.Request, req validatable) bool {
+	var v validator
+	req.validate(&v)
+	if len(v.errors) == 0 {
+		return true
+	}
+	writeValidationError(w, r, v.errors)
+	return false
+}
+
+func writeValidationError(w http.ResponseWriter, r *http.Request, fields []FieldError) {
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = field.Field
+	}
+	writeError(w, r, http.StatusBadRequest, errCodeValidationFailed,
+		"Invalid fields: "+strings.Join(names, ", "), ValidationErrorDetails{Fields: fields})
+}
+
+// jsonTypeName describes a Go type in JSON terms for error messages
+func jsonTypeName(goType string) string {
+	switch goType {
+	case "string":
+		return "string"
+	case "bool":
+		return "boolean"
+	case "float64", "float32", "int", "int64", "int32":
+		return "number"
+	}
+	if strings.HasPrefix(goType, "[]") {
+		return "array"
+	}
+	return "object"
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// decodeTestRequest is a request body with one field of each JSON type
+type decodeTestRequest struct {
+	Name   string `json:"name"`
+	Rating int    `json:"rating"`
+}
+
+// decodeTestHandler decodes a decodeTestRequest with decode and echoes it back
+func decodeTestHandler(decode func(http.ResponseWriter, *http.Request, interface{}) bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req decodeTestRequest
+		if !decode(w, r, &req) {
+			return
+		}
+		writeJSON(w, http.StatusOK, req)
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCode   string
+		wantFields []string // fields reported in validation_failed details
+		want       decodeTestRequest
+	}{
+		{name: "valid", body: `{"name": "Dune", "rating": 5}`, wantStatus: http.StatusOK, want: decodeTestRequest{Name: "Dune", Rating: 5}},
+		{name: "trailing whitespace", body: "{\"name\": \"Dune\"}\n  ", wantStatus: http.StatusOK, want: decodeTestRequest{Name: "Dune"}},
+		{name: "unknown field", body: `{"name": "Dune", "isbn": "123"}`, wantStatus: http.StatusBadRequest,
+			wantCode: errCodeValidationFailed, wantFields: []string{"isbn"}},
+		{name: "wrong type", body: `{"rating": "five"}`, wantStatus: http.StatusBadRequest,
+			wantCode: errCodeValidationFailed, wantFields: []string{"rating"}},
+		{name: "trailing object", body: `{"name": "Dune"}{"name": "Emma"}`, wantStatus: http.StatusBadRequest, wantCode: errCodeInvalidRequest},
+		{name: "trailing garbage", body: `{"name": "Dune"} x`, wantStatus: http.StatusBadRequest, wantCode: errCodeInvalidRequest},
+		{name: "malformed", body: `{"name": `, wantStatus: http.StatusBadRequest, wantCode: errCodeInvalidRequest},
+		{name: "invalid syntax", body: `{name: "Dune"}`, wantStatus: http.StatusBadRequest, wantCode: errCodeInvalidRequest},
+		{name: "empty", body: "", wantStatus: http.StatusBadRequest, wantCode: errCodeInvalidRequest},
+		{name: "at the size limit", body: `{"name": "` + strings.Repeat("a", maxRequestBodyBytes-len(`{"name": ""}`)) + `"}`,
+			wantStatus: http.StatusOK, want: decodeTestRequest{Name: strings.Repeat("a", maxRequestBodyBytes-len(`{"name": ""}`))}},
+		{name: "over the size limit", body: `{"name": "` + strings.Repeat("a", maxRequestBodyBytes) + `"}`,
+			wantStatus: http.StatusRequestEntityTooLarge, wantCode: errCodeRequestTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkDecode(t, decodeTestHandler(decodeJSON), tt.body, tt.wantStatus, tt.wantCode, tt.wantFields, tt.want)
+		})
+	}
+}
+
+func TestDecodeOptionalJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCode   string
+		wantFields []string
+		want       decodeTestRequest
+	}{
+		{name: "empty body", body: "", wantStatus: http.StatusOK},
+		{name: "empty object", body: `{}`, wantStatus: http.StatusOK},
+		{name: "valid", body: `{"name": "Dune", "rating": 4}`, wantStatus: http.StatusOK, want: decodeTestRequest{Name: "Dune", Rating: 4}},
+		{name: "unknown field", body: `{"extra": true}`, wantStatus: http.StatusBadRequest,
+			wantCode: errCodeValidationFailed, wantFields: []string{"extra"}},
+		{name: "trailing data", body: `{} {}`, wantStatus: http.StatusBadRequest, wantCode: errCodeInvalidRequest},
+		{name: "malformed", body: `{`, wantStatus: http.StatusBadRequest, wantCode: errCodeInvalidRequest},
+		{name: "over the size limit", body: `{"name": "` + strings.Repeat("a", maxRequestBodyBytes) + `"}`,
+			wantStatus: http.StatusRequestEntityTooLarge, wantCode: errCodeRequestTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkDecode(t, decodeTestHandler(decodeOptionalJSON), tt.body, tt.wantStatus, tt.wantCode, tt.wantFields, tt.want)
+		})
+	}
+}
+
+// checkDecode posts body to handler and checks the status, error code, reported
+// fields and, on success, the decoded request
+func checkDecode(t *testing.T, handler http.HandlerFunc, body string, wantStatus int, wantCode string, wantFields []string, want decodeTestRequest) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+	if w.Code != wantStatus {
+		t.Fatalf("status = %d, want %d (body: %.200s)", w.Code, wantStatus, w.Body)
+	}
+	if wantStatus == http.StatusOK {
+		var got decodeTestRequest
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if got != want {
+			t.Errorf("decoded %+v, want %+v", got, want)
+		}
+		return
+	}
+
+	var response struct {
+		Error struct {
+			Code    string                 `json:"code"`
+			Details ValidationErrorDetails `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode error response %q: %v", w.Body.String(), err)
+	}
+	if response.Error.Code != wantCode {
+		t.Errorf("error code = %q, want %q", response.Error.Code, wantCode)
+	}
+	var fields []string
+	for _, field := range response.Error.Details.Fields {
+		fields = append(fields, field.Field)
+	}
+	if !reflect.DeepEqual(fields, wantFields) {
+		t.Errorf("fields = %v, want %v", fields, wantFields)
+	}
+}
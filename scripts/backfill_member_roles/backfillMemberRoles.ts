@@ -0,0 +1,54 @@
+import * as admin from "firebase-admin";
+
+// --- Initialize Firebase Admin ---
+admin.initializeApp({
+  credential: admin.credential.cert("../../firebase_sa_key.json"),
+  databaseURL: "https://sombk-firebase-free-default-rtdb.firebaseio.com"
+});
+
+const db = admin.database();
+
+// Members are stored as an array, but older clubs may store them as an object keyed
+// by push ID
+function membersOf(clubData: any): any[] {
+  const members = clubData?.members;
+  if (!members) return [];
+  return Array.isArray(members) ? members : Object.values(members);
+}
+
+async function backfillMemberRoles() {
+  console.log(`🔄 Backfilling clubs/{clubId}/memberRoles from members...`);
+
+  try {
+    const clubsSnapshot = await db.ref("clubs").once("value");
+    const allClubs = clubsSnapshot.val() || {};
+
+    let clubsUpdated = 0;
+    for (const [clubId, clubData] of Object.entries(allClubs)) {
+      const memberRoles: Record<string, string> = {};
+      for (const member of membersOf(clubData)) {
+        if (member && typeof member.id === "string" && member.id !== "") {
+          memberRoles[member.id] = member.role || "member";
+        }
+      }
+
+      await db.ref(`clubs/${clubId}`).update({
+        memberRoles,
+        memberCount: Object.keys(memberRoles).length
+      });
+      console.log(`✅ ${clubId}: ${Object.keys(memberRoles).length} members`);
+      clubsUpdated++;
+    }
+
+    console.log(`\n📊 Backfill Summary:`);
+    console.log(`- Clubs updated: ${clubsUpdated}`);
+  } catch (error) {
+    console.error(`❌ Backfill failed:`, error);
+  }
+
+  // Close the Firebase connection
+  await admin.app().delete();
+  process.exit(0);
+}
+
+backfillMemberRoles().catch(console.error);
@@ -104,6 +104,11 @@ echo "   Using image: $FULL_IMAGE_NAME"
 
 ENV_VARS="REACT_APP_INVITE_SERVICE_URL=$REACT_APP_INVITE_SERVICE_URL"
 
+# reCAPTCHA v3 site key for Firebase App Check; the app sends no App Check tokens without it
+if [ -n "$REACT_APP_APP_CHECK_SITE_KEY" ]; then
+  ENV_VARS="$ENV_VARS,REACT_APP_APP_CHECK_SITE_KEY=$REACT_APP_APP_CHECK_SITE_KEY"
+fi
+
 gcloud run deploy "$SERVICE_NAME" \
   --image "$FULL_IMAGE_NAME" \
   --platform managed \
@@ -6,8 +6,9 @@ PORT=${PORT:-8080}
 
 # Generate config.js from environment variable
 INVITE_SERVICE_URL=${REACT_APP_INVITE_SERVICE_URL:-http://localhost:8080}
+APP_CHECK_SITE_KEY=${REACT_APP_APP_CHECK_SITE_KEY:-}
 
-echo "window.__RUNTIME_CONFIG__ = { inviteServiceURL: \"$INVITE_SERVICE_URL\" };" > /usr/share/nginx/html/config.js
+echo "window.__RUNTIME_CONFIG__ = { inviteServiceURL: \"$INVITE_SERVICE_URL\", appCheckSiteKey: \"$APP_CHECK_SITE_KEY\" };" > /usr/share/nginx/html/config.js
 
 # Generate nginx config with dynamic port
 cat > /etc/nginx/conf.d/nginx.conf <<EOF
@@ -7,6 +7,9 @@ import { useEffect, useState } from 'react';
 import { getAuth, onAuthStateChanged, User } from 'firebase/auth';
 import Clubs from './components/Clubs';
 import { ClubPage, ProfilePage, LoginPage, BlogList, BlogPost, HomePage } from './features';
+import { initAppCheck } from './utils/appCheck';
+
+initAppCheck();
 
 function App() {
   const [user, setUser] = useState<User | null>(null); // Parent holds the state
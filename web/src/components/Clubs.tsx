@@ -1,11 +1,13 @@
 import React, { useEffect, useState } from 'react';
 import { useNavigate } from 'react-router-dom';
-import { Database, ref, onValue, update, get } from 'firebase/database';
+import { Database, ref, onValue, query, orderByChild, equalTo } from 'firebase/database';
 import HeaderBar from './HeaderBar';
 import { motion, AnimatePresence } from 'framer-motion';
 import { ClubsProps, Club } from '../types';
 import { extractClubBooksRead } from '../utils/bookUtils';
 import CreateClubModal from './CreateClubModal';
+import { joinClub, leaveClub } from '../utils/clubMembership';
+import { ServiceRequestError } from '../utils/serviceErrors';
 
 // Helper function to calculate average rating for a book
 const calculateAverageRating = (ratings?: Record<string, number>): number => {
@@ -488,10 +490,8 @@ const Clubs: React.FC<ClubsProps> = ({ user, db }) => {
   const navigate = useNavigate();
   const [clubs, setClubs] = useState<Club[]>([]);
   const [publicClubs, setPublicClubs] = useState<Club[]>([]);
-  const [privateClubs, setPrivateClubs] = useState<Club[]>([]);
   const [loading, setLoading] = useState(true);
   const [publicClubsLoading, setPublicClubsLoading] = useState(false);
-  const [privateClubsLoading, setPrivateClubsLoading] = useState(false);
   const [selectedClubId, setSelectedClubId] = useState<string | null>(null);
   const [showMenu, setShowMenu] = useState(false);
   const [showCreateModal, setShowCreateModal] = useState(false);
@@ -612,8 +612,9 @@ const Clubs: React.FC<ClubsProps> = ({ user, db }) => {
         unsubscribe();
       };
     } else {
-      // Load public clubs for non-logged-in users to explore
-      const clubsRef = ref(db, 'clubs');
+      // Load public clubs for non-logged-in users to explore. The database rules only
+      // allow listing clubs filtered to isPublic === true.
+      const clubsRef = query(ref(db, 'clubs'), orderByChild('isPublic'), equalTo(true));
       const unsubscribe = onValue(clubsRef, (snapshot) => {
         const clubsData = snapshot.val();
         
@@ -708,66 +709,6 @@ const Clubs: React.FC<ClubsProps> = ({ user, db }) => {
     }
   }, [user, db]);
 
-  // Load private clubs for logged-in users (excluding clubs they're already members of)
-  useEffect(() => {
-    if (!user || !db) {
-      return;
-    }
-
-    setPrivateClubsLoading(true);
-    const clubsRef = ref(db, 'clubs');
-    const unsubscribe = onValue(clubsRef, (snapshot) => {
-      const clubsData = snapshot.val();
-      
-      if (clubsData) {
-        // Get list of club IDs user is already a member of
-        const userClubIds = new Set(clubs.map(c => c.id));
-        
-        // Filter and get private clubs only (isPublic === false or undefined), excluding user's clubs
-        const privateClubsList = Object.entries(clubsData)
-          .filter(([clubId, clubData]: [string, any]) => {
-            // Show clubs that are explicitly marked as private (isPublic === false)
-            // Or clubs where isPublic is undefined (backward compatibility - treat as private)
-            // And exclude clubs user is already a member of
-            return (clubData.isPublic === false || clubData.isPublic === undefined) && !userClubIds.has(clubId);
-          })
-          .slice(0, 12) // Show up to 12 private clubs
-          .map(([clubId, clubData]: [string, any]) => {
-            // Calculate member count from members object
-            const membersCount = clubData.members 
-              ? (Array.isArray(clubData.members) ? clubData.members.length : Object.keys(clubData.members).length)
-              : 0;
-            
-            return {
-              id: clubId,
-              name: clubData.name || 'Untitled Club',
-              coverImage: clubData.coverImage,
-              coverColor: clubData.coverColor || '#667eea',
-              nextMeeting: clubData.nextMeeting,
-              currentBook: clubData.currentBook,
-              memberCount: clubData.memberCount || membersCount,
-              description: clubData.description,
-              booksRead: clubData.booksRead,
-              members: clubData.members,
-              recentActivity: clubData.recentActivity,
-              isPublic: clubData.isPublic,
-              meetings: clubData.meetings,
-            };
-          });
-        setPrivateClubs(privateClubsList);
-      } else {
-        setPrivateClubs([]);
-      }
-      setPrivateClubsLoading(false);
-    }, (error) => {
-      console.error('Error loading private clubs:', error);
-      setPrivateClubs([]);
-      setPrivateClubsLoading(false);
-    });
-
-    return () => unsubscribe();
-  }, [user, db, clubs]);
-
   // Load public clubs for logged-in users (excluding clubs they're already members of)
   useEffect(() => {
     if (!user || !db) {
@@ -775,7 +716,7 @@ const Clubs: React.FC<ClubsProps> = ({ user, db }) => {
     }
 
     setPublicClubsLoading(true);
-    const clubsRef = ref(db, 'clubs');
+    const clubsRef = query(ref(db, 'clubs'), orderByChild('isPublic'), equalTo(true));
     const unsubscribe = onValue(clubsRef, (snapshot) => {
       const clubsData = snapshot.val();
       
@@ -834,7 +775,7 @@ const Clubs: React.FC<ClubsProps> = ({ user, db }) => {
     }
   }, [clubs, user]);
 
-  const handleClubClick = (clubId: string, isPrivate: boolean = false) => {
+  const handleClubClick = (clubId: string) => {
     // Check if the club is public by looking in the clubs array (for non-logged-in users) or publicClubs array
     const club = clubs.find(c => c.id === clubId) || publicClubs.find(c => c.id === clubId);
     const isPublicClub = club?.isPublic === true;
@@ -845,15 +786,6 @@ const Clubs: React.FC<ClubsProps> = ({ user, db }) => {
       return;
     }
     
-    // If it's explicitly marked as private and user clicked on it, show message
-    if (isPrivate && user) {
-      const privateClub = privateClubs.find(c => c.id === clubId);
-      if (privateClub) {
-        alert('This is a private club. You need to be invited by a member to join.');
-        return;
-      }
-    }
-    
     // For non-logged-in users trying to access non-public clubs, show login prompt
     if (!user) {
       // Show login prompt for non-logged-in users
@@ -893,57 +825,19 @@ const Clubs: React.FC<ClubsProps> = ({ user, db }) => {
     }
 
     try {
-      // Get club data
-      const clubRef = ref(db, `clubs/${clubId}`);
-      const clubSnapshot = await get(clubRef);
-      const clubData = clubSnapshot.val();
-
-      if (!clubData) {
-        alert('Club not found');
-        return;
-      }
-
-      // Check if club is public
-      if (clubData.isPublic !== true) {
-        alert('This club is private. You need an invitation to join.');
-        return;
-      }
-
-      // Add user to club's members array
-      const members = clubData.members || [];
-      const userMember = {
-        id: user.uid,
-        name: user.displayName || user.email || 'New Member',
-        img: user.photoURL || '',
-        role: 'member',
-        joinedAt: new Date().toISOString()
-      };
-
-      // Check if user is already a member (double-check)
-      if (!members.some((m: any) => m.id === user.uid)) {
-        members.push(userMember);
-        await update(clubRef, {
-          members: members,
-          memberCount: members.length
-        });
-      }
-
-      // Add club to user's clubs array
-      const userRef = ref(db, `users/${user.uid}`);
-      const userSnapshot = await get(userRef);
-      const userData = userSnapshot.val() || {};
-      const userClubs = userData.clubs || [];
-      
-      if (!userClubs.includes(clubId)) {
-        userClubs.push(clubId);
-        await update(userRef, { clubs: userClubs });
-      }
+      await joinClub(clubId);
 
       // Navigate to the club page
       navigate(`/clubs/${clubId}`);
     } catch (error) {
       console.error('Error joining club:', error);
-      alert('Failed to join club. Please try again.');
+      if (error instanceof ServiceRequestError && error.code === 'forbidden') {
+        alert('This club is private. You need an invitation to join.');
+      } else if (error instanceof ServiceRequestError && error.code === 'not_found') {
+        alert('Club not found');
+      } else {
+        alert('Failed to join club. Please try again.');
+      }
     }
   };
 
@@ -952,18 +846,15 @@ const Clubs: React.FC<ClubsProps> = ({ user, db }) => {
 
     switch (action) {
       case 'leave':
-        // Remove club from user's clubs
-        const userRef = ref(db, `users/${user.uid}`);
-        const userSnapshot = await new Promise((resolve) => {
-          const unsubscribe = onValue(userRef, (snapshot) => {
-            resolve(snapshot);
-            unsubscribe();
-          });
-        });
-        const userData = (userSnapshot as any).val();
-        if (userData && userData.clubs) {
-          const updatedClubs = userData.clubs.filter((id: string) => id !== selectedClubId);
-          await update(userRef, { clubs: updatedClubs });
+        try {
+          await leaveClub(selectedClubId);
+        } catch (error) {
+          console.error('Error leaving club:', error);
+          if (error instanceof ServiceRequestError && error.code === 'last_admin') {
+            alert('Make another member an admin before leaving the club.');
+          } else {
+            alert('Failed to leave club. Please try again.');
+          }
         }
         break;
       case 'details':
@@ -1142,54 +1033,6 @@ const Clubs: React.FC<ClubsProps> = ({ user, db }) => {
             </div>
           )}
 
-          {/* Private Clubs Section - show for logged-in users */}
-          {user && (
-            <div style={{ marginTop: user ? '3rem' : '0', marginBottom: '3rem' }}>
-              <h2 style={{ fontSize: '1.8rem', fontWeight: 'bold', color: '#333', marginBottom: '1.5rem' }}>
-                Private Clubs
-              </h2>
-              <p style={{ color: '#666', fontSize: '0.95rem', marginBottom: '1.5rem' }}>
-                These clubs are private. You can view them but need an invitation to join.
-              </p>
-              {privateClubsLoading ? (
-                <div style={{ textAlign: 'center', padding: '2rem', color: '#666' }}>
-                  Loading private clubs...
-                </div>
-              ) : (
-                <div style={{ 
-                  display: 'grid', 
-                  gridTemplateColumns: 'repeat(auto-fill, minmax(350px, 1fr))', 
-                  gap: '1.5rem' 
-                }}>
-                  {privateClubs.map((club) => (
-                    <ClubCard
-                      key={club.id}
-                      club={club}
-                      onClubClick={(id) => handleClubClick(id, true)}
-                      onLongPress={handleLongPress}
-                      user={user}
-                      isPrivate={true}
-                    />
-                  ))}
-
-                  {/* Show "No private clubs" message if no clubs available */}
-                  {privateClubs.length === 0 && !privateClubsLoading && (
-                    <div style={{ 
-                      gridColumn: '1 / -1',
-                      textAlign: 'center', 
-                      padding: '1.5rem',
-                      color: '#999',
-                      fontSize: '0.95rem',
-                      fontStyle: 'italic'
-                    }}>
-                      No private clubs available. Private clubs require an invitation to join.
-                    </div>
-                  )}
-                </div>
-              )}
-            </div>
-          )}
-
           {/* Public Clubs Section - show for logged-in users */}
           {user && (
             <div style={{ marginTop: user ? '3rem' : '0', marginBottom: '3rem' }}>
@@ -56,6 +56,8 @@ const CreateClubModal: React.FC<CreateClubModalProps> = ({ show, onClose, user,
             joinedAt: new Date().toISOString(),
           },
         ],
+        // The database rules read memberRoles to decide who may see and edit the club
+        memberRoles: { [user.uid]: 'admin' },
         booksRead: [],
         recentActivity: [],
       };
@@ -1,9 +1,12 @@
 import React, { useState, useEffect } from "react";
 import HeaderBar from "./HeaderBar";
-import { User, getAuth, createUserWithEmailAndPassword, updateProfile, signInWithPopup, GoogleAuthProvider } from "firebase/auth";
+import { User, getAuth, createUserWithEmailAndPassword, updateProfile, signInWithPopup, GoogleAuthProvider, sendEmailVerification } from "firebase/auth";
 import { Database, ref, get, update } from "firebase/database";
 import { useNavigate, useSearchParams } from "react-router-dom";
 import { getInviteServiceURL } from "../config/runtimeConfig";
+import { readServiceError, ServiceRequestError } from "../utils/serviceErrors";
+import { acceptClubInvite } from "../utils/clubMembership";
+import { appCheckHeaders } from "../utils/appCheck";
 
 interface SignupProps {
   user: User | null;
@@ -38,6 +41,7 @@ const Signup: React.FC<SignupProps> = ({ user, db }) => {
   const [displayName, setDisplayName] = useState("");
   const [isLoading, setIsLoading] = useState(false);
   const [showPassword, setShowPassword] = useState(false);
+  const [verificationSent, setVerificationSent] = useState(false);
 
   // Validate invite on mount (only if inviteId is present)
   useEffect(() => {
@@ -62,6 +66,7 @@ const Signup: React.FC<SignupProps> = ({ user, db }) => {
           method: 'POST',
           headers: {
             'Content-Type': 'application/json',
+            ...(await appCheckHeaders()),
           },
           body: JSON.stringify({
             inviteId: inviteId,
@@ -70,7 +75,13 @@ const Signup: React.FC<SignupProps> = ({ user, db }) => {
         });
 
         if (!response.ok) {
-          throw new Error('Failed to validate invite');
+          const serviceError = await readServiceError(response);
+          if (serviceError.code === 'invite_not_found' || serviceError.code === 'invite_inactive') {
+            setError("This invite link is not valid or has expired.");
+            setInviteValid(false);
+            return;
+          }
+          throw new Error(serviceError.message);
         }
 
         const data: InviteValidationResponse = await response.json();
@@ -125,51 +136,60 @@ const Signup: React.FC<SignupProps> = ({ user, db }) => {
 
     await update(userRef, updates);
 
-    // If there's a valid invite, add user to that club
+    // If there's a valid invite, join that club through the invite service
     if (inviteValid && inviteData?.clubId && inviteId) {
-      // Add user to club
-      const clubRef = ref(db, `clubs/${inviteData.clubId}`);
-      const clubSnapshot = await get(clubRef);
-      const clubData = clubSnapshot.val() || {};
-
-      const members = clubData.members || [];
-      const userMember = {
-        id: newUser.uid,
-        name: displayNameValue?.trim() || newUser.displayName || newUser.email || 'New Member',
-        img: newUser.photoURL || '',
-        role: 'member',
-        joinedAt: new Date().toISOString()
-      };
-
-      // Check if user is already a member (shouldn't happen, but safety check)
-      if (!members.some((m: any) => m.id === newUser.uid)) {
-        members.push(userMember);
-        await update(clubRef, {
-          members: members,
-          memberCount: members.length
-        });
-      }
+      await joinInvitedClub(newUser);
+    } else {
+      // No invite - just create account and go to profile
+      navigate("/profile");
+    }
+  };
 
-      // Add club to user's clubs array
-      const userClubs = updates.clubs || [];
-      if (!userClubs.includes(inviteData.clubId)) {
-        userClubs.push(inviteData.clubId);
-        await update(userRef, { clubs: userClubs });
-      }
+  // Accepts the invite as member. The invite service only accepts invites for verified
+  // emails, so unverified accounts are sent a verification email first and accept
+  // once they come back and continue.
+  const joinInvitedClub = async (member: User) => {
+    if (!inviteData?.clubId || !inviteId) {
+      return;
+    }
 
-      // Mark invite as accepted
-      const inviteRef = ref(db, `club_invites/${inviteData.clubId}/${inviteId}`);
-      await update(inviteRef, {
-        status: 'accepted',
-        acceptedAt: Date.now(),
-        acceptedBy: newUser.uid
-      });
+    await member.reload();
+    if (!member.emailVerified) {
+      if (!verificationSent) {
+        await sendEmailVerification(member);
+        setVerificationSent(true);
+      }
+      return;
+    }
 
-      // Navigate to the club page
+    try {
+      // Refresh the ID token so it carries the verified email claim
+      await member.getIdToken(true);
+      await acceptClubInvite(inviteData.clubId, inviteId);
       navigate(`/clubs/${inviteData.clubId}`);
-    } else {
-      // No invite - just create account and go to profile
-      navigate("/profile");
+    } catch (err: any) {
+      console.error('Error accepting invite:', err);
+      if (err instanceof ServiceRequestError && err.code === 'invite_inactive') {
+        setError("This invite has already been used or has expired.");
+      } else if (err instanceof ServiceRequestError && err.code === 'forbidden') {
+        setError("This invite was sent to a different email address.");
+      } else {
+        setError(err.message || "Failed to join the club. Please try again.");
+      }
+    }
+  };
+
+  const handleContinueToClub = async () => {
+    if (!user) return;
+    setError("");
+    setIsLoading(true);
+    try {
+      await joinInvitedClub(user);
+    } catch (err: any) {
+      console.error('Error joining club:', err);
+      setError(err.message || "Failed to join the club. Please try again.");
+    } finally {
+      setIsLoading(false);
     }
   };
 
@@ -278,8 +298,8 @@ const Signup: React.FC<SignupProps> = ({ user, db }) => {
     }
   };
 
-  // Redirect if already logged in
-  if (user) {
+  // Redirect if already logged in, unless there is an invite to accept
+  if (user && (!inviteId || (!validating && !inviteValid))) {
     navigate("/profile");
     return null;
   }
@@ -301,6 +321,32 @@ const Signup: React.FC<SignupProps> = ({ user, db }) => {
               </div>
               <p style={{ color: "#6b7280", fontSize: "1.1rem" }}>Validating invite...</p>
             </div>
+          ) : user ? (
+            <>
+              <div className="login-header">
+                <h1 className="login-title">Join {inviteData?.clubName || "Book Clurb"}</h1>
+                <p className="login-subtitle">
+                  {verificationSent
+                    ? `We sent a verification email to ${user.email}. Verify your email, then continue.`
+                    : inviteData?.inviterName && `You've been invited by ${inviteData.inviterName}`}
+                </p>
+              </div>
+
+              {error && (
+                <div className="error-message">
+                  {error}
+                </div>
+              )}
+
+              <button
+                type="button"
+                className="login-button"
+                onClick={handleContinueToClub}
+                disabled={isLoading}
+              >
+                {isLoading ? 'Joining...' : verificationSent ? "I've verified my email" : 'Join Club'}
+              </button>
+            </>
           ) : !inviteId || (inviteId && !inviteValid) ? (
             <>
               {inviteId && !inviteValid ? (
@@ -3,11 +3,13 @@
 
 interface RuntimeConfig {
   inviteServiceURL: string;
+  appCheckSiteKey?: string; // reCAPTCHA v3 site key for Firebase App Check; App Check is off without it
 }
 
 // Default values (used if config.js is not loaded)
 const defaultConfig: RuntimeConfig = {
   inviteServiceURL: process.env.REACT_APP_INVITE_SERVICE_URL || 'http://localhost:8080',
+  appCheckSiteKey: process.env.REACT_APP_APP_CHECK_SITE_KEY || '',
 };
 
 // Access config from window object (injected by config.js)
@@ -18,3 +20,6 @@ export const getInviteServiceURL = (): string => {
   return runtimeConfig.inviteServiceURL;
 };
 
+export const getAppCheckSiteKey = (): string => {
+  return runtimeConfig.appCheckSiteKey || '';
+};
@@ -1,10 +1,22 @@
 import React, { useState, useEffect } from 'react';
-import { Database, ref, update, get } from 'firebase/database';
+import { Database, ref, update, set, remove } from 'firebase/database';
 import { getAuth } from 'firebase/auth';
 import { Club } from '../../../../types';
 import EditBookReadersModal from './EditBookReadersModal';
+import HardcoverImportModal from './HardcoverImportModal';
 import StarRating from './StarRating';
 import { getInviteServiceURL } from '../../../../config/runtimeConfig';
+import { appCheckHeaders } from '../../../../utils/appCheck';
+import {
+  syncClubReadingToHardcover,
+  syncClubRatingsToHardcover,
+  waitForClubRatingsSync,
+  retractReviewFromHardcover,
+  getHardcoverLink,
+  HardcoverBookCandidate,
+} from '../../../../utils/hardcoverSync';
+import { readServiceError, ServiceRequestError } from '../../../../utils/serviceErrors';
+import HardcoverBookPickerModal from './HardcoverBookPickerModal';
 
 interface BooksTabProps {
   club: Club;
@@ -20,38 +32,69 @@ const BooksTab: React.FC<BooksTabProps> = ({ club, userId, db }) => {
   const [editingReviewIndex, setEditingReviewIndex] = useState<number | null>(null);
   const [reviewText, setReviewText] = useState<string>('');
   const [savingReviews, setSavingReviews] = useState<Record<number, boolean>>({});
-  const [hardcoverToken, setHardcoverToken] = useState<string | null>(null);
+  const [isHardcoverLinked, setIsHardcoverLinked] = useState(false);
   const [syncingToHardcover, setSyncingToHardcover] = useState<Record<number, boolean>>({});
   const [hardcoverSyncSuccess, setHardcoverSyncSuccess] = useState<Record<number, boolean>>({});
   const [syncingReviewToHardcover, setSyncingReviewToHardcover] = useState<Record<number, boolean>>({});
   const [hardcoverReviewSyncSuccess, setHardcoverReviewSyncSuccess] = useState<Record<number, boolean>>({});
   const [showHardcoverTooltip, setShowHardcoverTooltip] = useState<Record<number, boolean>>({});
+  const [showHardcoverImport, setShowHardcoverImport] = useState(false);
+  const [syncingClubRatings, setSyncingClubRatings] = useState(false);
+  const [clubRatingsSyncMessage, setClubRatingsSyncMessage] = useState<string | null>(null);
+  // A sync that matched several Hardcover books, waiting for the user to pick one
+  const [hardcoverBookChoice, setHardcoverBookChoice] = useState<{
+    path: string;
+    body: Record<string, unknown>;
+    title: string;
+    candidates: HardcoverBookCandidate[];
+  } | null>(null);
+  const [choosingHardcoverBook, setChoosingHardcoverBook] = useState(false);
 
   // Check if current user is an admin
   const isAdmin = club.members?.some(
     member => member.id === userId && member.role === 'admin'
   );
 
-  // Load Hardcover token on mount
+  // Load Hardcover link status on mount
   useEffect(() => {
-    const loadHardcoverToken = async () => {
-      if (!userId || !db) return;
+    const loadHardcoverLink = async () => {
+      if (!userId) return;
       
       try {
-        const userRef = ref(db, `users/${userId}`);
-        const snapshot = await get(userRef);
-        const userData = snapshot.val();
-        
-        if (userData?.hardcoverApiToken) {
-          setHardcoverToken(userData.hardcoverApiToken);
-        }
+        const status = await getHardcoverLink();
+        setIsHardcoverLinked(status.linked);
       } catch (error) {
-        console.error('Error loading Hardcover token:', error);
+        console.error('Error loading Hardcover link:', error);
       }
     };
 
-    loadHardcoverToken();
-  }, [userId, db]);
+    loadHardcoverLink();
+  }, [userId]);
+
+  // Sync the club history's ratings and reviews to Hardcover, for the user or every linked member
+  const handleSyncClubRatings = async (allMembers: boolean) => {
+    setSyncingClubRatings(true);
+    setClubRatingsSyncMessage(null);
+    try {
+      const started = await syncClubRatingsToHardcover(club.id, allMembers);
+      const job = started.status === 'running'
+        ? await waitForClubRatingsSync(club.id, started.id, progress =>
+            setClubRatingsSyncMessage(`Syncing to Hardcover: ${progress.membersDone} of ${progress.members} members done`))
+        : started;
+      const report = job.report;
+      const synced = report?.synced ?? 0;
+      const parts = [`Synced ${synced} rating${synced !== 1 ? 's' : ''} to Hardcover`];
+      if (report && report.skipped > 0) parts.push(`${report.skipped} skipped`);
+      if (report && report.failed > 0) parts.push(`${report.failed} failed`);
+      if (job.status === 'failed') parts.push('the sync stopped before finishing, try again');
+      setClubRatingsSyncMessage(parts.join(', '));
+    } catch (error: any) {
+      console.error('Error syncing club ratings to Hardcover:', error);
+      setClubRatingsSyncMessage(`Hardcover sync failed: ${error.message}`);
+    } finally {
+      setSyncingClubRatings(false);
+    }
+  };
 
   // Helper function to map user IDs to member names
   const getUserName = (userId: string): string => {
@@ -123,6 +166,16 @@ const BooksTab: React.FC<BooksTabProps> = ({ club, userId, db }) => {
       // Get all member IDs for readBy array
       const memberIds = club.members?.map(m => m.id).filter(Boolean) || [];
 
+      // Mark the finished book read on linked members' Hardcover accounts while it is
+      // still the club's current book; a failed sync shouldn't block moving on
+      if (club.currentBook?.isbn) {
+        try {
+          await syncClubReadingToHardcover(club.id, { completed: true });
+        } catch (error) {
+          console.error('Failed to mark book read on Hardcover:', error);
+        }
+      }
+
       // If there's a current book, move it to booksRead
       if (club.currentBook) {
         const existingBooksRead = club.booksRead || [];
@@ -157,6 +210,21 @@ const BooksTab: React.FC<BooksTabProps> = ({ club, userId, db }) => {
       updates.onDeckBook = null;
 
       await update(clubRef, updates);
+
+      if (updates.currentBook.isbn) {
+        syncClubReadingToHardcover(club.id).catch(error => {
+          if (error instanceof ServiceRequestError && error.code === 'hardcover_book_ambiguous') {
+            setHardcoverBookChoice({
+              path: `/v1/clubs/${encodeURIComponent(club.id)}/hardcover/reading`,
+              body: {},
+              title: updates.currentBook.title,
+              candidates: (error.details as { candidates: HardcoverBookCandidate[] }).candidates,
+            });
+          } else {
+            console.error('Failed to sync new book to Hardcover:', error);
+          }
+        });
+      }
     } catch (error) {
       console.error('Failed to make On Deck book current:', error);
       alert('Failed to make On Deck book current. Please try again.');
@@ -245,25 +313,14 @@ const BooksTab: React.FC<BooksTabProps> = ({ club, userId, db }) => {
 
     setSavingRatings(prev => ({ ...prev, [bookIndex]: true }));
     try {
-      const clubRef = ref(db, `clubs/${club.id}`);
-      const updatedBooksRead = [...club.booksRead];
-      const currentRatings = updatedBooksRead[bookIndex].ratings || {};
-      const book = updatedBooksRead[bookIndex];
-      
-      updatedBooksRead[bookIndex] = {
-        ...updatedBooksRead[bookIndex],
-        ratings: {
-          ...currentRatings,
-          [userId]: rating
-        }
-      };
-      
-      await update(clubRef, {
-        booksRead: updatedBooksRead
-      });
+      const book = club.booksRead[bookIndex];
+
+      // Members may only write their own rating; the rest of booksRead is admin-only
+      await set(ref(db, `clubs/${club.id}/booksRead/${bookIndex}/ratings/${userId}`), rating);
 
-      // Always sync to Hardcover if token exists and book has ISBN
-      if (hardcoverToken && book.isbn) {
+      // Always sync to Hardcover if the account is linked; books without a catalogued
+      // ISBN are matched by title and author
+      if (isHardcoverLinked && (book.isbn || book.title)) {
         setSyncingToHardcover(prev => ({ ...prev, [bookIndex]: true }));
         setHardcoverSyncSuccess(prev => ({ ...prev, [bookIndex]: false }));
         try {
@@ -283,12 +340,16 @@ const BooksTab: React.FC<BooksTabProps> = ({ club, userId, db }) => {
             method: 'POST',
             headers: {
               'Content-Type': 'application/json',
-              'Authorization': `Bearer ${idToken}`
+              'Authorization': `Bearer ${idToken}`,
+              ...(await appCheckHeaders())
             },
             body: JSON.stringify({
-              isbn: book.isbn,
+              isbn: book.isbn || undefined,
+              title: book.title,
+              author: book.author || undefined,
               rating: rating,
-              reviewText: currentReview || undefined
+              reviewText: currentReview || undefined,
+              clubId: club.id
             })
           });
 
@@ -305,8 +366,17 @@ const BooksTab: React.FC<BooksTabProps> = ({ club, userId, db }) => {
               // Don't show error to user, just log it
             }
           } else {
-            const errorText = await response.text();
-            console.error('Failed to sync rating to Hardcover:', errorText);
+            const error = await readServiceError(response);
+            if (error.code === 'hardcover_book_ambiguous') {
+              setHardcoverBookChoice({
+                path: '/SyncRatingToHardcover',
+                body: { title: book.title, rating, reviewText: currentReview || undefined, clubId: club.id },
+                title: book.title,
+                candidates: (error.details as { candidates: HardcoverBookCandidate[] }).candidates,
+              });
+            } else {
+              console.error('Failed to sync rating to Hardcover:', error.message);
+            }
           }
         } catch (error) {
           console.error('Error syncing to Hardcover:', error);
@@ -322,6 +392,15 @@ const BooksTab: React.FC<BooksTabProps> = ({ club, userId, db }) => {
     }
   };
 
+  // Remove a deleted club review from Hardcover too, keeping the rating. Failures are
+  // only logged; the club review is already gone.
+  const retractHardcoverReview = (book: { isbn?: string; title: string; author?: string }) => {
+    if (!isHardcoverLinked || !(book.isbn || book.title)) return;
+    retractReviewFromHardcover(book, { keepRating: true }).catch(error => {
+      console.error('Failed to remove review from Hardcover:', error);
+    });
+  };
+
   // Function to handle review changes
   const handleReviewSave = async (bookIndex: number) => {
     if (!club.booksRead || bookIndex < 0 || bookIndex >= club.booksRead.length) {
@@ -330,42 +409,22 @@ const BooksTab: React.FC<BooksTabProps> = ({ club, userId, db }) => {
 
     setSavingReviews(prev => ({ ...prev, [bookIndex]: true }));
     try {
-      const clubRef = ref(db, `clubs/${club.id}`);
-      const updatedBooksRead = [...club.booksRead];
-      const currentReviews = updatedBooksRead[bookIndex].reviews || {};
-      
-      // If review text is empty, remove the review
+      const book = club.booksRead[bookIndex];
+      const currentReviews = book.reviews || {};
+
+      // Members may only write their own review; an empty review removes it
+      const reviewRef = ref(db, `clubs/${club.id}/booksRead/${bookIndex}/reviews/${userId}`);
       if (reviewText.trim() === '') {
-        const { [userId]: _, ...rest } = currentReviews;
-        
-        if (Object.keys(rest).length > 0) {
-          // If there are other reviews, keep the reviews property with remaining reviews
-          updatedBooksRead[bookIndex] = {
-            ...updatedBooksRead[bookIndex],
-            reviews: rest
-          };
-        } else {
-          // If no reviews left, remove the reviews property entirely by omitting it
-          const { reviews: _, ...bookWithoutReviews } = updatedBooksRead[bookIndex];
-          updatedBooksRead[bookIndex] = bookWithoutReviews as any;
-        }
+        await remove(reviewRef);
       } else {
-        updatedBooksRead[bookIndex] = {
-          ...updatedBooksRead[bookIndex],
-          reviews: {
-            ...currentReviews,
-            [userId]: reviewText.trim()
-          }
-        };
+        await set(reviewRef, reviewText.trim());
       }
       
-      await update(clubRef, {
-        booksRead: updatedBooksRead
-      });
-      
-      // Always sync review to Hardcover if token exists and book has ISBN
-      const book = updatedBooksRead[bookIndex];
-      if (hardcoverToken && book.isbn && reviewText.trim() !== '') {
+      // Always sync review to Hardcover if the account is linked
+      if (reviewText.trim() === '' && currentReviews[userId]) {
+        retractHardcoverReview(book);
+      }
+      if (isHardcoverLinked && (book.isbn || book.title) && reviewText.trim() !== '') {
         setSyncingReviewToHardcover(prev => ({ ...prev, [bookIndex]: true }));
         setHardcoverReviewSyncSuccess(prev => ({ ...prev, [bookIndex]: false }));
         try {
@@ -385,12 +444,16 @@ const BooksTab: React.FC<BooksTabProps> = ({ club, userId, db }) => {
             method: 'POST',
             headers: {
               'Content-Type': 'application/json',
-              'Authorization': `Bearer ${idToken}`
+              'Authorization': `Bearer ${idToken}`,
+              ...(await appCheckHeaders())
             },
             body: JSON.stringify({
-              isbn: book.isbn,
+              isbn: book.isbn || undefined,
+              title: book.title,
+              author: book.author || undefined,
               reviewText: reviewText.trim(),
-              rating: currentRating
+              rating: currentRating,
+              clubId: club.id
             })
           });
 
@@ -407,8 +470,17 @@ const BooksTab: React.FC<BooksTabProps> = ({ club, userId, db }) => {
               // Don't show error to user, just log it
             }
           } else {
-            const errorText = await response.text();
-            console.error('Failed to sync review to Hardcover:', errorText);
+            const error = await readServiceError(response);
+            if (error.code === 'hardcover_book_ambiguous') {
+              setHardcoverBookChoice({
+                path: '/SyncReviewToHardcover',
+                body: { title: book.title, reviewText: reviewText.trim(), rating: currentRating, clubId: club.id },
+                title: book.title,
+                candidates: (error.details as { candidates: HardcoverBookCandidate[] }).candidates,
+              });
+            } else {
+              console.error('Failed to sync review to Hardcover:', error.message);
+            }
           }
         } catch (error) {
           console.error('Error syncing review to Hardcover:', error);
@@ -427,6 +499,40 @@ const BooksTab: React.FC<BooksTabProps> = ({ club, userId, db }) => {
     }
   };
 
+  // Retry a sync that matched several Hardcover books with the book the user picked
+  const handleChooseHardcoverBook = async (hardcoverBookId: number) => {
+    if (!hardcoverBookChoice) return;
+
+    setChoosingHardcoverBook(true);
+    try {
+      const currentUser = getAuth().currentUser;
+      if (!currentUser) {
+        throw new Error('User not authenticated');
+      }
+      const idToken = await currentUser.getIdToken();
+
+      const response = await fetch(`${getInviteServiceURL()}${hardcoverBookChoice.path}`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+          'Authorization': `Bearer ${idToken}`,
+          ...(await appCheckHeaders())
+        },
+        body: JSON.stringify({ ...hardcoverBookChoice.body, hardcoverBookId })
+      });
+      if (!response.ok) {
+        const error = await readServiceError(response);
+        throw new Error(error.message);
+      }
+      setHardcoverBookChoice(null);
+    } catch (error) {
+      console.error('Error syncing to Hardcover:', error);
+      alert('Failed to sync to Hardcover. Please try again.');
+    } finally {
+      setChoosingHardcoverBook(false);
+    }
+  };
+
   // Function to start editing a review
   const handleStartEditReview = (bookIndex: number) => {
     if (!club.booksRead || bookIndex < 0 || bookIndex >= club.booksRead.length) {
@@ -451,28 +557,9 @@ const BooksTab: React.FC<BooksTabProps> = ({ club, userId, db }) => {
 
     setSavingReviews(prev => ({ ...prev, [bookIndex]: true }));
     try {
-      const clubRef = ref(db, `clubs/${club.id}`);
-      const updatedBooksRead = [...club.booksRead];
-      const currentReviews = updatedBooksRead[bookIndex].reviews || {};
-      
       // Remove the user's review
-      const { [userId]: _, ...rest } = currentReviews;
-      
-      if (Object.keys(rest).length > 0) {
-        // If there are other reviews, keep the reviews property with remaining reviews
-        updatedBooksRead[bookIndex] = {
-          ...updatedBooksRead[bookIndex],
-          reviews: rest
-        };
-      } else {
-        // If no reviews left, remove the reviews property entirely by omitting it
-        const { reviews: _, ...bookWithoutReviews } = updatedBooksRead[bookIndex];
-        updatedBooksRead[bookIndex] = bookWithoutReviews as any;
-      }
-      
-      await update(clubRef, {
-        booksRead: updatedBooksRead
-      });
+      await remove(ref(db, `clubs/${club.id}/booksRead/${bookIndex}/reviews/${userId}`));
+      retractHardcoverReview(club.booksRead[bookIndex]);
       
       setEditingReviewIndex(null);
       setReviewText('');
@@ -613,7 +700,71 @@ const BooksTab: React.FC<BooksTabProps> = ({ club, userId, db }) => {
         </h3>
         <p style={{ color: '#666', marginBottom: '2rem' }}>
           Track the books we've read together as a club.
+          {isHardcoverLinked && club.booksRead?.some(book => book.isbn) && (
+            <button
+              onClick={() => setShowHardcoverImport(true)}
+              style={{
+                marginLeft: '1rem',
+                padding: '0.4rem 0.8rem',
+                fontSize: '0.85rem',
+                fontWeight: '500',
+                background: 'white',
+                color: '#9333EA',
+                border: '1px solid #9333EA',
+                borderRadius: '6px',
+                cursor: 'pointer',
+              }}
+            >
+              Import ratings from Hardcover
+            </button>
+          )}
+          {isHardcoverLinked && club.booksRead && club.booksRead.length > 0 && (
+            <button
+              onClick={() => handleSyncClubRatings(false)}
+              disabled={syncingClubRatings}
+              style={{
+                marginLeft: '0.5rem',
+                padding: '0.4rem 0.8rem',
+                fontSize: '0.85rem',
+                fontWeight: '500',
+                background: 'white',
+                color: '#9333EA',
+                border: '1px solid #9333EA',
+                borderRadius: '6px',
+                cursor: syncingClubRatings ? 'not-allowed' : 'pointer',
+                opacity: syncingClubRatings ? 0.6 : 1,
+              }}
+            >
+              {syncingClubRatings ? 'Syncing...' : 'Sync my ratings to Hardcover'}
+            </button>
+          )}
+          {isAdmin && club.booksRead && club.booksRead.length > 0 && (
+            <button
+              onClick={() => handleSyncClubRatings(true)}
+              disabled={syncingClubRatings}
+              title="Syncs your ratings and reviews, and other members' ratings"
+              style={{
+                marginLeft: '0.5rem',
+                padding: '0.4rem 0.8rem',
+                fontSize: '0.85rem',
+                fontWeight: '500',
+                background: 'white',
+                color: '#9333EA',
+                border: '1px solid #9333EA',
+                borderRadius: '6px',
+                cursor: syncingClubRatings ? 'not-allowed' : 'pointer',
+                opacity: syncingClubRatings ? 0.6 : 1,
+              }}
+            >
+              {syncingClubRatings ? 'Syncing...' : 'Sync all members to Hardcover'}
+            </button>
+          )}
         </p>
+        {clubRatingsSyncMessage && (
+          <p style={{ color: '#666', fontSize: '0.85rem', marginTop: '-1.5rem', marginBottom: '1.5rem' }}>
+            {clubRatingsSyncMessage}
+          </p>
+        )}
         
         {club.booksRead && club.booksRead.length > 0 ? (
           <div style={{ display: 'grid', gap: '1rem' }}>
@@ -796,9 +947,9 @@ const BooksTab: React.FC<BooksTabProps> = ({ club, userId, db }) => {
                                   rating={getUserRating(book)}
                                   onRatingChange={(rating) => handleRatingChange(index, rating)}
                                   size="small"
-                                  color={hardcoverToken && book.isbn ? '#9333EA' : undefined}
+                                  color={isHardcoverLinked && (book.isbn || book.title) ? '#9333EA' : undefined}
                                 />
-                                {hardcoverToken && book.isbn && (
+                                {isHardcoverLinked && (book.isbn || book.title) && (
                                   <div
                                     style={{
                                       position: 'relative',
@@ -1257,6 +1408,24 @@ const BooksTab: React.FC<BooksTabProps> = ({ club, userId, db }) => {
           onDelete={() => handleDeleteBook(editingBookIndex)}
         />
       )}
+
+      {/* Hardcover Book Picker Modal */}
+      {hardcoverBookChoice && (
+        <HardcoverBookPickerModal
+          title={hardcoverBookChoice.title}
+          candidates={hardcoverBookChoice.candidates}
+          saving={choosingHardcoverBook}
+          onClose={() => setHardcoverBookChoice(null)}
+          onChoose={handleChooseHardcoverBook}
+        />
+      )}
+
+      {/* Hardcover Import Modal */}
+      <HardcoverImportModal
+        clubId={club.id}
+        isOpen={showHardcoverImport}
+        onClose={() => setShowHardcoverImport(false)}
+      />
     </div>
   );
 };
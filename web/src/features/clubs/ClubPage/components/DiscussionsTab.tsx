@@ -85,25 +85,15 @@ const DiscussionsTab: React.FC<DiscussionsTabProps> = ({ club, user, db }) => {
       timestamp: Date.now(),
     };
 
-    // Update the club.meetings structure
-    const updatedMeetings = [...(club.meetings || [])];
-    const meeting = updatedMeetings[meetingIndex];
-    
-    if (!meeting.reflections) {
-      meeting.reflections = [];
-    }
-    
-    // Remove existing reflection from this user if it exists
-    meeting.reflections = meeting.reflections.filter(r => r.userId !== user.uid);
-    
-    // Add the new reflection
-    meeting.reflections.push(newReflection);
+    // Write only this user's reflection: the database rules let members write their
+    // own reflection slot but not the rest of the meetings
+    const reflections = club.meetings?.[meetingIndex]?.reflections || [];
+    const existingIndex = reflections.findIndex(r => r && r.userId === user.uid);
+    const reflectionIndex = existingIndex === -1 ? reflections.length : existingIndex;
 
     // Save to Firebase
-    const clubRef = ref(db, `clubs/${club.id}`);
-    update(clubRef, {
-      meetings: updatedMeetings
-    }).then(() => {
+    const reflectionRef = ref(db, `clubs/${club.id}/meetings/${meetingIndex}/reflections/${reflectionIndex}`);
+    set(reflectionRef, newReflection).then(() => {
       setSaved(true);
       setTimeout(() => setSaved(false), 1500);
       
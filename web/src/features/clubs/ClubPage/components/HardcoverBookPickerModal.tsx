@@ -0,0 +1,142 @@
+import React from 'react';
+import { motion, AnimatePresence } from 'framer-motion';
+import { HardcoverBookCandidate } from '../../../../utils/hardcoverSync';
+
+interface HardcoverBookPickerModalProps {
+  title: string;
+  candidates: HardcoverBookCandidate[];
+  saving: boolean;
+  onClose: () => void;
+  onChoose: (hardcoverBookId: number) => void;
+}
+
+const HardcoverBookPickerModal: React.FC<HardcoverBookPickerModalProps> = ({
+  title,
+  candidates,
+  saving,
+  onClose,
+  onChoose,
+}) => {
+  return (
+    <AnimatePresence>
+      <motion.div
+        initial={{ opacity: 0 }}
+        animate={{ opacity: 1 }}
+        exit={{ opacity: 0 }}
+        style={{
+          position: 'fixed',
+          top: 0,
+          left: 0,
+          right: 0,
+          bottom: 0,
+          background: 'rgba(0,0,0,0.5)',
+          display: 'flex',
+          alignItems: 'center',
+          justifyContent: 'center',
+          zIndex: 1000,
+        }}
+        onClick={onClose}
+      >
+        <motion.div
+          initial={{ scale: 0.8, opacity: 0 }}
+          animate={{ scale: 1, opacity: 1 }}
+          exit={{ scale: 0.8, opacity: 0 }}
+          style={{
+            background: 'white',
+            borderRadius: '12px',
+            padding: '2rem',
+            minWidth: '400px',
+            maxWidth: '600px',
+            maxHeight: '90vh',
+            overflow: 'auto',
+            boxShadow: '0 10px 30px rgba(0,0,0,0.3)',
+          }}
+          onClick={(e) => e.stopPropagation()}
+        >
+          <div style={{
+            fontSize: '1.5rem',
+            fontWeight: 'bold',
+            marginBottom: '0.5rem',
+            color: '#333'
+          }}>
+            Which book on Hardcover?
+          </div>
+          <p style={{ color: '#666', fontSize: '0.9rem', marginBottom: '1.5rem' }}>
+            We couldn't find this edition of <strong>{title}</strong> on Hardcover by ISBN.
+            Pick the matching book to finish syncing.
+          </p>
+
+          <div style={{ marginBottom: '1.5rem' }}>
+            {candidates.map(candidate => (
+              <button
+                key={candidate.id}
+                onClick={() => onChoose(candidate.id)}
+                disabled={saving}
+                style={{
+                  display: 'block',
+                  width: '100%',
+                  textAlign: 'left',
+                  padding: '0.75rem',
+                  marginBottom: '0.5rem',
+                  background: 'white',
+                  border: '1px solid #e9ecef',
+                  borderRadius: '6px',
+                  cursor: saving ? 'not-allowed' : 'pointer',
+                  opacity: saving ? 0.6 : 1,
+                  transition: 'background-color 0.2s ease',
+                }}
+                onMouseEnter={(e) => {
+                  if (!saving) {
+                    e.currentTarget.style.backgroundColor = '#f3e8ff';
+                  }
+                }}
+                onMouseLeave={(e) => {
+                  e.currentTarget.style.backgroundColor = 'white';
+                }}
+              >
+                <div style={{ fontWeight: 600, color: '#333', fontSize: '0.95rem' }}>
+                  {candidate.title}
+                  {candidate.releaseYear && (
+                    <span style={{ fontWeight: 400, color: '#888' }}> ({candidate.releaseYear})</span>
+                  )}
+                </div>
+                {candidate.authors.length > 0 && (
+                  <div style={{ color: '#666', fontSize: '0.85rem' }}>
+                    by {candidate.authors.join(', ')}
+                  </div>
+                )}
+                {candidate.readers !== undefined && (
+                  <div style={{ color: '#888', fontSize: '0.75rem', marginTop: '0.25rem' }}>
+                    {candidate.readers.toLocaleString()} reader{candidate.readers !== 1 ? 's' : ''} on Hardcover
+                  </div>
+                )}
+              </button>
+            ))}
+          </div>
+
+          <div style={{ display: 'flex', justifyContent: 'flex-end' }}>
+            <button
+              onClick={onClose}
+              disabled={saving}
+              style={{
+                padding: '0.75rem 1.5rem',
+                fontSize: '1rem',
+                fontWeight: '500',
+                background: '#f8f9fa',
+                color: '#495057',
+                border: '1px solid #dee2e6',
+                borderRadius: '8px',
+                cursor: saving ? 'not-allowed' : 'pointer',
+                opacity: saving ? 0.6 : 1,
+              }}
+            >
+              {saving ? 'Syncing...' : 'Skip'}
+            </button>
+          </div>
+        </motion.div>
+      </motion.div>
+    </AnimatePresence>
+  );
+};
+
+export default HardcoverBookPickerModal;
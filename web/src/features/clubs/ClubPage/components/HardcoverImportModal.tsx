@@ -0,0 +1,296 @@
+import React, { useState, useEffect } from 'react';
+import { motion, AnimatePresence } from 'framer-motion';
+import StarRating from './StarRating';
+import {
+  HardcoverImportItem,
+  previewHardcoverImport,
+  importFromHardcover,
+} from '../../../../utils/hardcoverSync';
+
+interface HardcoverImportModalProps {
+  clubId: string;
+  isOpen: boolean;
+  onClose: () => void;
+}
+
+const HardcoverImportModal: React.FC<HardcoverImportModalProps> = ({
+  clubId,
+  isOpen,
+  onClose,
+}) => {
+  const [items, setItems] = useState<HardcoverImportItem[]>([]);
+  const [selectedIsbns, setSelectedIsbns] = useState<string[]>([]);
+  const [overwrite, setOverwrite] = useState(false);
+  const [loading, setLoading] = useState(false);
+  const [importing, setImporting] = useState(false);
+  const [error, setError] = useState<string | null>(null);
+  const [importedCount, setImportedCount] = useState<number | null>(null);
+
+  // Load matches from Hardcover when the modal opens
+  useEffect(() => {
+    if (!isOpen) return;
+
+    setLoading(true);
+    setError(null);
+    setImportedCount(null);
+    previewHardcoverImport(clubId)
+      .then(result => {
+        setItems(result.items);
+        setSelectedIsbns(
+          result.items
+            .filter(item => item.canImportRating || item.canImportReview)
+            .map(item => item.isbn)
+        );
+      })
+      .catch(err => setError(err.message))
+      .finally(() => setLoading(false));
+  }, [isOpen, clubId]);
+
+  const canImport = (item: HardcoverImportItem) =>
+    item.canImportRating || item.canImportReview ||
+    (overwrite && (item.rating !== undefined || item.review !== undefined));
+
+  const toggleItem = (isbn: string) => {
+    setSelectedIsbns(prev =>
+      prev.includes(isbn) ? prev.filter(id => id !== isbn) : [...prev, isbn]
+    );
+  };
+
+  const selectedItems = items.filter(item => selectedIsbns.includes(item.isbn) && canImport(item));
+
+  const handleImport = async () => {
+    const isbns = selectedItems.map(item => item.isbn);
+    if (isbns.length === 0) return;
+
+    setImporting(true);
+    setError(null);
+    try {
+      const result = await importFromHardcover(clubId, isbns, overwrite);
+      setItems(result.items);
+      setSelectedIsbns([]);
+      setImportedCount(result.imported);
+    } catch (err: any) {
+      setError(err.message);
+    } finally {
+      setImporting(false);
+    }
+  };
+
+  return (
+    <AnimatePresence>
+      {isOpen && (
+        <motion.div
+          initial={{ opacity: 0 }}
+          animate={{ opacity: 1 }}
+          exit={{ opacity: 0 }}
+          style={{
+            position: 'fixed',
+            top: 0,
+            left: 0,
+            right: 0,
+            bottom: 0,
+            background: 'rgba(0,0,0,0.5)',
+            display: 'flex',
+            alignItems: 'center',
+            justifyContent: 'center',
+            zIndex: 1000,
+          }}
+          onClick={onClose}
+        >
+          <motion.div
+            initial={{ scale: 0.8, opacity: 0 }}
+            animate={{ scale: 1, opacity: 1 }}
+            exit={{ scale: 0.8, opacity: 0 }}
+            style={{
+              background: 'white',
+              borderRadius: '12px',
+              padding: '2rem',
+              minWidth: '400px',
+              maxWidth: '600px',
+              maxHeight: '90vh',
+              overflow: 'auto',
+              boxShadow: '0 10px 30px rgba(0,0,0,0.3)',
+            }}
+            onClick={(e) => e.stopPropagation()}
+          >
+            <div style={{
+              fontSize: '1.5rem',
+              fontWeight: 'bold',
+              marginBottom: '0.5rem',
+              color: '#333'
+            }}>
+              Import from Hardcover
+            </div>
+            <p style={{ color: '#666', fontSize: '0.9rem', marginBottom: '1.5rem' }}>
+              Copy ratings and reviews from your Hardcover shelf onto books the club has read.
+            </p>
+
+            {error && (
+              <div style={{
+                color: '#dc3545',
+                marginBottom: '1rem',
+                padding: '0.75rem',
+                background: '#f8d7da',
+                borderRadius: '6px',
+                fontSize: '0.9rem'
+              }}>
+                {error}
+              </div>
+            )}
+
+            {importedCount !== null && (
+              <div style={{
+                color: '#155724',
+                marginBottom: '1rem',
+                padding: '0.75rem',
+                background: '#d4edda',
+                borderRadius: '6px',
+                fontSize: '0.9rem'
+              }}>
+                Imported {importedCount} book{importedCount !== 1 ? 's' : ''} from Hardcover
+              </div>
+            )}
+
+            {loading ? (
+              <div style={{ color: '#666', padding: '1rem', textAlign: 'center' }}>
+                Loading your Hardcover shelf...
+              </div>
+            ) : items.length === 0 ? (
+              <div style={{ color: '#666', padding: '1rem', textAlign: 'center' }}>
+                None of the club's books are on your Hardcover shelf.
+              </div>
+            ) : (
+              <div style={{
+                maxHeight: '300px',
+                overflowY: 'auto',
+                marginBottom: '1rem',
+                padding: '0.5rem',
+                border: '1px solid #e9ecef',
+                borderRadius: '8px',
+                background: '#f8f9fa'
+              }}>
+                {items.map(item => {
+                  const importable = canImport(item);
+                  const isSelected = importable && selectedIsbns.includes(item.isbn);
+                  return (
+                    <label
+                      key={item.isbn}
+                      style={{
+                        display: 'flex',
+                        alignItems: 'flex-start',
+                        padding: '0.75rem',
+                        marginBottom: '0.5rem',
+                        background: isSelected ? '#e7f3ff' : 'white',
+                        border: isSelected ? '2px solid #667eea' : '1px solid #e9ecef',
+                        borderRadius: '6px',
+                        cursor: importable ? 'pointer' : 'default',
+                        opacity: importable ? 1 : 0.6,
+                      }}
+                    >
+                      <input
+                        type="checkbox"
+                        checked={isSelected}
+                        disabled={!importable}
+                        onChange={() => toggleItem(item.isbn)}
+                        style={{ marginRight: '0.75rem', width: '18px', height: '18px' }}
+                      />
+                      <div style={{ flex: 1 }}>
+                        <div style={{ fontWeight: 600, color: '#333', fontSize: '0.95rem' }}>
+                          {item.title}
+                        </div>
+                        {item.rating !== undefined && (
+                          <div style={{ display: 'flex', alignItems: 'center', gap: '0.5rem', marginTop: '0.25rem' }}>
+                            <StarRating rating={item.rating} readOnly size="small" color="#9333EA" />
+                            {item.clubRating !== undefined && item.clubRating !== item.rating && (
+                              <span style={{ fontSize: '0.8rem', color: '#666' }}>
+                                (club: {item.clubRating})
+                              </span>
+                            )}
+                          </div>
+                        )}
+                        {item.review && (
+                          <div style={{ fontSize: '0.85rem', color: '#555', marginTop: '0.25rem' }}>
+                            {item.review.length > 120 ? `${item.review.slice(0, 120)}...` : item.review}
+                          </div>
+                        )}
+                        {item.finishedAt && (
+                          <div style={{ fontSize: '0.75rem', color: '#888', marginTop: '0.25rem' }}>
+                            Finished {item.finishedAt}
+                          </div>
+                        )}
+                        {!importable && (
+                          <div style={{ fontSize: '0.75rem', color: '#888', marginTop: '0.25rem' }}>
+                            Already in the club
+                          </div>
+                        )}
+                      </div>
+                    </label>
+                  );
+                })}
+              </div>
+            )}
+
+            {items.length > 0 && (
+              <label style={{
+                display: 'flex',
+                alignItems: 'center',
+                gap: '0.5rem',
+                fontSize: '0.9rem',
+                color: '#666',
+                marginBottom: '1.5rem'
+              }}>
+                <input
+                  type="checkbox"
+                  checked={overwrite}
+                  onChange={(e) => setOverwrite(e.target.checked)}
+                />
+                Replace ratings and reviews I already left in the club
+              </label>
+            )}
+
+            <div style={{ display: 'flex', gap: '1rem', justifyContent: 'flex-end' }}>
+              <button
+                onClick={onClose}
+                disabled={importing}
+                style={{
+                  padding: '0.75rem 1.5rem',
+                  fontSize: '1rem',
+                  fontWeight: '500',
+                  background: '#f8f9fa',
+                  color: '#495057',
+                  border: '1px solid #dee2e6',
+                  borderRadius: '8px',
+                  cursor: importing ? 'not-allowed' : 'pointer',
+                  opacity: importing ? 0.6 : 1,
+                }}
+              >
+                Close
+              </button>
+              <button
+                onClick={handleImport}
+                disabled={importing || loading || selectedItems.length === 0}
+                style={{
+                  padding: '0.75rem 1.5rem',
+                  fontSize: '1rem',
+                  fontWeight: '500',
+                  background: importing || selectedItems.length === 0
+                    ? '#ccc'
+                    : 'linear-gradient(135deg, #667eea 0%, #764ba2 100%)',
+                  color: 'white',
+                  border: 'none',
+                  borderRadius: '8px',
+                  cursor: importing || selectedItems.length === 0 ? 'not-allowed' : 'pointer',
+                  opacity: importing ? 0.6 : 1,
+                }}
+              >
+                {importing ? 'Importing...' : `Import ${selectedItems.length} Book${selectedItems.length !== 1 ? 's' : ''}`}
+              </button>
+            </div>
+          </motion.div>
+        </motion.div>
+      )}
+    </AnimatePresence>
+  );
+};
+
+export default HardcoverImportModal;
@@ -3,6 +3,8 @@ import { User, getAuth } from 'firebase/auth';
 import { Database, ref, push, set } from 'firebase/database';
 import { Club } from '../../../../types';
 import { getInviteServiceURL } from '../../../../config/runtimeConfig';
+import { appCheckHeaders } from '../../../../utils/appCheck';
+import { readServiceError } from '../../../../utils/serviceErrors';
 
 interface MembersTabProps {
   club: Club;
@@ -82,7 +84,8 @@ const MembersTab: React.FC<MembersTabProps> = ({ club, user, db }) => {
         method: 'POST',
         headers: {
           'Content-Type': 'application/json',
-          'Authorization': `Bearer ${idToken}`
+          'Authorization': `Bearer ${idToken}`,
+          ...(await appCheckHeaders())
         },
         body: JSON.stringify({
           email: inviteEmail,
@@ -94,15 +97,9 @@ const MembersTab: React.FC<MembersTabProps> = ({ club, user, db }) => {
       });
 
       if (!response.ok) {
-        const errorText = await response.text();
-        console.error('Invite service error response:', errorText);
-        let errorData;
-        try {
-          errorData = JSON.parse(errorText);
-        } catch {
-          errorData = { message: errorText || response.statusText };
-        }
-        throw new Error(errorData.message || 'Failed to send invite email');
+        const serviceError = await readServiceError(response);
+        console.error('Invite service error response:', serviceError);
+        throw new Error(serviceError.message || 'Failed to send invite email');
       }
 
       const result = await response.json();
@@ -5,6 +5,7 @@ import { Club, GoogleBooksVolume } from '../../../../types';
 import OnDeckBookCard from './OnDeckBookCard';
 import ReadingProgressTracker, { ReadingProgressTrackerRef } from './ReadingProgressTracker';
 import ReadingScheduleDisplay from './ReadingScheduleDisplay';
+import { syncClubReadingToHardcover } from '../../../../utils/hardcoverSync';
 
 interface OverviewTabProps {
   club: Club;
@@ -35,6 +36,14 @@ const OverviewTab: React.FC<OverviewTabProps> = ({ club, user, db }) => {
   
   const progressTrackerRef = useRef<ReadingProgressTrackerRef>(null);
 
+  // Mirror the club's progress to linked members' Hardcover accounts in the background
+  const syncProgressToHardcover = (isbn = club.currentBook?.isbn) => {
+    if (!isbn) return;
+    syncClubReadingToHardcover(club.id).catch(error => {
+      console.error('Failed to sync reading progress to Hardcover:', error);
+    });
+  };
+
   // Check if current user is an admin
   const isAdmin = user && club.members?.some(
     member => member.id === user.uid && member.role === 'admin'
@@ -106,6 +115,7 @@ const OverviewTab: React.FC<OverviewTabProps> = ({ club, user, db }) => {
       await update(clubRef, {
         currentBook: bookData
       });
+      syncProgressToHardcover(bookData.isbn);
 
       // Reset form
       setShowAddBook(false);
@@ -847,6 +857,7 @@ const OverviewTab: React.FC<OverviewTabProps> = ({ club, user, db }) => {
                     totalPages,
                     percentage
                   });
+                  syncProgressToHardcover();
                 }}
               />
             )}
@@ -931,6 +942,7 @@ const OverviewTab: React.FC<OverviewTabProps> = ({ club, user, db }) => {
                     totalPages,
                     percentage
                   });
+                  syncProgressToHardcover();
                 } else {
                   // Calculate the target pages based on what's set
                   let targetPages = 0;
@@ -968,6 +980,7 @@ const OverviewTab: React.FC<OverviewTabProps> = ({ club, user, db }) => {
                     totalPages,
                     percentage
                   });
+                  syncProgressToHardcover();
                 }
               }}
             />
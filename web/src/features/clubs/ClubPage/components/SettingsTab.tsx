@@ -1,8 +1,14 @@
 import React, { useState, useEffect } from 'react';
 import { useNavigate } from 'react-router-dom';
 import { User } from 'firebase/auth';
-import { Database, ref, update, remove, get } from 'firebase/database';
+import { Database, ref, update } from 'firebase/database';
 import { Club } from '../../../../types';
+import {
+  HardcoverSyncSettings,
+  getClubHardcoverSyncSettings,
+  updateClubHardcoverSyncSettings,
+} from '../../../../utils/hardcoverSync';
+import { deleteClub, updateClubMemberRole } from '../../../../utils/clubMembership';
 
 interface SettingsTabProps {
   club: Club;
@@ -41,6 +47,17 @@ const SettingsTab: React.FC<SettingsTabProps> = ({ club, user, db }) => {
     setIsPublic(club.isPublic ?? false);
   }, [club]);
 
+  // Hardcover sync settings, applied on top of each member's own
+  const [hardcoverSettings, setHardcoverSettings] = useState<HardcoverSyncSettings>({});
+  const [savingHardcoverSettings, setSavingHardcoverSettings] = useState(false);
+
+  useEffect(() => {
+    if (!isAdmin) return;
+    getClubHardcoverSyncSettings(club.id)
+      .then(setHardcoverSettings)
+      .catch(error => console.error('Failed to load Hardcover sync settings:', error));
+  }, [club.id, isAdmin]);
+
   // If not admin, show access denied message
   if (!isAdmin) {
     return (
@@ -103,6 +120,22 @@ const SettingsTab: React.FC<SettingsTabProps> = ({ club, user, db }) => {
   };
 
 
+  const handleSaveHardcoverSettings = async () => {
+    setSavingHardcoverSettings(true);
+    setMessage(null);
+
+    try {
+      setHardcoverSettings(await updateClubHardcoverSyncSettings(club.id, hardcoverSettings));
+      setMessage({ type: 'success', text: 'Hardcover settings saved successfully!' });
+      setTimeout(() => setMessage(null), 3000);
+    } catch (error) {
+      console.error('Failed to save Hardcover sync settings:', error);
+      setMessage({ type: 'error', text: 'Failed to save Hardcover settings. Please try again.' });
+    } finally {
+      setSavingHardcoverSettings(false);
+    }
+  };
+
   const handleRoleChange = async (memberId: string, newRole: 'admin' | 'member') => {
     if (!club.members) return;
 
@@ -120,14 +153,7 @@ const SettingsTab: React.FC<SettingsTabProps> = ({ club, user, db }) => {
     setMessage(null);
 
     try {
-      const clubRef = ref(db, `clubs/${club.id}`);
-      const updatedMembers = club.members.map(member => 
-        member.id === memberId 
-          ? { ...member, role: newRole }
-          : member
-      );
-
-      await update(clubRef, { members: updatedMembers });
+      await updateClubMemberRole(club.id, memberId, newRole);
       setMessage({ type: 'success', text: `Member role updated to ${newRole}` });
       setTimeout(() => setMessage(null), 3000);
     } catch (error) {
@@ -146,24 +172,8 @@ const SettingsTab: React.FC<SettingsTabProps> = ({ club, user, db }) => {
     setMessage(null);
 
     try {
-      // Remove club ID from all members' clubs arrays
-      const memberPromises = club.members.map(async (member) => {
-        if (!member.id) return;
-        
-        const userRef = ref(db, `users/${member.id}`);
-        const userSnapshot = await get(userRef);
-        const userData = userSnapshot.val() || {};
-        const userClubs = userData.clubs || [];
-        
-        const updatedClubs = userClubs.filter((id: string) => id !== club.id);
-        await update(userRef, { clubs: updatedClubs });
-      });
-
-      await Promise.all(memberPromises);
-
-      // Delete the club itself
-      const clubRef = ref(db, `clubs/${club.id}`);
-      await remove(clubRef);
+      // The invite service removes the club from every member's clubs before deleting it
+      await deleteClub(club.id);
 
       // Navigate back to clubs list
       navigate('/clubs');
@@ -438,6 +448,105 @@ const SettingsTab: React.FC<SettingsTabProps> = ({ club, user, db }) => {
         </div>
       </div>
 
+      {/* Hardcover Review Settings */}
+      <div style={{
+        background: 'white',
+        borderRadius: '12px',
+        padding: '2rem',
+        marginBottom: '2rem',
+        boxShadow: '0 4px 20px rgba(0,0,0,0.1)'
+      }}>
+        <h3 style={{ 
+          fontSize: '1.5rem', 
+          fontWeight: 'bold', 
+          marginBottom: '1.5rem', 
+          color: '#333',
+          display: 'flex',
+          alignItems: 'center',
+          gap: '0.5rem'
+        }}>
+          <span>📚</span>
+          <span>Hardcover Reviews</span>
+        </h3>
+        <p style={{ 
+          color: '#666', 
+          marginBottom: '1.5rem',
+          fontSize: '0.95rem'
+        }}>
+          Applies when members sync ratings and reviews of this club's books to Hardcover. Where a member's own settings are more private, theirs are used.
+          {!isPublic && ' This club is private, so you may want to keep reviews private too.'}
+        </p>
+
+        <div style={{ marginBottom: '1.5rem' }}>
+          <label style={{ 
+            display: 'block', 
+            fontSize: '0.95rem', 
+            fontWeight: '600', 
+            color: '#333',
+            marginBottom: '0.5rem'
+          }}>
+            Review Privacy
+          </label>
+          <select
+            value={hardcoverSettings.reviewPrivacy || ''}
+            onChange={(e) => setHardcoverSettings(prev => ({
+              ...prev,
+              reviewPrivacy: e.target.value as HardcoverSyncSettings['reviewPrivacy']
+            }))}
+            style={{
+              width: '100%',
+              padding: '0.75rem',
+              border: '2px solid #e1e5e9',
+              borderRadius: '8px',
+              fontSize: '1rem'
+            }}
+          >
+            <option value="">Each member's Hardcover default</option>
+            <option value="public">Public</option>
+            <option value="followers">Followers only</option>
+            <option value="private">Private</option>
+          </select>
+        </div>
+
+        <div style={{ marginBottom: '1.5rem', display: 'flex', flexDirection: 'column', gap: '0.5rem' }}>
+          <label style={{ display: 'flex', alignItems: 'center', gap: '0.5rem', fontSize: '0.95rem', color: '#333' }}>
+            <input
+              type="checkbox"
+              checked={hardcoverSettings.spoilers ?? false}
+              onChange={(e) => setHardcoverSettings(prev => ({ ...prev, spoilers: e.target.checked }))}
+            />
+            Mark synced reviews as containing spoilers
+          </label>
+          <label style={{ display: 'flex', alignItems: 'center', gap: '0.5rem', fontSize: '0.95rem', color: '#333' }}>
+            <input
+              type="checkbox"
+              checked={hardcoverSettings.ratingOnly ?? false}
+              onChange={(e) => setHardcoverSettings(prev => ({ ...prev, ratingOnly: e.target.checked }))}
+            />
+            Sync ratings only, never review text
+          </label>
+        </div>
+
+        <div style={{ display: 'flex', gap: '0.75rem', justifyContent: 'flex-end' }}>
+          <button
+            onClick={handleSaveHardcoverSettings}
+            disabled={savingHardcoverSettings}
+            style={{
+              padding: '0.75rem 2rem',
+              background: savingHardcoverSettings ? '#ccc' : 'linear-gradient(135deg, #667eea 0%, #764ba2 100%)',
+              color: 'white',
+              border: 'none',
+              borderRadius: '8px',
+              fontSize: '1rem',
+              fontWeight: '600',
+              cursor: savingHardcoverSettings ? 'not-allowed' : 'pointer'
+            }}
+          >
+            {savingHardcoverSettings ? 'Saving...' : 'Save Hardcover Settings'}
+          </button>
+        </div>
+      </div>
+
       {/* Member Role Management */}
       <div style={{
         background: 'white',
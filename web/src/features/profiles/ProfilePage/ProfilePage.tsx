@@ -3,12 +3,13 @@ import { useNavigate } from 'react-router-dom';
 import { User } from 'firebase/auth';
 import { Database } from 'firebase/database';
 import { getAuth, signOut } from 'firebase/auth';
-import { ref, get, update } from 'firebase/database';
 import HeaderBar from '../../../components/HeaderBar';
 import { useProfileData } from './useProfileData';
 import AccountInfo from './components/AccountInfo';
 import MyClubs from './components/MyClubs';
 import LeaveClubModal from './components/LeaveClubModal';
+import { leaveClub } from '../../../utils/clubMembership';
+import { ServiceRequestError } from '../../../utils/serviceErrors';
 
 interface ProfilePageProps {
   user: User | null;
@@ -48,55 +49,17 @@ const ProfilePage: React.FC<ProfilePageProps> = ({ user, db }) => {
     setLeavingClub(true);
 
     try {
-      const { id: clubId, name: clubName } = clubToLeave;
-      // Remove club ID from user's clubs array
-      const userRef = ref(db, `users/${user.uid}`);
-      const userSnapshot = await get(userRef);
-      const userData = userSnapshot.val() || {};
-      const userClubs = userData.clubs || [];
-      
-      const updatedClubs = userClubs.filter((id: string) => id !== clubId);
-      await update(userRef, { clubs: updatedClubs });
-
-      // Remove user from club's members
-      const clubRef = ref(db, `clubs/${clubId}`);
-      const clubSnapshot = await get(clubRef);
-      const clubData = clubSnapshot.val();
-
-      if (clubData && clubData.members) {
-        let updatedMembers: any[] | { [key: string]: any };
-        let updatedMemberCount: number;
-
-        if (Array.isArray(clubData.members)) {
-          // Members is an array
-          updatedMembers = clubData.members.filter((member: any) => member && member.id !== user.uid);
-          updatedMemberCount = (updatedMembers as any[]).length;
-        } else {
-          // Members is an object (key-value pairs)
-          updatedMembers = {};
-          let count = 0;
-          Object.keys(clubData.members).forEach((key) => {
-            const member = clubData.members[key];
-            if (member && member.id !== user.uid) {
-              (updatedMembers as { [key: string]: any })[key] = member;
-              count++;
-            }
-          });
-          updatedMemberCount = count;
-        }
-
-        // Update club with new members list and member count
-        await update(clubRef, {
-          members: updatedMembers,
-          memberCount: updatedMemberCount,
-        });
-      }
+      await leaveClub(clubToLeave.id);
 
       // Close modal
       setClubToLeave(null);
     } catch (error) {
       console.error("Error leaving club:", error);
-      alert("Failed to leave club. Please try again.");
+      if (error instanceof ServiceRequestError && error.code === 'last_admin') {
+        alert("Make another member an admin before leaving the club.");
+      } else {
+        alert("Failed to leave club. Please try again.");
+      }
     } finally {
       setLeavingClub(false);
     }
@@ -223,7 +186,7 @@ const ProfilePage: React.FC<ProfilePageProps> = ({ user, db }) => {
 
           {user ? (
             <div>
-              <AccountInfo user={user} db={db} />
+              <AccountInfo user={user} />
               
               <MyClubs
                 clubs={clubs}
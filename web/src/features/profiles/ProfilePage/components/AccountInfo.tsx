@@ -1,75 +1,78 @@
-import React, { useState, useEffect } from 'react';
+import React, { useState, useEffect, useCallback } from 'react';
 import { User } from 'firebase/auth';
-import { Database } from 'firebase/database';
-import { ref, get, update } from 'firebase/database';
 import { getInviteServiceURL } from '../../../../config/runtimeConfig';
+import { appCheckHeaders } from '../../../../utils/appCheck';
+import { readServiceError } from '../../../../utils/serviceErrors';
+import HardcoverSyncHistory from './HardcoverSyncHistory';
+import HardcoverSyncSettings from './HardcoverSyncSettings';
 
 interface AccountInfoProps {
   user: User;
-  db: Database;
 }
 
-const AccountInfo: React.FC<AccountInfoProps> = ({ user, db }) => {
+interface HardcoverUser {
+  id: string;
+  username: string;
+  cachedImageUrl?: string;
+}
+
+interface HardcoverLinkStatus {
+  linked: boolean;
+  user?: HardcoverUser;
+  linkedAt?: number;
+}
+
+const AccountInfo: React.FC<AccountInfoProps> = ({ user }) => {
   const [hardcoverToken, setHardcoverToken] = useState<string>('');
-  const [hardcoverUser, setHardcoverUser] = useState<{ id: string; username: string; cachedImageUrl?: string } | null>(null);
+  const [hardcoverUser, setHardcoverUser] = useState<HardcoverUser | null>(null);
+  const [isLinked, setIsLinked] = useState(false);
   const [isEditingToken, setIsEditingToken] = useState(false);
   const [isSaving, setIsSaving] = useState(false);
   const [isLoading, setIsLoading] = useState(true);
 
-  useEffect(() => {
-    const loadHardcoverToken = async () => {
-      if (!user || !db) {
-        setIsLoading(false);
-        return;
-      }
+  // Calls the Hardcover link endpoint; the API token itself never comes back to the browser
+  const requestHardcoverLink = useCallback(async (method: string, body?: object): Promise<Response> => {
+    const idToken = await user.getIdToken();
+    return fetch(`${getInviteServiceURL()}/v1/me/hardcover`, {
+      method,
+      headers: {
+        'Content-Type': 'application/json',
+        'Authorization': `Bearer ${idToken}`,
+        ...(await appCheckHeaders())
+      },
+      body: body ? JSON.stringify(body) : undefined
+    });
+  }, [user]);
 
-      try {
-        const userRef = ref(db, `users/${user.uid}`);
-        const snapshot = await get(userRef);
-        const userData = snapshot.val();
-        
-        if (userData?.hardcoverApiToken) {
-          setHardcoverToken(userData.hardcoverApiToken);
-          
-          // Load Hardcover user info if token exists
-          if (userData.hardcoverApiToken) {
-            try {
-              const inviteServiceURL = getInviteServiceURL();
-              
-              const response = await fetch(`${inviteServiceURL}/TestHardcoverToken`, {
-                method: 'POST',
-                headers: {
-                  'Content-Type': 'application/json',
-                },
-                body: JSON.stringify({
-                  token: userData.hardcoverApiToken
-                })
-              });
+  const applyLinkStatus = useCallback((status: HardcoverLinkStatus) => {
+    setIsLinked(status.linked);
+    setHardcoverUser(status.user || null);
+  }, []);
 
-              if (response.ok) {
-                const testResult = await response.json();
-                if (testResult.valid && testResult.user) {
-                  setHardcoverUser(testResult.user);
-                }
-              }
-            } catch (error) {
-              console.error('Error testing Hardcover token:', error);
-            }
-          }
-        }
-      } catch (error) {
-        console.error('Error loading Hardcover token:', error);
-      } finally {
-        setIsLoading(false);
+  const loadHardcoverLink = useCallback(async () => {
+    try {
+      const response = await requestHardcoverLink('GET');
+      if (!response.ok) {
+        const serviceError = await readServiceError(response);
+        throw new Error(serviceError.message);
       }
-    };
+      applyLinkStatus(await response.json());
+    } catch (error) {
+      console.error('Error loading Hardcover link:', error);
+    }
+  }, [requestHardcoverLink, applyLinkStatus]);
 
-    loadHardcoverToken();
-  }, [user, db]);
+  useEffect(() => {
+    if (!user) {
+      setIsLoading(false);
+      return;
+    }
+    loadHardcoverLink().finally(() => setIsLoading(false));
+  }, [user, loadHardcoverLink]);
 
 
   const handleSaveToken = async () => {
-    if (!user || !db) return;
+    if (!user) return;
 
     // Strip "Bearer " prefix if user included it
     const token = hardcoverToken.replace(/^Bearer\s+/i, '').trim();
@@ -80,40 +83,18 @@ const AccountInfo: React.FC<AccountInfoProps> = ({ user, db }) => {
 
     setIsSaving(true);
     try {
-      // Test the token before saving
-      const inviteServiceURL = getInviteServiceURL();
-      const response = await fetch(`${inviteServiceURL}/TestHardcoverToken`, {
-        method: 'POST',
-        headers: {
-          'Content-Type': 'application/json',
-        },
-        body: JSON.stringify({
-          token: token
-        })
-      });
+      // The service checks the token with Hardcover before linking it
+      const response = await requestHardcoverLink('PUT', { token });
 
-      const testResult = await response.json();
-      
-      if (!testResult.valid) {
-        const errorMsg = testResult.error 
-          ? `Invalid API token: ${testResult.error}. Please check your token and try again. You can get your token from https://hardcover.app/settings/api`
-          : 'Invalid API token. Please check your token and try again. You can get your token from https://hardcover.app/settings/api';
-        window.alert(errorMsg);
+      if (!response.ok) {
+        const serviceError = await readServiceError(response);
+        window.alert(`Invalid API token: ${serviceError.message}. Please check your token and try again. You can get your token from https://hardcover.app/settings/api`);
         setIsSaving(false);
         return;
       }
 
-      // Token is valid, save it and store user info
-      const userRef = ref(db, `users/${user.uid}`);
-      await update(userRef, {
-        hardcoverApiToken: token
-      });
-      
-      // Store user info
-      if (testResult.user) {
-        setHardcoverUser(testResult.user);
-      }
-      
+      applyLinkStatus(await response.json());
+      setHardcoverToken('');
       setIsEditingToken(false);
       window.alert('Hardcover account linked successfully!');
     } catch (error) {
@@ -125,7 +106,7 @@ const AccountInfo: React.FC<AccountInfoProps> = ({ user, db }) => {
   };
 
   const handleRemoveToken = async () => {
-    if (!user || !db) return;
+    if (!user) return;
 
     if (!window.confirm('Are you sure you want to unlink your Hardcover account? Reviews will no longer be uploaded automatically.')) {
       return;
@@ -133,12 +114,13 @@ const AccountInfo: React.FC<AccountInfoProps> = ({ user, db }) => {
 
     setIsSaving(true);
     try {
-      const userRef = ref(db, `users/${user.uid}`);
-      await update(userRef, {
-        hardcoverApiToken: null
-      });
+      const response = await requestHardcoverLink('DELETE');
+      if (!response.ok) {
+        const serviceError = await readServiceError(response);
+        throw new Error(serviceError.message);
+      }
+      applyLinkStatus(await response.json());
       setHardcoverToken('');
-      setHardcoverUser(null);
       setIsEditingToken(false);
     } catch (error) {
       console.error('Error removing Hardcover token:', error);
@@ -148,8 +130,6 @@ const AccountInfo: React.FC<AccountInfoProps> = ({ user, db }) => {
     }
   };
 
-  const isLinked = hardcoverToken.trim().length > 0 && hardcoverUser !== null;
-
   return (
     <div style={{ marginBottom: "2rem" }}>
       <h3 style={{ 
@@ -307,51 +287,7 @@ const AccountInfo: React.FC<AccountInfoProps> = ({ user, db }) => {
                 <button
                   onClick={() => {
                     setIsEditingToken(false);
-                    // Reload token and user info from database
-                    const reloadToken = async () => {
-                      try {
-                        const userRef = ref(db, `users/${user.uid}`);
-                        const snapshot = await get(userRef);
-                        const userData = snapshot.val();
-                        const token = userData?.hardcoverApiToken || '';
-                        setHardcoverToken(token);
-                        
-                        // Reload user info if token exists
-                        if (token) {
-                          try {
-                            const inviteServiceURL = getInviteServiceURL();
-                            const response = await fetch(`${inviteServiceURL}/TestHardcoverToken`, {
-                              method: 'POST',
-                              headers: {
-                                'Content-Type': 'application/json',
-                              },
-                              body: JSON.stringify({
-                                token: token
-                              })
-                            });
-
-                            if (response.ok) {
-                              const testResult = await response.json();
-                              if (testResult.valid && testResult.user) {
-                                setHardcoverUser(testResult.user);
-                              } else {
-                                setHardcoverUser(null);
-                              }
-                            } else {
-                              setHardcoverUser(null);
-                            }
-                          } catch (error) {
-                            console.error('Error reloading token:', error);
-                            setHardcoverUser(null);
-                          }
-                        } else {
-                          setHardcoverUser(null);
-                        }
-                      } catch (error) {
-                        console.error('Error reloading token:', error);
-                      }
-                    };
-                    reloadToken();
+                    setHardcoverToken('');
                   }}
                   disabled={isSaving}
                   style={{
@@ -388,13 +324,13 @@ const AccountInfo: React.FC<AccountInfoProps> = ({ user, db }) => {
                   }}>
                     Hardcover Account
                   </label>
-                  {isLinked && hardcoverUser ? (
+                  {isLinked ? (
                     <div style={{ 
                       display: "flex", 
                       alignItems: "center", 
                       gap: "0.75rem"
                     }}>
-                      {hardcoverUser.cachedImageUrl && (
+                      {hardcoverUser?.cachedImageUrl && (
                         <img
                           src={hardcoverUser.cachedImageUrl}
                           alt="Hardcover avatar"
@@ -434,7 +370,7 @@ const AccountInfo: React.FC<AccountInfoProps> = ({ user, db }) => {
                           fontSize: "0.75rem", 
                           color: "#6c757d"
                         }}>
-                          @{hardcoverUser.username}
+                          {hardcoverUser ? `@${hardcoverUser.username}` : ''}
                         </div>
                       </div>
                     </div>
@@ -529,6 +465,8 @@ const AccountInfo: React.FC<AccountInfoProps> = ({ user, db }) => {
                   </a>
                 </div>
               )}
+              {isLinked && <HardcoverSyncSettings />}
+              {isLinked && <HardcoverSyncHistory />}
             </div>
           )}
         </div>
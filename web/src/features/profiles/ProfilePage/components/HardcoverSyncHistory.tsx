@@ -0,0 +1,175 @@
+import React, { useState, useEffect, useCallback } from 'react';
+import {
+  HardcoverSyncAttempt,
+  getHardcoverSyncHistory,
+  retryHardcoverSync,
+} from '../../../../utils/hardcoverSync';
+
+// Explanations for the error codes a failed sync can record
+const syncErrorDescriptions: Record<string, string> = {
+  hardcover_book_not_found: "This book isn't on Hardcover",
+  hardcover_book_ambiguous: 'Several Hardcover books matched',
+  hardcover_invalid_token: 'Hardcover rejected your API token',
+  hardcover_rate_limited: 'Hardcover was busy',
+  hardcover_unavailable: "Hardcover couldn't be reached",
+  invalid_isbn: "The book's ISBN isn't valid",
+};
+
+// Why a retry skipped an attempt; retrying it again needs the cause fixed first
+const retrySkipDescriptions: Record<string, string> = {
+  rating_only: 'retry skipped: only ratings are synced',
+  nothing_to_sync: 'retry skipped: the review was removed',
+  review_too_long: 'retry skipped: the review is too long',
+};
+
+const HardcoverSyncHistory: React.FC = () => {
+  const [attempts, setAttempts] = useState<HardcoverSyncAttempt[]>([]);
+  const [retryable, setRetryable] = useState(0);
+  const [isLoading, setIsLoading] = useState(true);
+  const [retrying, setRetrying] = useState(false);
+  const [error, setError] = useState<string | null>(null);
+
+  const loadHistory = useCallback(async () => {
+    try {
+      const history = await getHardcoverSyncHistory();
+      setAttempts(history.attempts);
+      setRetryable(history.retryable);
+      setError(null);
+    } catch (err: any) {
+      console.error('Error loading Hardcover sync history:', err);
+      setError(err.message);
+    }
+  }, []);
+
+  useEffect(() => {
+    loadHistory().finally(() => setIsLoading(false));
+  }, [loadHistory]);
+
+  const handleRetry = async (ids?: string[]) => {
+    setRetrying(true);
+    try {
+      await retryHardcoverSync(ids);
+      await loadHistory();
+    } catch (err: any) {
+      console.error('Error retrying Hardcover sync:', err);
+      setError(err.message);
+    } finally {
+      setRetrying(false);
+    }
+  };
+
+  if (isLoading) {
+    return (
+      <div style={{ color: "#6c757d", fontSize: "0.875rem", marginTop: "1rem" }}>
+        Loading sync history...
+      </div>
+    );
+  }
+
+  return (
+    <div style={{ marginTop: "1rem", borderTop: "1px solid #e9ecef", paddingTop: "1rem" }}>
+      <div style={{
+        display: "flex",
+        alignItems: "center",
+        justifyContent: "space-between",
+        marginBottom: "0.5rem"
+      }}>
+        <label style={{
+          fontSize: "0.875rem",
+          fontWeight: "500",
+          color: "#6c757d"
+        }}>
+          Recent Syncs
+        </label>
+        {retryable > 0 && (
+          <button
+            onClick={() => handleRetry()}
+            disabled={retrying}
+            style={{
+              backgroundColor: "transparent",
+              color: "#00356B",
+              border: "1px solid #00356B",
+              borderRadius: "4px",
+              padding: "0.25rem 0.75rem",
+              fontSize: "0.75rem",
+              fontWeight: "500",
+              cursor: retrying ? "not-allowed" : "pointer",
+              opacity: retrying ? 0.6 : 1
+            }}
+          >
+            {retrying ? "Retrying..." : `Retry ${retryable} failed`}
+          </button>
+        )}
+      </div>
+
+      {error && (
+        <div style={{ color: "#dc3545", fontSize: "0.75rem", marginBottom: "0.5rem" }}>
+          {error}
+        </div>
+      )}
+
+      {attempts.length === 0 ? (
+        <div style={{ color: "#6c757d", fontSize: "0.875rem" }}>
+          No ratings or reviews synced yet.
+        </div>
+      ) : (
+        <div style={{ maxHeight: "240px", overflowY: "auto" }}>
+          {attempts.map(attempt => (
+            <div
+              key={attempt.id}
+              style={{
+                display: "flex",
+                alignItems: "flex-start",
+                justifyContent: "space-between",
+                gap: "0.5rem",
+                padding: "0.5rem 0",
+                borderBottom: "1px solid #e9ecef",
+                fontSize: "0.8rem"
+              }}
+            >
+              <div style={{ flex: 1 }}>
+                <div style={{ color: "#212529", fontWeight: "500" }}>
+                  {attempt.title || attempt.isbn || 'Unknown book'}
+                </div>
+                <div style={{ color: "#6c757d", fontSize: "0.75rem" }}>
+                  {new Date(attempt.at * 1000).toLocaleString()}
+                  {attempt.outcome === 'synced'
+                    ? ` · ${attempt.action === 'created' ? 'Added to' : 'Updated on'} Hardcover`
+                    : ` · ${(attempt.error && syncErrorDescriptions[attempt.error]) || 'Sync failed'}`}
+                  {attempt.retriedBy && ' · retried'}
+                  {attempt.retrySkipped && ` · ${retrySkipDescriptions[attempt.retrySkipped]}`}
+                </div>
+              </div>
+              <span style={{
+                color: attempt.outcome === 'synced' ? "#28a745" : "#dc3545",
+                fontWeight: "500",
+                whiteSpace: "nowrap"
+              }}>
+                {attempt.outcome === 'synced' ? 'Synced' : 'Failed'}
+              </span>
+              {(attempt.retryable || (attempt.retrySkipped && !attempt.retriedBy)) && (
+                <button
+                  onClick={() => handleRetry([attempt.id])}
+                  disabled={retrying}
+                  style={{
+                    backgroundColor: "transparent",
+                    color: "#00356B",
+                    border: "none",
+                    padding: 0,
+                    fontSize: "0.75rem",
+                    textDecoration: "underline",
+                    cursor: retrying ? "not-allowed" : "pointer"
+                  }}
+                >
+                  Retry
+                </button>
+              )}
+            </div>
+          ))}
+        </div>
+      )}
+    </div>
+  );
+};
+
+export default HardcoverSyncHistory;
@@ -0,0 +1,108 @@
+import React, { useState, useEffect } from 'react';
+import {
+  HardcoverSyncSettings as SyncSettings,
+  getHardcoverSyncSettings,
+  updateHardcoverSyncSettings,
+} from '../../../../utils/hardcoverSync';
+
+const HardcoverSyncSettings: React.FC = () => {
+  const [settings, setSettings] = useState<SyncSettings>({});
+  const [isLoading, setIsLoading] = useState(true);
+  const [isSaving, setIsSaving] = useState(false);
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    getHardcoverSyncSettings()
+      .then(setSettings)
+      .catch((err: any) => {
+        console.error('Error loading Hardcover sync settings:', err);
+        setError(err.message);
+      })
+      .finally(() => setIsLoading(false));
+  }, []);
+
+  // Settings are saved as soon as they change
+  const handleChange = async (changes: Partial<SyncSettings>) => {
+    const previous = settings;
+    setSettings({ ...settings, ...changes });
+    setIsSaving(true);
+    try {
+      setSettings(await updateHardcoverSyncSettings({ ...previous, ...changes }));
+      setError(null);
+    } catch (err: any) {
+      console.error('Error saving Hardcover sync settings:', err);
+      setSettings(previous);
+      setError(err.message);
+    } finally {
+      setIsSaving(false);
+    }
+  };
+
+  if (isLoading) {
+    return (
+      <div style={{ color: "#6c757d", fontSize: "0.875rem", marginTop: "1rem" }}>
+        Loading sync settings...
+      </div>
+    );
+  }
+
+  return (
+    <div style={{ marginTop: "1rem", borderTop: "1px solid #e9ecef", paddingTop: "1rem" }}>
+      <label style={{
+        display: "block",
+        fontSize: "0.875rem",
+        fontWeight: "500",
+        color: "#6c757d",
+        marginBottom: "0.5rem"
+      }}>
+        Review Privacy
+      </label>
+      <select
+        value={settings.reviewPrivacy || ''}
+        disabled={isSaving}
+        onChange={(e) => handleChange({ reviewPrivacy: e.target.value as SyncSettings['reviewPrivacy'] })}
+        style={{
+          width: "100%",
+          padding: "0.5rem",
+          border: "1px solid #ced4da",
+          borderRadius: "4px",
+          fontSize: "0.875rem",
+          marginBottom: "0.75rem"
+        }}
+      >
+        <option value="">My Hardcover default</option>
+        <option value="public">Public</option>
+        <option value="followers">Followers only</option>
+        <option value="private">Private</option>
+      </select>
+      <label style={{ display: "flex", alignItems: "center", gap: "0.5rem", fontSize: "0.875rem", color: "#495057", marginBottom: "0.25rem" }}>
+        <input
+          type="checkbox"
+          checked={settings.spoilers ?? false}
+          disabled={isSaving}
+          onChange={(e) => handleChange({ spoilers: e.target.checked })}
+        />
+        Mark synced reviews as containing spoilers
+      </label>
+      <label style={{ display: "flex", alignItems: "center", gap: "0.5rem", fontSize: "0.875rem", color: "#495057" }}>
+        <input
+          type="checkbox"
+          checked={settings.ratingOnly ?? false}
+          disabled={isSaving}
+          onChange={(e) => handleChange({ ratingOnly: e.target.checked })}
+        />
+        Sync ratings only, never review text
+      </label>
+      <div style={{ color: "#6c757d", fontSize: "0.75rem", marginTop: "0.5rem" }}>
+        A club's own settings apply to its books when they are more private.
+      </div>
+      {error && (
+        <div style={{ color: "#dc3545", fontSize: "0.75rem", marginTop: "0.5rem" }}>
+          {error}
+        </div>
+      )}
+    </div>
+  );
+};
+
+export default HardcoverSyncSettings;
@@ -0,0 +1,38 @@
+import { getApp } from 'firebase/app';
+import { AppCheck, getToken, initializeAppCheck, ReCaptchaV3Provider } from 'firebase/app-check';
+import { getAppCheckSiteKey } from '../config/runtimeConfig';
+
+let appCheck: AppCheck | null = null;
+
+/**
+ * Starts Firebase App Check if a reCAPTCHA v3 site key is configured. Call once, after
+ * the Firebase app is initialized.
+ */
+export const initAppCheck = (): void => {
+  const siteKey = getAppCheckSiteKey();
+  if (!siteKey || appCheck) {
+    return;
+  }
+  appCheck = initializeAppCheck(getApp(), {
+    provider: new ReCaptchaV3Provider(siteKey),
+    isTokenAutoRefreshEnabled: true,
+  });
+};
+
+/**
+ * Returns the App Check header to send with invite service requests. Without App Check,
+ * or if no token can be obtained, no header is sent and the service's APP_CHECK_MODE
+ * decides whether the request goes through.
+ */
+export const appCheckHeaders = async (): Promise<Record<string, string>> => {
+  if (!appCheck) {
+    return {};
+  }
+  try {
+    const { token } = await getToken(appCheck);
+    return { 'X-Firebase-AppCheck': token };
+  } catch (error) {
+    console.warn('Failed to get App Check token:', error);
+    return {};
+  }
+};
@@ -0,0 +1,54 @@
+import { inviteServiceRequest } from './inviteService';
+
+// Club membership is written by the invite service; the database rules deny client
+// writes to a club's members and memberCount.
+
+export interface ClubMembershipResult {
+  success: boolean;
+  clubId: string;
+  userId: string;
+  role?: 'member' | 'admin' | 'owner';
+}
+
+/**
+ * Joins the club of an invite sent to the signed-in user's email. The email must be
+ * verified, so refresh the ID token after verifying it.
+ */
+export const acceptClubInvite = (clubId: string, inviteId: string): Promise<{ success: boolean; clubId: string }> =>
+  inviteServiceRequest(
+    'POST',
+    `/v1/clubs/${encodeURIComponent(clubId)}/invites/${encodeURIComponent(inviteId)}/accept`
+  );
+
+/**
+ * Joins a public club
+ */
+export const joinClub = (clubId: string): Promise<ClubMembershipResult> =>
+  inviteServiceRequest<ClubMembershipResult>('POST', `/v1/clubs/${encodeURIComponent(clubId)}/members`);
+
+/**
+ * Leaves a club. Fails with code last_admin if the user is the club's only admin and
+ * other members remain.
+ */
+export const leaveClub = (clubId: string): Promise<ClubMembershipResult> =>
+  inviteServiceRequest<ClubMembershipResult>('DELETE', `/v1/clubs/${encodeURIComponent(clubId)}/members/me`);
+
+/**
+ * Makes a member an admin or a plain member. Only club admins may call this.
+ */
+export const updateClubMemberRole = (
+  clubId: string,
+  userId: string,
+  role: 'member' | 'admin'
+): Promise<ClubMembershipResult> =>
+  inviteServiceRequest<ClubMembershipResult>(
+    'PUT',
+    `/v1/clubs/${encodeURIComponent(clubId)}/members/${encodeURIComponent(userId)}/role`,
+    { role }
+  );
+
+/**
+ * Deletes a club and removes it from its members' clubs. Only club admins may call this.
+ */
+export const deleteClub = (clubId: string): Promise<{ success: boolean; clubId: string }> =>
+  inviteServiceRequest('DELETE', `/v1/clubs/${encodeURIComponent(clubId)}`);
@@ -0,0 +1,371 @@
+import { inviteServiceRequest } from './inviteService';
+
+export interface ClubReadingSyncOptions {
+  completed?: boolean; // Mark the book read even if progress is short of the end
+  finishedAt?: string; // YYYY-MM-DD, defaults to today
+  hardcoverBookId?: number; // A candidate picked after a hardcover_book_ambiguous error
+}
+
+export interface ClubReadingSyncReport {
+  clubId: string;
+  isbn: string;
+  status: 'currently_reading' | 'read';
+  progressPages?: number;
+  totalPages?: number;
+  synced: number;
+  skipped: number;
+  failed: number;
+  members: Array<{
+    userId: string;
+    name: string;
+    outcome: 'synced' | 'skipped' | 'failed';
+    action?: 'created' | 'updated';
+    reason?: string;
+  }>;
+}
+
+export interface ClubReadingSyncJob {
+  id: string;
+  clubId: string;
+  status: 'running' | 'completed' | 'failed';
+  startedBy: string;
+  startedAt: number; // Unix seconds
+  finishedAt?: number;
+  members: number;
+  membersDone: number;
+  error?: 'timeout' | 'interrupted';
+  report?: ClubReadingSyncReport; // Set once the job finishes
+}
+
+/**
+ * Starts a background job putting the club's current book, with the club's progress,
+ * on the Hardcover shelf of every member with a linked account. Only club admins may
+ * call this. Poll the returned job with getClubReadingSyncJob or waitForClubReadingSync.
+ * @param clubId - The club whose current book to sync
+ * @param options - Set completed to mark the book read
+ * @throws ServiceRequestError with code hardcover_book_ambiguous and the candidates in
+ * details when the book matches several Hardcover books; retry with hardcoverBookId
+ */
+export const syncClubReadingToHardcover = (
+  clubId: string,
+  options: ClubReadingSyncOptions = {}
+): Promise<ClubReadingSyncJob> =>
+  inviteServiceRequest<ClubReadingSyncJob>(
+    'POST',
+    `/v1/clubs/${encodeURIComponent(clubId)}/hardcover/reading`,
+    options
+  );
+
+/**
+ * Gets a club reading sync job, with its report once it finishes
+ * @param clubId - The club the job syncs
+ * @param jobId - The job returned by syncClubReadingToHardcover
+ */
+export const getClubReadingSyncJob = (clubId: string, jobId: string): Promise<ClubReadingSyncJob> =>
+  inviteServiceRequest<ClubReadingSyncJob>(
+    'GET',
+    `/v1/clubs/${encodeURIComponent(clubId)}/hardcover/reading/jobs/${encodeURIComponent(jobId)}`
+  );
+
+/**
+ * Polls a club reading sync job until it finishes
+ * @param clubId - The club the job syncs
+ * @param jobId - The job returned by syncClubReadingToHardcover
+ * @param intervalMs - Time between polls
+ */
+export const waitForClubReadingSync = async (
+  clubId: string,
+  jobId: string,
+  intervalMs = 3000
+): Promise<ClubReadingSyncJob> => {
+  for (;;) {
+    await new Promise(resolve => setTimeout(resolve, intervalMs));
+    const job = await getClubReadingSyncJob(clubId, jobId);
+    if (job.status !== 'running') return job;
+  }
+};
+
+export interface ClubRatingsSyncReport {
+  clubId: string;
+  members: number;
+  synced: number;
+  skipped: number;
+  failed: number;
+  results: Array<{
+    userId: string;
+    name: string;
+    isbn?: string;
+    title: string;
+    outcome: 'synced' | 'skipped' | 'failed';
+    action?: 'created' | 'updated';
+    reason?: string;
+  }>;
+}
+
+export interface ClubRatingsSyncJob {
+  id: string;
+  clubId: string;
+  status: 'running' | 'completed' | 'failed';
+  startedBy: string;
+  allMembers?: boolean;
+  startedAt: number; // Unix seconds
+  finishedAt?: number;
+  members: number; // Members with ratings to sync
+  membersDone: number;
+  error?: 'timeout' | 'interrupted';
+  report?: ClubRatingsSyncReport; // Set once the job finishes
+}
+
+/**
+ * Starts a background job syncing the ratings and reviews in the club's history to
+ * Hardcover. Poll the returned job with getClubRatingsSyncJob or waitForClubRatingsSync.
+ * @param clubId - The club whose history to sync
+ * @param allMembers - Sync every linked member instead of just the user (admins only);
+ * other members' reviews are left for them to sync
+ */
+export const syncClubRatingsToHardcover = (
+  clubId: string,
+  allMembers = false
+): Promise<ClubRatingsSyncJob> =>
+  inviteServiceRequest<ClubRatingsSyncJob>(
+    'POST',
+    `/v1/clubs/${encodeURIComponent(clubId)}/hardcover/ratings`,
+    { allMembers }
+  );
+
+/**
+ * Gets a club ratings sync job, with its report once it finishes
+ * @param clubId - The club the job syncs
+ * @param jobId - The job returned by syncClubRatingsToHardcover
+ */
+export const getClubRatingsSyncJob = (clubId: string, jobId: string): Promise<ClubRatingsSyncJob> =>
+  inviteServiceRequest<ClubRatingsSyncJob>(
+    'GET',
+    `/v1/clubs/${encodeURIComponent(clubId)}/hardcover/ratings/jobs/${encodeURIComponent(jobId)}`
+  );
+
+/**
+ * Polls a club ratings sync job until it finishes
+ * @param clubId - The club the job syncs
+ * @param jobId - The job returned by syncClubRatingsToHardcover
+ * @param onProgress - Called with the job after each poll while it runs
+ * @param intervalMs - Time between polls
+ */
+export const waitForClubRatingsSync = async (
+  clubId: string,
+  jobId: string,
+  onProgress?: (job: ClubRatingsSyncJob) => void,
+  intervalMs = 3000
+): Promise<ClubRatingsSyncJob> => {
+  for (;;) {
+    await new Promise(resolve => setTimeout(resolve, intervalMs));
+    const job = await getClubRatingsSyncJob(clubId, jobId);
+    if (job.status !== 'running') return job;
+    onProgress?.(job);
+  }
+};
+
+export interface HardcoverImportItem {
+  isbn: string;
+  title: string;
+  hardcoverBookId: number;
+  status: string;
+  rating?: number; // Hardcover rating rounded to whole stars
+  review?: string;
+  startedAt?: string;
+  finishedAt?: string;
+  clubRating?: number;
+  clubReview?: string;
+  canImportRating: boolean;
+  canImportReview: boolean;
+}
+
+export interface HardcoverImportResult {
+  clubId: string;
+  matched: number;
+  imported: number;
+  items: HardcoverImportItem[];
+}
+
+/**
+ * Lists the club's past books that are on the user's Hardcover shelf
+ * @param clubId - The club whose history to match
+ */
+export const previewHardcoverImport = (clubId: string): Promise<HardcoverImportResult> =>
+  inviteServiceRequest<HardcoverImportResult>(
+    'GET',
+    `/v1/clubs/${encodeURIComponent(clubId)}/hardcover/import`
+  );
+
+/**
+ * Copies the user's Hardcover ratings and reviews into the club's history
+ * @param clubId - The club whose history to back-fill
+ * @param isbns - The matched books to import; all of them if omitted
+ * @param overwrite - Replace ratings and reviews the user already has in the club
+ */
+export const importFromHardcover = (
+  clubId: string,
+  isbns?: string[],
+  overwrite = false
+): Promise<HardcoverImportResult> =>
+  inviteServiceRequest<HardcoverImportResult>(
+    'POST',
+    `/v1/clubs/${encodeURIComponent(clubId)}/hardcover/import`,
+    { isbns, overwrite }
+  );
+
+/**
+ * A possible Hardcover match returned with a hardcover_book_ambiguous error
+ */
+export interface HardcoverBookCandidate {
+  id: number;
+  title: string;
+  authors: string[];
+  releaseYear?: number;
+  readers?: number;
+  score: number;
+}
+
+export interface HardcoverSyncAttempt {
+  id: string;
+  at: number; // Unix seconds
+  source: 'rating' | 'review' | 'club_ratings';
+  clubId?: string;
+  isbn?: string;
+  title?: string;
+  author?: string;
+  hardcoverBookId?: number;
+  rating?: number;
+  review?: boolean; // A review was sent; retries use the user's current review in the club
+  outcome: 'synced' | 'failed';
+  action?: 'created' | 'updated';
+  error?: string; // error code when failed
+  errorMessage?: string;
+  retryOf?: string;
+  retriedBy?: string;
+  retrySkipped?: 'rating_only' | 'nothing_to_sync' | 'review_too_long'; // Why the latest retry skipped it
+  retryable?: boolean; // Failed, and neither retried nor skipped by a retry
+}
+
+export interface HardcoverSyncHistory {
+  attempts: HardcoverSyncAttempt[]; // newest first
+  retryable: number;
+}
+
+export interface HardcoverSyncRetryResult {
+  retried: number;
+  synced: number;
+  failed: number;
+  skipped: number;
+  remaining: number; // Retryable attempts left for another retry
+  attempts: HardcoverSyncAttempt[];
+  skips?: Array<{ id: string; reason: 'rating_only' | 'nothing_to_sync' | 'review_too_long' }>;
+}
+
+export interface HardcoverLinkStatus {
+  linked: boolean;
+  user?: { id: string; username: string; cachedImageUrl?: string };
+  linkedAt?: number;
+}
+
+/**
+ * Reports whether the user has linked a Hardcover account. The token itself is never
+ * returned to the browser.
+ */
+export const getHardcoverLink = (): Promise<HardcoverLinkStatus> =>
+  inviteServiceRequest<HardcoverLinkStatus>('GET', '/v1/me/hardcover');
+
+/**
+ * Lists the user's recent rating and review syncs to Hardcover
+ */
+export const getHardcoverSyncHistory = (): Promise<HardcoverSyncHistory> =>
+  inviteServiceRequest<HardcoverSyncHistory>('GET', '/v1/me/hardcover/sync-history');
+
+/**
+ * Retries the user's failed Hardcover syncs
+ * @param ids - The attempts to retry, at most 20; the oldest 20 retryable attempts if omitted
+ * @param hardcoverBookId - The book to use, when retrying a single ambiguous attempt
+ */
+export const retryHardcoverSync = (
+  ids?: string[],
+  hardcoverBookId?: number
+): Promise<HardcoverSyncRetryResult> =>
+  inviteServiceRequest<HardcoverSyncRetryResult>(
+    'POST',
+    '/v1/me/hardcover/sync-history/retry',
+    { ids, hardcoverBookId }
+  );
+
+export interface RetractReviewOptions {
+  keepRating?: boolean; // Remove only the review
+  deleteBook?: boolean; // Remove the book from the shelf if BookClurb added it
+}
+
+export interface RetractReviewResult {
+  success: boolean;
+  action: 'cleared' | 'deleted' | 'not_on_shelf' | 'untracked';
+}
+
+/**
+ * Removes the user's synced review, and rating unless keepRating is set, from Hardcover
+ * @param book - The club book the review was left on
+ * @param options - What to remove
+ */
+export const retractReviewFromHardcover = (
+  book: { isbn?: string; title: string; author?: string },
+  options: RetractReviewOptions = {}
+): Promise<RetractReviewResult> =>
+  inviteServiceRequest<RetractReviewResult>('DELETE', '/v1/me/hardcover/reviews', {
+    isbn: book.isbn || undefined,
+    title: book.title,
+    author: book.author || undefined,
+    ...options,
+  });
+
+export type HardcoverReviewPrivacy = '' | 'public' | 'followers' | 'private';
+
+export interface HardcoverSyncSettings {
+  reviewPrivacy?: HardcoverReviewPrivacy; // The Hardcover account's default if empty
+  spoilers?: boolean; // Flag synced reviews as containing spoilers
+  ratingOnly?: boolean; // Sync ratings but never review text
+}
+
+/**
+ * Gets the user's Hardcover sync settings
+ */
+export const getHardcoverSyncSettings = (): Promise<HardcoverSyncSettings> =>
+  inviteServiceRequest<HardcoverSyncSettings>('GET', '/v1/me/hardcover/settings');
+
+/**
+ * Replaces the user's Hardcover sync settings
+ * @param settings - The new settings
+ */
+export const updateHardcoverSyncSettings = (
+  settings: HardcoverSyncSettings
+): Promise<HardcoverSyncSettings> =>
+  inviteServiceRequest<HardcoverSyncSettings>('PUT', '/v1/me/hardcover/settings', settings);
+
+/**
+ * Gets a club's Hardcover sync settings, which apply on top of each member's own
+ * @param clubId - The club
+ */
+export const getClubHardcoverSyncSettings = (clubId: string): Promise<HardcoverSyncSettings> =>
+  inviteServiceRequest<HardcoverSyncSettings>(
+    'GET',
+    `/v1/clubs/${encodeURIComponent(clubId)}/hardcover/settings`
+  );
+
+/**
+ * Replaces a club's Hardcover sync settings (club admins only)
+ * @param clubId - The club
+ * @param settings - The new settings
+ */
+export const updateClubHardcoverSyncSettings = (
+  clubId: string,
+  settings: HardcoverSyncSettings
+): Promise<HardcoverSyncSettings> =>
+  inviteServiceRequest<HardcoverSyncSettings>(
+    'PUT',
+    `/v1/clubs/${encodeURIComponent(clubId)}/hardcover/settings`,
+    settings
+  );
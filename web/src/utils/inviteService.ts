@@ -0,0 +1,30 @@
+import { getAuth } from 'firebase/auth';
+import { getInviteServiceURL } from '../config/runtimeConfig';
+import { appCheckHeaders } from './appCheck';
+import { readServiceError, ServiceRequestError } from './serviceErrors';
+
+/**
+ * Sends an authenticated request to the invite service
+ * @throws ServiceRequestError with the service's error code and message on a non-2xx response
+ */
+export const inviteServiceRequest = async <T>(method: string, path: string, body?: unknown): Promise<T> => {
+  const currentUser = getAuth().currentUser;
+  if (!currentUser) {
+    throw new Error('User not authenticated');
+  }
+  const idToken = await currentUser.getIdToken();
+
+  const response = await fetch(`${getInviteServiceURL()}${path}`, {
+    method,
+    headers: {
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${idToken}`,
+      ...(await appCheckHeaders())
+    },
+    body: body === undefined ? undefined : JSON.stringify(body)
+  });
+  if (!response.ok) {
+    throw new ServiceRequestError(await readServiceError(response));
+  }
+  return response.json();
+};
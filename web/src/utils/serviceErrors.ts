@@ -0,0 +1,44 @@
+/**
+ * Error envelope returned by the invite service for every non-2xx response
+ */
+export interface ServiceError {
+  code: string;
+  message: string;
+  details?: unknown;
+  requestId?: string;
+}
+
+/**
+ * Reads the error envelope from a failed invite service response
+ * @param response - A fetch Response with a non-2xx status
+ * @returns The parsed error, or a generic one if the body is not an envelope
+ */
+export const readServiceError = async (response: Response): Promise<ServiceError> => {
+  try {
+    const body = await response.json();
+    if (body && body.error && typeof body.error.message === 'string') {
+      return body.error as ServiceError;
+    }
+  } catch {
+    // Fall through to the generic error below
+  }
+  return {
+    code: 'unknown_error',
+    message: response.statusText || 'Request failed',
+  };
+};
+
+/**
+ * Error thrown for a failed invite service request, carrying the service's error code
+ */
+export class ServiceRequestError extends Error {
+  code: string;
+  details?: unknown;
+
+  constructor(error: ServiceError) {
+    super(error.message);
+    this.name = 'ServiceRequestError';
+    this.code = error.code;
+    this.details = error.details;
+  }
+}